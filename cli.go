@@ -6,6 +6,8 @@ import (
 	"os"
 	"strconv"
 	"strings"
+
+	"pos-system/internal/money"
 )
 
 func main() {
@@ -119,7 +121,7 @@ func manageSales(reader *bufio.Reader, store *Store) {
 		fmt.Println("1) List")
 		fmt.Println("2) Add")
 		fmt.Println("3) Edit")
-		fmt.Println("4) Remove")
+		fmt.Println("4) Void")
 		fmt.Println("0) Back")
 
 		switch readInt(reader, "Select option: ") {
@@ -136,7 +138,7 @@ func manageSales(reader *bufio.Reader, store *Store) {
 				fmt.Printf("Failed to save data: %v\n", err)
 			}
 		case 4:
-			removeSale(reader, store)
+			voidSale(reader, store)
 			if err := saveStore(dataFile, store); err != nil {
 				fmt.Printf("Failed to save data: %v\n", err)
 			}
@@ -195,7 +197,7 @@ func readInt(reader *bufio.Reader, prompt string) int {
 	}
 }
 
-func readFloat(reader *bufio.Reader, prompt string) float64 {
+func readMoney(reader *bufio.Reader, prompt string) money.Money {
 	for {
 		fmt.Print(prompt)
 		input, err := reader.ReadString('\n')
@@ -208,9 +210,9 @@ func readFloat(reader *bufio.Reader, prompt string) float64 {
 			fmt.Println("Value cannot be empty.")
 			continue
 		}
-		value, err := strconv.ParseFloat(input, 64)
+		value, err := money.Parse(input, money.DefaultCurrency)
 		if err != nil {
-			fmt.Println("Invalid number. Please try again.")
+			fmt.Println("Invalid amount. Please try again.")
 			continue
 		}
 		return value
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
@@ -11,6 +12,7 @@ import (
 	"fyne.io/fyne/v2/widget"
 
 	"pos-system/internal/backup"
+	"pos-system/internal/barcode"
 	"pos-system/internal/store"
 	"pos-system/internal/ui"
 )
@@ -27,6 +29,9 @@ func main() {
 		fmt.Println("Failed to migrate database:", err)
 		os.Exit(1)
 	}
+	if _, err := store.ApplyDuePriceChanges(db, time.Now()); err != nil {
+		fmt.Println("Failed to apply scheduled prices:", err)
+	}
 
 	gui := app.New()
 	window := gui.NewWindow("POS System")
@@ -38,19 +43,37 @@ func main() {
 		}()
 	})
 
+	terminal := terminalName()
 	scanner := ui.NewScannerService()
-	checkoutView := ui.NewCheckoutTab(db, window, scanner)
-	salesView := ui.NewSalesTab(db, window)
-	productsView := ui.NewProductsTab(db)
+	checkoutView := ui.NewCheckoutTab(db, window, scanner, terminal)
+	customersView := ui.NewCustomersTab(db)
+	salesView := ui.NewSalesTab(db, window, terminal)
+	returnsView := ui.NewReturnsTab(db, window)
+	productsView := ui.NewProductsTab(db, window)
+	purchaseOrdersView := ui.NewPurchaseOrdersTab(db, window)
+	stocktakeView := ui.NewStocktakeTab(db, window)
+	transfersView := ui.NewTransfersTab(db, window)
+	priceListsView := ui.NewPriceListsTab(db, window)
+	reportsView := ui.NewReportsTab(db, window)
 	salesActive := false
 	checkoutActive := false
-	scanner.OnScan(func(barcode string) {
+	returnsActive := false
+	stocktakeActive := false
+	scanner.OnScan(func(scan barcode.Scan) {
 		if checkoutActive {
-			checkoutView.HandleScan(barcode)
+			checkoutView.HandleScan(scan)
 			return
 		}
 		if salesActive {
-			salesView.HandleScan(barcode)
+			salesView.HandleScan(scan)
+			return
+		}
+		if returnsActive {
+			returnsView.HandleScan(scan)
+			return
+		}
+		if stocktakeActive {
+			stocktakeView.HandleScan(scan)
 		}
 	})
 	scanner.Start(window)
@@ -58,22 +81,82 @@ func main() {
 
 	tabs := container.NewAppTabs(
 		checkoutView.Tab,
-		container.NewTabItem("Customers", ui.CustomersTab(db)),
+		customersView.Tab,
 		productsView.Tab,
 		salesView.Tab,
+		returnsView.Tab,
+		container.NewTabItem("Suppliers", ui.SuppliersTab(db)),
+		purchaseOrdersView.Tab,
+		stocktakeView.Tab,
+		transfersView.Tab,
+		priceListsView.Tab,
+		container.NewTabItem("Promotions", ui.PromotionsTab(db)),
+		reportsView.Tab,
+		container.NewTabItem("Settings", ui.SettingsTab(db, terminal)),
 	)
 	tabs.OnSelected = func(item *container.TabItem) {
 		salesActive = item == salesView.Tab
 		checkoutActive = item == checkoutView.Tab
+		returnsActive = item == returnsView.Tab
+		stocktakeActive = item == stocktakeView.Tab
 		checkoutView.SetActive(checkoutActive)
+		if item == purchaseOrdersView.Tab {
+			purchaseOrdersView.Refresh()
+		}
+		if stocktakeActive {
+			stocktakeView.Refresh()
+		}
+		if item == transfersView.Tab {
+			transfersView.Refresh()
+		}
+		if item == customersView.Tab {
+			customersView.Refresh()
+		}
+		if item == priceListsView.Tab {
+			priceListsView.Refresh()
+		}
+		if item == reportsView.Tab {
+			reportsView.Refresh()
+		}
 	}
 	salesActive = tabs.Selected() == salesView.Tab
 	checkoutActive = tabs.Selected() == checkoutView.Tab
+	returnsActive = tabs.Selected() == returnsView.Tab
+	stocktakeActive = tabs.Selected() == stocktakeView.Tab
 	checkoutView.SetActive(checkoutActive)
 
 	salesView.OnSaleCreated = func() {
 		productsView.Refresh()
 	}
+	returnsView.OnStockChanged = func() {
+		productsView.Refresh()
+	}
+	purchaseOrdersView.OnStockChanged = func() {
+		productsView.Refresh()
+	}
+	stocktakeView.OnStockChanged = func() {
+		productsView.Refresh()
+	}
+	transfersView.OnStockChanged = func() {
+		productsView.Refresh()
+	}
+
+	// Sales apply scheduled price changes as they fall due; this keeps the
+	// product list current between sales.
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			applied, err := store.ApplyDuePriceChanges(db, time.Now())
+			if err != nil {
+				fmt.Println("Failed to apply scheduled prices:", err)
+				continue
+			}
+			if applied > 0 {
+				fyne.Do(productsView.Refresh)
+			}
+		}
+	}()
 
 	backupButton := widget.NewButton("Backup Now", func() {
 		go func() {
@@ -91,3 +174,15 @@ func main() {
 	window.Resize(fyne.NewSize(900, 600))
 	window.ShowAndRun()
 }
+
+// terminalName identifies this till in per-terminal settings. POS_TERMINAL
+// overrides the host name.
+func terminalName() string {
+	if name := os.Getenv("POS_TERMINAL"); name != "" {
+		return name
+	}
+	if name, err := os.Hostname(); err == nil && name != "" {
+		return name
+	}
+	return "default"
+}
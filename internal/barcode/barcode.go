@@ -0,0 +1,174 @@
+package barcode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"pos-system/internal/quantity"
+)
+
+type Symbology string
+
+const (
+	EAN8   Symbology = "ean8"
+	EAN13  Symbology = "ean13"
+	UPCA   Symbology = "upca"
+	UPCE   Symbology = "upce"
+	GTIN14 Symbology = "gtin14"
+	GS1    Symbology = "gs1"
+	// Other covers in-store codes, PLUs and anything else without a
+	// check digit to verify.
+	Other Symbology = "other"
+)
+
+var (
+	ErrInvalidBarcode = errors.New("invalid barcode")
+	ErrCheckDigit     = errors.New("check digit mismatch")
+)
+
+// Scan is a decoded barcode.
+type Scan struct {
+	Raw       string
+	Symbology Symbology
+	// Code is what to look the product up by: UPC-A and UPC-E are
+	// expanded to EAN-13, as are GTINs that fit in 13 digits. Other codes
+	// are kept as scanned.
+	Code string
+	// The remaining fields come from GS1 application identifiers and are
+	// zero when the symbol did not carry them.
+	GTIN   string
+	Batch  string
+	Serial string
+	Expiry time.Time
+	// Weight is the net weight in kilograms.
+	Weight quantity.Quantity
+}
+
+// Parse decodes a scanner reading. Retail codes must carry a valid check
+// digit; GS1-128 and GS1 DataMatrix element strings are split into their
+// application identifiers.
+func Parse(raw string) (Scan, error) {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		return Scan{}, fmt.Errorf("%w: nothing was scanned", ErrInvalidBarcode)
+	}
+	scan := Scan{Raw: value, Symbology: Other, Code: value}
+
+	if data, ok := elementString(value); ok {
+		return parseElementString(scan, data)
+	}
+	// Scanners that drop the GS1 symbology identifier send a bare element
+	// string, which then opens with the GTIN.
+	if len(value) > 16 && strings.HasPrefix(value, "01") && ValidCheckDigit(value[2:16]) {
+		return parseElementString(scan, value)
+	}
+	if !isDigits(value) {
+		return scan, nil
+	}
+
+	switch len(value) {
+	case 8:
+		// EAN-8 and UPC-E share a length; only the check digit tells them apart.
+		if ValidCheckDigit(value) {
+			scan.Symbology = EAN8
+			return scan, nil
+		}
+		if expanded, ok := ExpandUPCE(value); ok {
+			scan.Symbology = UPCE
+			scan.Code = "0" + expanded
+			return scan, nil
+		}
+		return Scan{}, fmt.Errorf("%w: %s is not a valid EAN-8 or UPC-E code", ErrCheckDigit, value)
+	case 12:
+		scan.Symbology = UPCA
+		scan.Code = "0" + value
+	case 13:
+		scan.Symbology = EAN13
+	case 14:
+		scan.Symbology = GTIN14
+		scan.GTIN = value
+		scan.Code = shortGTIN(value)
+	default:
+		return scan, nil
+	}
+	if !ValidCheckDigit(value) {
+		return Scan{}, fmt.Errorf("%w: %s is not a valid %s code", ErrCheckDigit, value, symbologyNames[scan.Symbology])
+	}
+	return scan, nil
+}
+
+var symbologyNames = map[Symbology]string{
+	EAN8:   "EAN-8",
+	EAN13:  "EAN-13",
+	UPCA:   "UPC-A",
+	UPCE:   "UPC-E",
+	GTIN14: "GTIN-14",
+	GS1:    "GS1",
+	Other:  "barcode",
+}
+
+func (s Symbology) String() string {
+	return symbologyNames[s]
+}
+
+// ValidCheckDigit verifies the GS1 mod-10 check digit that ends code.
+// It applies to EAN-8, EAN-13, UPC-A and GTIN-14 alike.
+func ValidCheckDigit(code string) bool {
+	if len(code) < 2 || !isDigits(code) {
+		return false
+	}
+	return checkDigit(code[:len(code)-1]) == code[len(code)-1]
+}
+
+func checkDigit(body string) byte {
+	sum := 0
+	weight := 3
+	for i := len(body) - 1; i >= 0; i-- {
+		sum += int(body[i]-'0') * weight
+		weight = 4 - weight
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// ExpandUPCE turns an 8-digit UPC-E code into the UPC-A code it stands
+// for. It reports false when code is not a UPC-E code or the expansion
+// fails its check digit.
+func ExpandUPCE(code string) (string, bool) {
+	if len(code) != 8 || !isDigits(code) || (code[0] != '0' && code[0] != '1') {
+		return "", false
+	}
+	system, d, check := code[:1], code[1:7], code[7:]
+	var body string
+	switch d[5] {
+	case '0', '1', '2':
+		body = d[0:2] + d[5:6] + "0000" + d[2:5]
+	case '3':
+		body = d[0:3] + "00000" + d[3:5]
+	case '4':
+		body = d[0:4] + "00000" + d[4:5]
+	default:
+		body = d[0:5] + "0000" + d[5:6]
+	}
+	expanded := system + body + check
+	return expanded, ValidCheckDigit(expanded)
+}
+
+// shortGTIN drops the leading zero of a GTIN-14 so it matches the EAN-13
+// printed on the same item. GTINs with a packaging indicator are kept whole.
+func shortGTIN(gtin string) string {
+	if len(gtin) == 14 && gtin[0] == '0' {
+		return gtin[1:]
+	}
+	return gtin
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
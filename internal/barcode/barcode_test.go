@@ -0,0 +1,96 @@
+package barcode
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		raw       string
+		symbology Symbology
+		code      string
+		gtin      string
+	}{
+		{"4006381333931", EAN13, "4006381333931", ""},
+		{" 4006381333931\n", EAN13, "4006381333931", ""},
+		{"036000291452", UPCA, "0036000291452", ""},
+		{"96385074", EAN8, "96385074", ""},
+		{"04252614", UPCE, "0042100005264", ""},
+		{"01000009", EAN8, "01000009", ""},
+		{"10012345678902", GTIN14, "10012345678902", "10012345678902"},
+		{"00012345678905", GTIN14, "0012345678905", "00012345678905"},
+		{"4011", Other, "4011", ""},
+		{"SKU-123", Other, "SKU-123", ""},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.raw)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", tt.raw, err)
+			continue
+		}
+		if got.Symbology != tt.symbology || got.Code != tt.code || got.GTIN != tt.gtin {
+			t.Errorf("Parse(%q) = %s %q GTIN %q, want %s %q GTIN %q",
+				tt.raw, got.Symbology, got.Code, got.GTIN, tt.symbology, tt.code, tt.gtin)
+		}
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	for _, raw := range []string{"", "  "} {
+		if _, err := Parse(raw); !errors.Is(err, ErrInvalidBarcode) {
+			t.Errorf("Parse(%q) error = %v, want ErrInvalidBarcode", raw, err)
+		}
+	}
+}
+
+func TestParseCheckDigit(t *testing.T) {
+	for _, raw := range []string{"4006381333932", "036000291453", "12345678", "10012345678903"} {
+		if _, err := Parse(raw); !errors.Is(err, ErrCheckDigit) {
+			t.Errorf("Parse(%q) error = %v, want ErrCheckDigit", raw, err)
+		}
+	}
+}
+
+func TestValidCheckDigit(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"4006381333931", true},
+		{"4006381333930", false},
+		{"036000291452", true},
+		{"96385074", true},
+		{"10012345678902", true},
+		{"0", false},
+		{"", false},
+		{"40063813339a1", false},
+	}
+	for _, tt := range tests {
+		if got := ValidCheckDigit(tt.code); got != tt.want {
+			t.Errorf("ValidCheckDigit(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestExpandUPCE(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+		ok   bool
+	}{
+		{"04252614", "042100005264", true},
+		{"01000009", "010000000009", true},
+		{"01234505", "012000003455", true},
+		{"04252615", "", false},
+		{"24252614", "", false},
+		{"0425261", "", false},
+		{"0425261x", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ExpandUPCE(tt.code)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("ExpandUPCE(%q) = %q, %v, want %q, %v", tt.code, got, ok, tt.want, tt.ok)
+		}
+	}
+}
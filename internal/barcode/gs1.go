@@ -0,0 +1,238 @@
+package barcode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"pos-system/internal/quantity"
+)
+
+// groupSeparator is how scanners transmit FNC1 after a variable-length field.
+const groupSeparator = "\x1d"
+
+// symbologyIdentifiers prefix GS1-128, GS1 DataMatrix, GS1 QR and GS1
+// DataBar readings when the scanner is set to send them.
+var symbologyIdentifiers = []string{"]C1", "]d2", "]Q3", "]e0"}
+
+// applicationIdentifier describes one GS1 AI. Fixed-length fields have
+// length set; variable-length ones run to a group separator or the end,
+// up to maxLength characters.
+type applicationIdentifier struct {
+	length    int
+	maxLength int
+}
+
+var applicationIdentifiers = map[string]applicationIdentifier{
+	"00": {length: 18},
+	"01": {length: 14},
+	"02": {length: 14},
+	"10": {maxLength: 20},
+	"11": {length: 6},
+	"13": {length: 6},
+	"15": {length: 6},
+	"17": {length: 6},
+	"21": {maxLength: 20},
+	"30": {maxLength: 8},
+	"37": {maxLength: 8},
+}
+
+// predefinedLengths are the total lengths, AI included, of the GS1 fields
+// that never end in a separator, by the AI's first two digits. Unknown AIs
+// are skipped by these lengths, or else up to the next separator.
+var predefinedLengths = map[string]int{
+	"00": 20, "01": 16, "02": 16, "03": 16, "04": 18,
+	"11": 8, "12": 8, "13": 8, "14": 8, "15": 8, "16": 8, "17": 8, "18": 8, "19": 8,
+	"20": 4,
+	"31": 10, "32": 10, "33": 10, "34": 10, "35": 10, "36": 10,
+	"41": 16,
+}
+
+// elementString reports whether value is a GS1 element string and, if so,
+// returns it without its symbology identifier.
+func elementString(value string) (string, bool) {
+	for _, prefix := range symbologyIdentifiers {
+		if strings.HasPrefix(value, prefix) {
+			return value[len(prefix):], true
+		}
+	}
+	if strings.HasPrefix(value, "(") || strings.Contains(value, groupSeparator) {
+		return value, true
+	}
+	return "", false
+}
+
+// parseElementString fills scan from GS1 data, either as transmitted
+// ("0109501101530003" "10ABC" FNC1 ...) or in the human-readable form
+// printed under the symbol ("(01)09501101530003(10)ABC").
+func parseElementString(scan Scan, data string) (Scan, error) {
+	scan.Symbology = GS1
+	fields, err := splitElementString(strings.TrimPrefix(data, groupSeparator))
+	if err != nil {
+		return Scan{}, err
+	}
+
+	for _, field := range fields {
+		ai, value := field[0], field[1]
+		switch {
+		case ai == "01":
+			if !ValidCheckDigit(value) {
+				return Scan{}, fmt.Errorf("%w: GTIN %s", ErrCheckDigit, value)
+			}
+			scan.GTIN = value
+			scan.Code = shortGTIN(value)
+		case ai == "10":
+			scan.Batch = value
+		case ai == "21":
+			scan.Serial = value
+		case ai == "17":
+			expiry, err := gs1Date(value)
+			if err != nil {
+				return Scan{}, err
+			}
+			scan.Expiry = expiry
+		case strings.HasPrefix(ai, "310"):
+			weight, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return Scan{}, fmt.Errorf("%w: weight %q", ErrInvalidBarcode, value)
+			}
+			// The fourth AI digit is the number of decimals in kilograms.
+			scan.Weight = scaleWeight(weight, int(ai[3]-'0'))
+		}
+	}
+	if scan.GTIN == "" {
+		return Scan{}, fmt.Errorf("%w: GS1 code has no GTIN (01)", ErrInvalidBarcode)
+	}
+	return scan, nil
+}
+
+// splitElementString breaks data into [AI, value] pairs. Fields with an
+// AI this package does not read are left out.
+func splitElementString(data string) ([][2]string, error) {
+	var fields [][2]string
+	if strings.HasPrefix(data, "(") {
+		for data != "" {
+			end := strings.Index(data, ")")
+			if !strings.HasPrefix(data, "(") || end < 0 {
+				return nil, fmt.Errorf("%w: malformed GS1 text %q", ErrInvalidBarcode, data)
+			}
+			ai := data[1:end]
+			data = data[end+1:]
+			next := strings.Index(data, "(")
+			if next < 0 {
+				next = len(data)
+			}
+			value := data[:next]
+			data = data[next:]
+			if known, _, ok := lookupAI(ai); !ok || known != ai {
+				continue
+			}
+			if err := checkField(ai, value); err != nil {
+				return nil, err
+			}
+			fields = append(fields, [2]string{ai, value})
+		}
+		return fields, nil
+	}
+
+	for data != "" {
+		ai, spec, ok := lookupAI(data)
+		if !ok {
+			data = skipField(data)
+			continue
+		}
+		data = data[len(ai):]
+		var value string
+		if spec.length > 0 {
+			if len(data) < spec.length {
+				return nil, fmt.Errorf("%w: GS1 field (%s) is too short", ErrInvalidBarcode, ai)
+			}
+			value, data = data[:spec.length], data[spec.length:]
+		} else {
+			end := strings.Index(data, groupSeparator)
+			if end < 0 {
+				end = len(data)
+			}
+			value, data = data[:end], data[end:]
+		}
+		data = strings.TrimPrefix(data, groupSeparator)
+		if err := checkField(ai, value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, [2]string{ai, value})
+	}
+	return fields, nil
+}
+
+// skipField drops the field data opens with when its AI is unknown.
+func skipField(data string) string {
+	if len(data) >= 2 {
+		if length, ok := predefinedLengths[data[:2]]; ok {
+			if length >= len(data) {
+				return ""
+			}
+			return strings.TrimPrefix(data[length:], groupSeparator)
+		}
+	}
+	end := strings.Index(data, groupSeparator)
+	if end < 0 {
+		return ""
+	}
+	return data[end+len(groupSeparator):]
+}
+
+func lookupAI(data string) (string, applicationIdentifier, bool) {
+	// Net weight in kg is 310n, where n is the decimal point position.
+	if len(data) >= 4 && strings.HasPrefix(data, "310") && data[3] >= '0' && data[3] <= '6' {
+		return data[:4], applicationIdentifier{length: 6}, true
+	}
+	if len(data) < 2 {
+		return "", applicationIdentifier{}, false
+	}
+	spec, ok := applicationIdentifiers[data[:2]]
+	return data[:2], spec, ok
+}
+
+func checkField(ai string, value string) error {
+	known, spec, ok := lookupAI(ai)
+	if !ok || known != ai {
+		return fmt.Errorf("%w: unknown GS1 application identifier (%s)", ErrInvalidBarcode, ai)
+	}
+	switch {
+	case spec.length > 0 && len(value) != spec.length:
+		return fmt.Errorf("%w: GS1 field (%s) needs %d characters", ErrInvalidBarcode, ai, spec.length)
+	case spec.maxLength > 0 && (value == "" || len(value) > spec.maxLength):
+		return fmt.Errorf("%w: GS1 field (%s) takes 1 to %d characters", ErrInvalidBarcode, ai, spec.maxLength)
+	}
+	return nil
+}
+
+// gs1Date reads YYMMDD. A day of 00 means the end of the month.
+func gs1Date(value string) (time.Time, error) {
+	if !isDigits(value) {
+		return time.Time{}, fmt.Errorf("%w: date %q", ErrInvalidBarcode, value)
+	}
+	year, _ := strconv.Atoi(value[0:2])
+	month, _ := strconv.Atoi(value[2:4])
+	day, _ := strconv.Atoi(value[4:6])
+	if month < 1 || month > 12 || day > 31 {
+		return time.Time{}, fmt.Errorf("%w: date %q", ErrInvalidBarcode, value)
+	}
+	if day == 0 {
+		return time.Date(2000+year, time.Month(month)+1, 0, 0, 0, 0, 0, time.Local), nil
+	}
+	return time.Date(2000+year, time.Month(month), day, 0, 0, 0, 0, time.Local), nil
+}
+
+// scaleWeight converts a weight with the given number of decimals into
+// thousandths of a kilogram.
+func scaleWeight(value int64, decimals int) quantity.Quantity {
+	for ; decimals < 3; decimals++ {
+		value *= 10
+	}
+	for ; decimals > 3; decimals-- {
+		value = (value + 5) / 10
+	}
+	return quantity.Quantity(value)
+}
@@ -0,0 +1,98 @@
+package barcode
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"pos-system/internal/quantity"
+)
+
+func TestParseElementString(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    string
+		code   string
+		batch  string
+		serial string
+		expiry time.Time
+		weight quantity.Quantity
+	}{
+		{
+			name: "transmitted with separators",
+			raw:  "]C1010950110153000317251231" + "10ABC123\x1d21XYZ",
+			code: "9501101530003", batch: "ABC123", serial: "XYZ",
+			expiry: time.Date(2025, 12, 31, 0, 0, 0, 0, time.Local),
+		},
+		{
+			name: "human readable",
+			raw:  "(01)09501101530003(10)ABC(17)250200",
+			code: "9501101530003", batch: "ABC",
+			expiry: time.Date(2025, 2, 28, 0, 0, 0, 0, time.Local),
+		},
+		{
+			name: "bare element string",
+			raw:  "010950110153000310ABC",
+			code: "9501101530003", batch: "ABC",
+		},
+		{
+			name:   "net weight",
+			raw:    "(01)09501101530003(3103)001250",
+			code:   "9501101530003",
+			weight: 1250,
+		},
+		{
+			name:   "net weight with two decimals",
+			raw:    "]C101095011015300033102001250",
+			code:   "9501101530003",
+			weight: 12500,
+		},
+		{
+			name: "unknown human readable AI is skipped",
+			raw:  "(01)09501101530003(240)XYZ(10)B1",
+			code: "9501101530003", batch: "B1",
+		},
+		{
+			name: "unknown fixed-length AI is skipped",
+			raw:  "]C1010950110153000312250101" + "10B1",
+			code: "9501101530003", batch: "B1",
+		},
+		{
+			name: "unknown variable-length AI is skipped to the separator",
+			raw:  "]C10109501101530003" + "240ABC\x1d10B1",
+			code: "9501101530003", batch: "B1",
+		},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.raw)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got.Symbology != GS1 || got.Code != tt.code || got.Batch != tt.batch || got.Serial != tt.serial ||
+			!got.Expiry.Equal(tt.expiry) || got.Weight != tt.weight {
+			t.Errorf("%s: got %+v", tt.name, got)
+		}
+	}
+}
+
+func TestParseElementStringInvalid(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want error
+	}{
+		{"(01)09501101530004", ErrCheckDigit},
+		{"(10)ABC", ErrInvalidBarcode},
+		{"(01)09501101530003(17)251301", ErrInvalidBarcode},
+		{"(01)09501101530003(11)2501", ErrInvalidBarcode},
+		{"(01)09501101530003(10)", ErrInvalidBarcode},
+		{"(01", ErrInvalidBarcode},
+		{"]C10109501101530", ErrInvalidBarcode},
+		{"]C101095011015300033103abcdef", ErrInvalidBarcode},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.raw); !errors.Is(err, tt.want) {
+			t.Errorf("Parse(%q) error = %v, want %v", tt.raw, err, tt.want)
+		}
+	}
+}
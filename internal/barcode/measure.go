@@ -0,0 +1,42 @@
+package barcode
+
+import (
+	"strconv"
+
+	"pos-system/internal/money"
+	"pos-system/internal/quantity"
+)
+
+// VariableMeasure is a GS1 restricted-circulation EAN-13 printed by a
+// scale or price-labelling machine. Digits 1-2 are the prefix (20-29),
+// 3-7 the item code, 8-12 the embedded value and 13 the check digit.
+// Prefixes 20-24 embed a weight in grams, 25-29 a price in cents.
+type VariableMeasure struct {
+	ItemCode string
+	// Priced is set when the code embeds Price rather than Weight.
+	Priced bool
+	Weight quantity.Quantity
+	Price  money.Money
+}
+
+// ParseVariableMeasure decodes code if it is a variable-measure EAN-13
+// with a valid check digit.
+func ParseVariableMeasure(code string) (VariableMeasure, bool) {
+	if len(code) != 13 || code[0] != '2' || !ValidCheckDigit(code) {
+		return VariableMeasure{}, false
+	}
+	value, err := strconv.ParseInt(code[7:12], 10, 64)
+	if err != nil {
+		return VariableMeasure{}, false
+	}
+
+	measure := VariableMeasure{ItemCode: code[2:7]}
+	if code[1] <= '4' {
+		// A gram is a thousandth of a kilogram.
+		measure.Weight = quantity.Quantity(value)
+	} else {
+		measure.Priced = true
+		measure.Price = money.New(value, money.DefaultCurrency)
+	}
+	return measure, true
+}
@@ -0,0 +1,38 @@
+package barcode
+
+import "testing"
+
+func TestParseVariableMeasure(t *testing.T) {
+	tests := []struct {
+		code   string
+		ok     bool
+		item   string
+		priced bool
+		value  int64
+	}{
+		{"2012345012509", true, "12345", false, 1250},
+		{"2512345003991", true, "12345", true, 399},
+		{"2800000000004", true, "00000", true, 0},
+		{"2012345012508", false, "", false, 0},
+		{"4006381333931", false, "", false, 0},
+		{"201234501250", false, "", false, 0},
+		{"20123450125a9", false, "", false, 0},
+	}
+	for _, tt := range tests {
+		got, ok := ParseVariableMeasure(tt.code)
+		if ok != tt.ok {
+			t.Errorf("ParseVariableMeasure(%q) ok = %v, want %v", tt.code, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		value := int64(got.Weight)
+		if got.Priced {
+			value = got.Price.Amount
+		}
+		if got.ItemCode != tt.item || got.Priced != tt.priced || value != tt.value {
+			t.Errorf("ParseVariableMeasure(%q) = %+v", tt.code, got)
+		}
+	}
+}
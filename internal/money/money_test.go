@@ -0,0 +1,153 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"12.34", 1234},
+		{"12", 1200},
+		{"12.3", 1230},
+		{".5", 50},
+		{"0.005", 1},
+		{"0.004", 0},
+		{"1.995", 200},
+		{"-0.5", -50},
+		{"-1.005", -101},
+		{"+3.10", 310},
+		{"  7.25 ", 725},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in, "EUR")
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got.Amount != tt.want || got.Currency != "EUR" {
+			t.Errorf("Parse(%q) = %d %s, want %d EUR", tt.in, got.Amount, got.Currency, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, in := range []string{"", " ", "-", ".", "abc", "1.2.3", "1,50", "1e3", "--1", "12.3x"} {
+		if _, err := Parse(in, "USD"); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("Parse(%q) error = %v, want ErrInvalidAmount", in, err)
+		}
+	}
+}
+
+func TestParseDefaultCurrency(t *testing.T) {
+	got, err := Parse("1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Currency != DefaultCurrency {
+		t.Errorf("currency = %q, want %q", got.Currency, DefaultCurrency)
+	}
+}
+
+func TestMulDiv(t *testing.T) {
+	tests := []struct {
+		amount, num, den int64
+		want             int64
+	}{
+		{1000, 1, 3, 333},
+		{1000, 2, 3, 667},
+		{5, 1, 2, 3},
+		{-5, 1, 2, -3},
+		{5, -1, 2, -3},
+		{5, 1, -2, -3},
+		{-5, -1, 2, 3},
+		{7, 1, 4, 2},
+		{-7, 1, 4, -2},
+		{1999, 2000, 10000, 400},
+		{0, 5, 7, 0},
+		{1234, 5, 0, 0},
+		{-1234, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		got := New(tt.amount, "USD").MulDiv(tt.num, tt.den)
+		if got.Amount != tt.want {
+			t.Errorf("%d.MulDiv(%d, %d) = %d, want %d", tt.amount, tt.num, tt.den, got.Amount, tt.want)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	a, b := New(1050, "USD"), New(-275, "USD")
+	if got := a.Add(b); got != New(775, "USD") {
+		t.Errorf("Add = %v", got)
+	}
+	if got := a.Sub(b); got != New(1325, "USD") {
+		t.Errorf("Sub = %v", got)
+	}
+	if got := b.Mul(3); got != New(-825, "USD") {
+		t.Errorf("Mul = %v", got)
+	}
+	if got := b.Neg(); got != New(275, "USD") {
+		t.Errorf("Neg = %v", got)
+	}
+	if got := (Money{Amount: 5}).Add(a); got.Currency != "USD" {
+		t.Errorf("Add without currency took %q", got.Currency)
+	}
+}
+
+func TestAddCurrencyMismatchPanics(t *testing.T) {
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, ErrCurrencyMismatch) {
+			t.Errorf("recovered %v, want ErrCurrencyMismatch", err)
+		}
+	}()
+	New(1, "USD").Add(New(1, "EUR"))
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		amount int64
+		want   string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{1234, "12.34"},
+		{-5, "-0.05"},
+		{-1234, "-12.34"},
+	}
+	for _, tt := range tests {
+		if got := New(tt.amount, "USD").String(); got != tt.want {
+			t.Errorf("String(%d) = %q, want %q", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(New(-1234, "GBP"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var round Money
+	if err := json.Unmarshal(data, &round); err != nil {
+		t.Fatal(err)
+	}
+	if round != New(-1234, "GBP") {
+		t.Errorf("round trip = %v", round)
+	}
+
+	var legacy Money
+	if err := json.Unmarshal([]byte(`12.5`), &legacy); err != nil {
+		t.Fatal(err)
+	}
+	if legacy != New(1250, DefaultCurrency) {
+		t.Errorf("legacy = %v", legacy)
+	}
+	if err := json.Unmarshal([]byte(`"abc"`), &legacy); err == nil {
+		t.Error("expected an error for a malformed amount")
+	}
+}
@@ -0,0 +1,268 @@
+package promo
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"pos-system/internal/money"
+	"pos-system/internal/quantity"
+)
+
+type Kind string
+
+const (
+	PercentOff    Kind = "percent_off"
+	AmountOff     Kind = "amount_off"
+	BuyXGetY      Kind = "buy_x_get_y"
+	MixAndMatch   Kind = "mix_and_match"
+	CartThreshold Kind = "cart_threshold"
+)
+
+var Kinds = []Kind{PercentOff, AmountOff, BuyXGetY, MixAndMatch, CartThreshold}
+
+var ErrInvalidPromotion = errors.New("invalid promotion")
+
+// Promotion is a discount rule. Which fields matter depends on Kind:
+//   - PercentOff: PercentBP off each matching line.
+//   - AmountOff: Amount off each matching unit, pro rata for weighed lines.
+//   - BuyXGetY: for every BuyQty+GetQty whole units of a line, GetQty are free.
+//   - MixAndMatch: any BuyQty matching whole units together cost Amount.
+//   - CartThreshold: once matching lines reach Threshold, PercentBP or
+//     Amount off their total.
+//
+// An empty ProductIDs matches every product. Listing a parent product
+// matches all of its variants.
+type Promotion struct {
+	ID         int64
+	Name       string
+	Kind       Kind
+	PercentBP  int64
+	Amount     money.Money
+	BuyQty     int64
+	GetQty     int64
+	Threshold  money.Money
+	ProductIDs []int64
+}
+
+type Line struct {
+	ProductID int64
+	ParentID  int64
+	Quantity  quantity.Quantity
+	UnitPrice money.Money
+}
+
+type Discount struct {
+	PromotionID int64
+	LineIndex   int
+	Amount      money.Money
+}
+
+func (p Promotion) Validate() error {
+	switch p.Kind {
+	case PercentOff:
+		if p.PercentBP <= 0 || p.PercentBP > 10000 {
+			return fmt.Errorf("%w: percentage must be between 0 and 100", ErrInvalidPromotion)
+		}
+	case AmountOff:
+		if p.Amount.Amount <= 0 {
+			return fmt.Errorf("%w: amount must be positive", ErrInvalidPromotion)
+		}
+	case BuyXGetY:
+		if p.BuyQty <= 0 || p.GetQty <= 0 {
+			return fmt.Errorf("%w: buy and get quantities must be positive", ErrInvalidPromotion)
+		}
+	case MixAndMatch:
+		if p.BuyQty <= 1 || p.Amount.Amount <= 0 {
+			return fmt.Errorf("%w: bundle needs at least two items and a positive price", ErrInvalidPromotion)
+		}
+	case CartThreshold:
+		if p.Threshold.Amount <= 0 {
+			return fmt.Errorf("%w: threshold must be positive", ErrInvalidPromotion)
+		}
+		if (p.PercentBP <= 0) == (p.Amount.Amount <= 0) {
+			return fmt.Errorf("%w: set either a percentage or an amount", ErrInvalidPromotion)
+		}
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidPromotion, p.Kind)
+	}
+	return nil
+}
+
+func (p Promotion) matches(line Line) bool {
+	if len(p.ProductIDs) == 0 {
+		return true
+	}
+	for _, id := range p.ProductIDs {
+		if id == line.ProductID || (line.ParentID != 0 && id == line.ParentID) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply evaluates promotions in order against the cart lines. Line-level
+// rules run first and do not stack: the first rule that discounts a line
+// claims it. Cart thresholds run last on whatever is left of each line.
+func Apply(promotions []Promotion, lines []Line) []Discount {
+	claimed := make([]bool, len(lines))
+	remaining := make([]int64, len(lines))
+	for i, line := range lines {
+		remaining[i] = line.Quantity.Times(line.UnitPrice).Amount
+	}
+
+	var discounts []Discount
+	add := func(promotionID int64, index int, amount int64) {
+		if amount > remaining[index] {
+			amount = remaining[index]
+		}
+		if amount <= 0 {
+			return
+		}
+		remaining[index] -= amount
+		claimed[index] = true
+		discounts = append(discounts, Discount{
+			PromotionID: promotionID,
+			LineIndex:   index,
+			Amount:      money.New(amount, lines[index].UnitPrice.Currency),
+		})
+	}
+
+	ordered := make([]Promotion, 0, len(promotions))
+	for _, p := range promotions {
+		if p.Kind != CartThreshold {
+			ordered = append(ordered, p)
+		}
+	}
+	for _, p := range promotions {
+		if p.Kind == CartThreshold {
+			ordered = append(ordered, p)
+		}
+	}
+
+	for _, p := range ordered {
+		eligible := func(i int) bool {
+			return !claimed[i] && p.matches(lines[i])
+		}
+		switch p.Kind {
+		case PercentOff:
+			for i := range lines {
+				if eligible(i) {
+					add(p.ID, i, mulDiv(remaining[i], p.PercentBP, 10000))
+				}
+			}
+		case AmountOff:
+			for i, line := range lines {
+				if eligible(i) {
+					add(p.ID, i, line.Quantity.Times(p.Amount).Amount)
+				}
+			}
+		case BuyXGetY:
+			for i, line := range lines {
+				if eligible(i) {
+					free := line.Quantity.Units() / (p.BuyQty + p.GetQty) * p.GetQty
+					add(p.ID, i, free*line.UnitPrice.Amount)
+				}
+			}
+		case MixAndMatch:
+			for index, amount := range mixAndMatch(p, lines, eligible) {
+				add(p.ID, index, amount)
+			}
+		case CartThreshold:
+			var matched []int
+			var total int64
+			for i, line := range lines {
+				if p.matches(line) && remaining[i] > 0 {
+					matched = append(matched, i)
+					total += remaining[i]
+				}
+			}
+			if total == 0 || total < p.Threshold.Amount {
+				continue
+			}
+			off := p.Amount.Amount
+			if p.PercentBP > 0 {
+				off = mulDiv(total, p.PercentBP, 10000)
+			}
+			if off > total {
+				off = total
+			}
+			weights := make([]int64, len(matched))
+			for i, index := range matched {
+				weights[i] = remaining[index]
+			}
+			for i, share := range allocate(off, weights) {
+				add(p.ID, matched[i], share)
+			}
+		}
+	}
+
+	return discounts
+}
+
+// mixAndMatch groups matching units into bundles of BuyQty, most expensive
+// first, and spreads each bundle's saving over the lines it drew from.
+func mixAndMatch(p Promotion, lines []Line, eligible func(int) bool) map[int]int64 {
+	type unit struct {
+		index int
+		price int64
+	}
+	var units []unit
+	for i, line := range lines {
+		if !eligible(i) {
+			continue
+		}
+		for n := int64(0); n < line.Quantity.Units(); n++ {
+			units = append(units, unit{index: i, price: line.UnitPrice.Amount})
+		}
+	}
+	sort.SliceStable(units, func(a, b int) bool { return units[a].price > units[b].price })
+
+	result := make(map[int]int64)
+	for start := 0; start+int(p.BuyQty) <= len(units); start += int(p.BuyQty) {
+		bundle := units[start : start+int(p.BuyQty)]
+		var value int64
+		weights := make([]int64, len(bundle))
+		for i, u := range bundle {
+			value += u.price
+			weights[i] = u.price
+		}
+		saving := value - p.Amount.Amount
+		if saving <= 0 {
+			continue
+		}
+		for i, share := range allocate(saving, weights) {
+			result[bundle[i].index] += share
+		}
+	}
+	return result
+}
+
+// allocate splits total across weights pro rata; leftover cents go to the
+// earliest entries so the shares always add up to total.
+func allocate(total int64, weights []int64) []int64 {
+	var sum int64
+	for _, w := range weights {
+		sum += w
+	}
+	shares := make([]int64, len(weights))
+	if sum == 0 {
+		return shares
+	}
+	var assigned int64
+	for i, w := range weights {
+		shares[i] = total * w / sum
+		assigned += shares[i]
+	}
+	for i := 0; assigned < total; i = (i + 1) % len(shares) {
+		if weights[i] > 0 {
+			shares[i]++
+			assigned++
+		}
+	}
+	return shares
+}
+
+func mulDiv(value, numerator, denominator int64) int64 {
+	return money.New(value, "").MulDiv(numerator, denominator).Amount
+}
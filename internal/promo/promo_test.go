@@ -0,0 +1,195 @@
+package promo
+
+import (
+	"errors"
+	"testing"
+
+	"pos-system/internal/money"
+	"pos-system/internal/quantity"
+)
+
+func usd(cents int64) money.Money {
+	return money.New(cents, "USD")
+}
+
+func line(productID int64, qty string, cents int64) Line {
+	q, err := quantity.Parse(qty)
+	if err != nil {
+		panic(err)
+	}
+	return Line{ProductID: productID, Quantity: q, UnitPrice: usd(cents)}
+}
+
+// byLine totals discounts by line index and promotion ID.
+func byLine(discounts []Discount) map[[2]int64]int64 {
+	totals := make(map[[2]int64]int64)
+	for _, d := range discounts {
+		totals[[2]int64{int64(d.LineIndex), d.PromotionID}] += d.Amount.Amount
+	}
+	return totals
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name       string
+		promotions []Promotion
+		lines      []Line
+		want       map[[2]int64]int64
+	}{
+		{
+			name:       "percent off rounds half up",
+			promotions: []Promotion{{ID: 1, Kind: PercentOff, PercentBP: 1000}},
+			lines:      []Line{line(1, "3", 333)},
+			want:       map[[2]int64]int64{{0, 1}: 100},
+		},
+		{
+			name:       "amount off is pro rata for weighed lines",
+			promotions: []Promotion{{ID: 1, Kind: AmountOff, Amount: usd(50)}},
+			lines:      []Line{line(1, "1.5", 200)},
+			want:       map[[2]int64]int64{{0, 1}: 75},
+		},
+		{
+			name:       "amount off never exceeds the line",
+			promotions: []Promotion{{ID: 1, Kind: AmountOff, Amount: usd(200)}},
+			lines:      []Line{line(1, "2", 100)},
+			want:       map[[2]int64]int64{{0, 1}: 200},
+		},
+		{
+			name:       "buy two get one",
+			promotions: []Promotion{{ID: 1, Kind: BuyXGetY, BuyQty: 2, GetQty: 1}},
+			lines:      []Line{line(1, "7", 100)},
+			want:       map[[2]int64]int64{{0, 1}: 200},
+		},
+		{
+			name: "line rules do not stack",
+			promotions: []Promotion{
+				{ID: 1, Kind: PercentOff, PercentBP: 1000},
+				{ID: 2, Kind: AmountOff, Amount: usd(10)},
+			},
+			lines: []Line{line(1, "1", 1000)},
+			want:  map[[2]int64]int64{{0, 1}: 100},
+		},
+		{
+			name:       "parent product matches its variants",
+			promotions: []Promotion{{ID: 1, Kind: PercentOff, PercentBP: 5000, ProductIDs: []int64{10}}},
+			lines: []Line{
+				{ProductID: 11, ParentID: 10, Quantity: quantity.Of(1), UnitPrice: usd(400)},
+				line(12, "1", 400),
+			},
+			want: map[[2]int64]int64{{0, 1}: 200},
+		},
+		{
+			name:       "mix and match bundles the dearest units",
+			promotions: []Promotion{{ID: 1, Kind: MixAndMatch, BuyQty: 3, Amount: usd(500)}},
+			lines:      []Line{line(1, "2", 300), line(2, "2", 200)},
+			want:       map[[2]int64]int64{{0, 1}: 225, {1, 1}: 75},
+		},
+		{
+			name:       "mix and match skips bundles that cost more",
+			promotions: []Promotion{{ID: 1, Kind: MixAndMatch, BuyQty: 2, Amount: usd(500)}},
+			lines:      []Line{line(1, "2", 200)},
+			want:       map[[2]int64]int64{},
+		},
+		{
+			name:       "cart threshold spreads pro rata",
+			promotions: []Promotion{{ID: 1, Kind: CartThreshold, Threshold: usd(1000), PercentBP: 1000}},
+			lines:      []Line{line(1, "1", 600), line(2, "1", 500)},
+			want:       map[[2]int64]int64{{0, 1}: 60, {1, 1}: 50},
+		},
+		{
+			name:       "cart threshold not reached",
+			promotions: []Promotion{{ID: 1, Kind: CartThreshold, Threshold: usd(1000), Amount: usd(100)}},
+			lines:      []Line{line(1, "1", 900)},
+			want:       map[[2]int64]int64{},
+		},
+		{
+			name: "cart threshold runs last on what is left",
+			promotions: []Promotion{
+				{ID: 1, Kind: CartThreshold, Threshold: usd(1000), Amount: usd(300)},
+				{ID: 2, Kind: PercentOff, PercentBP: 5000, ProductIDs: []int64{1}},
+			},
+			lines: []Line{line(1, "1", 1000), line(2, "1", 1000)},
+			want:  map[[2]int64]int64{{0, 2}: 500, {0, 1}: 100, {1, 1}: 200},
+		},
+		{
+			name:       "zero quantity gets nothing",
+			promotions: []Promotion{{ID: 1, Kind: PercentOff, PercentBP: 1000}},
+			lines:      []Line{line(1, "0", 1000)},
+			want:       map[[2]int64]int64{},
+		},
+		{
+			name:       "no lines",
+			promotions: []Promotion{{ID: 1, Kind: PercentOff, PercentBP: 1000}},
+			want:       map[[2]int64]int64{},
+		},
+	}
+	for _, tt := range tests {
+		got := byLine(Apply(tt.promotions, tt.lines))
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for key, amount := range tt.want {
+			if got[key] != amount {
+				t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := []Promotion{
+		{Kind: PercentOff, PercentBP: 10000},
+		{Kind: AmountOff, Amount: usd(1)},
+		{Kind: BuyXGetY, BuyQty: 1, GetQty: 1},
+		{Kind: MixAndMatch, BuyQty: 2, Amount: usd(1)},
+		{Kind: CartThreshold, Threshold: usd(1), PercentBP: 1},
+		{Kind: CartThreshold, Threshold: usd(1), Amount: usd(1)},
+	}
+	for _, p := range valid {
+		if err := p.Validate(); err != nil {
+			t.Errorf("%+v: %v", p, err)
+		}
+	}
+	invalid := []Promotion{
+		{Kind: PercentOff},
+		{Kind: PercentOff, PercentBP: 10001},
+		{Kind: PercentOff, PercentBP: -5},
+		{Kind: AmountOff, Amount: usd(-1)},
+		{Kind: BuyXGetY, BuyQty: 2},
+		{Kind: MixAndMatch, BuyQty: 1, Amount: usd(1)},
+		{Kind: MixAndMatch, BuyQty: 2},
+		{Kind: CartThreshold, PercentBP: 1},
+		{Kind: CartThreshold, Threshold: usd(1)},
+		{Kind: CartThreshold, Threshold: usd(1), PercentBP: 1, Amount: usd(1)},
+		{Kind: "bogus"},
+	}
+	for _, p := range invalid {
+		if err := p.Validate(); !errors.Is(err, ErrInvalidPromotion) {
+			t.Errorf("%+v: error = %v, want ErrInvalidPromotion", p, err)
+		}
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		total   int64
+		weights []int64
+		want    []int64
+	}{
+		{10, []int64{1, 1, 1}, []int64{4, 3, 3}},
+		{300, []int64{300, 300, 200}, []int64{113, 112, 75}},
+		{5, []int64{0, 1}, []int64{0, 5}},
+		{5, []int64{0, 0}, []int64{0, 0}},
+	}
+	for _, tt := range tests {
+		got := allocate(tt.total, tt.weights)
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Errorf("allocate(%d, %v) = %v, want %v", tt.total, tt.weights, got, tt.want)
+				break
+			}
+		}
+	}
+}
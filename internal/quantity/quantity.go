@@ -0,0 +1,171 @@
+package quantity
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"pos-system/internal/money"
+)
+
+const (
+	// Scale is the number of Quantity steps in one unit.
+	Scale  = 1000
+	digits = 3
+)
+
+var ErrInvalidQuantity = errors.New("invalid quantity")
+
+// Quantity is an amount of a product in thousandths of its unit of
+// measure: 3 items is 3000, 1.25 kg is 1250.
+type Quantity int64
+
+type Unit string
+
+const (
+	Each     Unit = "each"
+	Kilogram Unit = "kg"
+	Gram     Unit = "g"
+	Pound    Unit = "lb"
+	Litre    Unit = "l"
+	Metre    Unit = "m"
+)
+
+var Units = []Unit{Each, Kilogram, Gram, Pound, Litre, Metre}
+
+// Whole reports whether the unit can only be sold in whole numbers.
+func (u Unit) Whole() bool {
+	return u == Each || u == ""
+}
+
+func Of(units int64) Quantity {
+	return Quantity(units * Scale)
+}
+
+// Parse reads a decimal string such as "2" or "0.375" without going
+// through float64. Digits beyond the third decimal are rounded half away
+// from zero.
+func Parse(value string) (Quantity, error) {
+	value = strings.TrimSpace(value)
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "-"), "+")
+	whole, fraction, _ := strings.Cut(value, ".")
+	if whole == "" && fraction == "" {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidQuantity, value)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+
+	roundUp := false
+	if len(fraction) > digits {
+		roundUp = fraction[digits] >= '5'
+		if !isDigits(fraction[digits:]) {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidQuantity, value)
+		}
+		fraction = fraction[:digits]
+	}
+	fraction += strings.Repeat("0", digits-len(fraction))
+	if !isDigits(whole) || !isDigits(fraction) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidQuantity, value)
+	}
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidQuantity, value)
+	}
+	thousandths, err := strconv.ParseInt(fraction, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidQuantity, value)
+	}
+
+	q := units*Scale + thousandths
+	if roundUp {
+		q++
+	}
+	if negative {
+		q = -q
+	}
+	return Quantity(q), nil
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (q Quantity) IsWhole() bool {
+	return q%Scale == 0
+}
+
+// Units is the whole number of units in q, rounded towards zero.
+func (q Quantity) Units() int64 {
+	return int64(q) / Scale
+}
+
+// Mul scales q by factor, e.g. 3 packs of 24 units is 72 units. The result
+// is rounded half away from zero to the nearest thousandth.
+func (q Quantity) Mul(factor Quantity) Quantity {
+	return Quantity(mulDiv(int64(q), int64(factor), Scale))
+}
+
+// Times prices q at unitPrice per unit, rounding to the nearest minor unit.
+func (q Quantity) Times(unitPrice money.Money) money.Money {
+	return unitPrice.MulDiv(int64(q), Scale)
+}
+
+// String formats q without trailing zeros: "3", "1.25", "0.005".
+func (q Quantity) String() string {
+	value := int64(q)
+	sign := ""
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	if value%Scale == 0 {
+		return fmt.Sprintf("%s%d", sign, value/Scale)
+	}
+	fraction := strings.TrimRight(fmt.Sprintf("%0*d", digits, value%Scale), "0")
+	return fmt.Sprintf("%s%d.%s", sign, value/Scale, fraction)
+}
+
+// Format adds the unit: "x3" for items counted each, "1.25 kg" otherwise.
+func (q Quantity) Format(unit Unit) string {
+	if unit.Whole() {
+		return "x" + q.String()
+	}
+	return q.String() + " " + string(unit)
+}
+
+// micrograms per unit of mass, so conversions stay in integers.
+var micrograms = map[Unit]int64{
+	Kilogram: 1_000_000_000,
+	Gram:     1_000_000,
+	Pound:    453_592_370,
+}
+
+// Convert expresses q, measured in from, in to. It reports false when the
+// units measure different things.
+func Convert(q Quantity, from, to Unit) (Quantity, bool) {
+	if from == to {
+		return q, true
+	}
+	fromMass, ok := micrograms[from]
+	if !ok {
+		return 0, false
+	}
+	toMass, ok := micrograms[to]
+	if !ok {
+		return 0, false
+	}
+	return Quantity(mulDiv(int64(q), fromMass, toMass)), true
+}
+
+func mulDiv(value, numerator, denominator int64) int64 {
+	return money.New(value, "").MulDiv(numerator, denominator).Amount
+}
@@ -0,0 +1,135 @@
+package quantity
+
+import (
+	"errors"
+	"testing"
+
+	"pos-system/internal/money"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Quantity
+	}{
+		{"2", 2000},
+		{"0.375", 375},
+		{".5", 500},
+		{"1.", 1000},
+		{"+3", 3000},
+		{" 4 ", 4000},
+		{"1.2345", 1235},
+		{"1.2344", 1234},
+		{"0.0005", 1},
+		{"-1.5", -1500},
+		{"-0.0005", -1},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, in := range []string{"", " ", "-", ".", "abc", "1.2.3", "1.23x45", "1e3", "--1", "1,5"} {
+		if _, err := Parse(in); !errors.Is(err, ErrInvalidQuantity) {
+			t.Errorf("Parse(%q) error = %v, want ErrInvalidQuantity", in, err)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		q    Quantity
+		want string
+	}{
+		{0, "0"},
+		{3000, "3"},
+		{1250, "1.25"},
+		{5, "0.005"},
+		{-1500, "-1.5"},
+	}
+	for _, tt := range tests {
+		if got := tt.q.String(); got != tt.want {
+			t.Errorf("String(%d) = %q, want %q", tt.q, got, tt.want)
+		}
+	}
+	if got := Of(3).Format(Each); got != "x3" {
+		t.Errorf("Format(each) = %q", got)
+	}
+	if got := Quantity(1250).Format(Kilogram); got != "1.25 kg" {
+		t.Errorf("Format(kg) = %q", got)
+	}
+}
+
+func TestWhole(t *testing.T) {
+	if !Of(2).IsWhole() || Quantity(2500).IsWhole() {
+		t.Error("IsWhole")
+	}
+	if Quantity(2500).Units() != 2 || Quantity(-2500).Units() != -2 {
+		t.Error("Units rounds towards zero")
+	}
+	if !Each.Whole() || !Unit("").Whole() || Kilogram.Whole() {
+		t.Error("Unit.Whole")
+	}
+}
+
+func TestMul(t *testing.T) {
+	tests := []struct {
+		q, factor, want Quantity
+	}{
+		{Of(3), Of(24), Of(72)},
+		{500, 333, 167},
+		{-500, 333, -167},
+		{1250, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.q.Mul(tt.factor); got != tt.want {
+			t.Errorf("%d.Mul(%d) = %d, want %d", tt.q, tt.factor, got, tt.want)
+		}
+	}
+}
+
+func TestTimes(t *testing.T) {
+	tests := []struct {
+		q     Quantity
+		price int64
+		want  int64
+	}{
+		{Of(3), 199, 597},
+		{1250, 399, 499},
+		{333, 100, 33},
+		{-1500, 200, -300},
+		{5, 100, 1},
+		{4, 100, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.q.Times(money.New(tt.price, "USD")); got.Amount != tt.want {
+			t.Errorf("%s x %d = %d, want %d", tt.q, tt.price, got.Amount, tt.want)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		q        Quantity
+		from, to Unit
+		want     Quantity
+		ok       bool
+	}{
+		{Of(1), Kilogram, Gram, Of(1000), true},
+		{Of(500), Gram, Kilogram, 500, true},
+		{Of(1), Pound, Kilogram, 454, true},
+		{Of(2), Litre, Litre, Of(2), true},
+		{Of(1), Each, Kilogram, 0, false},
+		{Of(1), Kilogram, Litre, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := Convert(tt.q, tt.from, tt.to)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Convert(%s, %s, %s) = %d, %v, want %d, %v", tt.q, tt.from, tt.to, got, ok, tt.want, tt.ok)
+		}
+	}
+}
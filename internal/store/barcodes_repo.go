@@ -0,0 +1,163 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"pos-system/internal/barcode"
+)
+
+type BarcodeType string
+
+const (
+	BarcodeEAN13    BarcodeType = "ean13"
+	BarcodeUPCA     BarcodeType = "upca"
+	BarcodeInternal BarcodeType = "internal"
+	BarcodePLU      BarcodeType = "plu"
+)
+
+var BarcodeTypes = []BarcodeType{BarcodeEAN13, BarcodeUPCA, BarcodeInternal, BarcodePLU}
+
+var (
+	ErrBarcodeInUse   = errors.New("barcode already in use")
+	ErrInvalidBarcode = errors.New("invalid barcode")
+)
+
+// BarcodeAlias is an extra code that scans as the product, alongside its
+// primary products.barcode.
+type BarcodeAlias struct {
+	Code      string
+	ProductID int64
+	Type      BarcodeType
+}
+
+func validateBarcode(code string, barcodeType BarcodeType) error {
+	digits := func(min, max int) error {
+		if len(code) < min || len(code) > max {
+			if min == max {
+				return fmt.Errorf("%w: %s codes have %d digits", ErrInvalidBarcode, barcodeType, min)
+			}
+			return fmt.Errorf("%w: %s codes have %d to %d digits", ErrInvalidBarcode, barcodeType, min, max)
+		}
+		for _, r := range code {
+			if r < '0' || r > '9' {
+				return fmt.Errorf("%w: %s codes are numeric", ErrInvalidBarcode, barcodeType)
+			}
+		}
+		return nil
+	}
+	checked := func(length int) error {
+		if err := digits(length, length); err != nil {
+			return err
+		}
+		if !barcode.ValidCheckDigit(code) {
+			return fmt.Errorf("%w: %s has a wrong check digit", ErrInvalidBarcode, code)
+		}
+		return nil
+	}
+	switch barcodeType {
+	case BarcodeEAN13:
+		return checked(13)
+	case BarcodeUPCA:
+		return checked(12)
+	case BarcodePLU:
+		return digits(4, 5)
+	case BarcodeInternal:
+		if code == "" {
+			return fmt.Errorf("%w: code is empty", ErrInvalidBarcode)
+		}
+		return checkScannable(code)
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidBarcode, barcodeType)
+	}
+}
+
+// checkScannable refuses a code the scanner would reject, such as an
+// in-store code of a retail length without a valid check digit.
+func checkScannable(code string) error {
+	if code == "" {
+		return nil
+	}
+	if _, err := barcode.Parse(code); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBarcode, err)
+	}
+	return nil
+}
+
+func ListBarcodes(db *sql.DB, productID int64) ([]BarcodeAlias, error) {
+	rows, err := db.Query(`SELECT code, product_id, type FROM product_barcodes WHERE product_id = ? ORDER BY code`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var aliases []BarcodeAlias
+	for rows.Next() {
+		var (
+			alias       BarcodeAlias
+			barcodeType string
+		)
+		if err := rows.Scan(&alias.Code, &alias.ProductID, &barcodeType); err != nil {
+			return nil, err
+		}
+		alias.Type = BarcodeType(barcodeType)
+		aliases = append(aliases, alias)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return aliases, nil
+}
+
+// AddBarcode registers code as another way to scan productID. A code can
+// belong to only one product, whether as its primary barcode or an alias.
+func AddBarcode(db *sql.DB, alias BarcodeAlias) error {
+	if err := validateBarcode(alias.Code, alias.Type); err != nil {
+		return err
+	}
+	existing, err := GetProductByBarcode(db, alias.Code)
+	if err == nil {
+		return fmt.Errorf("%w by product %d", ErrBarcodeInUse, existing.ID)
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+	if err := checkPackFree(db, alias.Code); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(
+		`INSERT INTO product_barcodes (code, product_id, type) VALUES (?, ?, ?)`,
+		alias.Code,
+		alias.ProductID,
+		string(alias.Type),
+	)
+	return err
+}
+
+func RemoveBarcode(db *sql.DB, code string) error {
+	_, err := db.Exec(`DELETE FROM product_barcodes WHERE code = ?`, code)
+	return err
+}
+
+// checkAliasFree refuses a primary barcode the scanner would reject or one
+// that shadows another product's alias or a pack barcode.
+func checkAliasFree(q queryer, code string, productID int64) error {
+	if code == "" {
+		return nil
+	}
+	if err := checkScannable(code); err != nil {
+		return err
+	}
+	var owner int64
+	err := q.QueryRow(`SELECT product_id FROM product_barcodes WHERE code = ?`, code).Scan(&owner)
+	if err == sql.ErrNoRows || (err == nil && owner == productID) {
+		return checkPackFree(q, code)
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w by product %d", ErrBarcodeInUse, owner)
+}
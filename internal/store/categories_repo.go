@@ -0,0 +1,151 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrCategoryCycle = errors.New("category cannot be moved under itself")
+	ErrCategoryInUse = errors.New("category is in use")
+)
+
+// subtreeCTE selects a category and everything below it as tree(id).
+const subtreeCTE = `WITH RECURSIVE tree(id) AS (
+	SELECT ?
+	UNION ALL
+	SELECT c.id FROM categories c JOIN tree ON c.parent_id = tree.id
+)`
+
+func ListCategories(db *sql.DB) ([]Category, error) {
+	rows, err := db.Query(`SELECT id, name, parent_id FROM categories ORDER BY name, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []Category
+	for rows.Next() {
+		var (
+			category Category
+			parentID sql.NullInt64
+		)
+		if err := rows.Scan(&category.ID, &category.Name, &parentID); err != nil {
+			return nil, err
+		}
+		if parentID.Valid {
+			category.ParentID = &parentID.Int64
+		}
+		categories = append(categories, category)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+func CreateCategory(db *sql.DB, category Category) (int64, error) {
+	if strings.TrimSpace(category.Name) == "" {
+		return 0, errors.New("category name is required")
+	}
+	result, err := db.Exec(`INSERT INTO categories (name, parent_id) VALUES (?, ?)`, category.Name, category.ParentID)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// UpdateCategory renames or moves a category. Moving it below one of its
+// own descendants is rejected.
+func UpdateCategory(db *sql.DB, category Category) error {
+	if strings.TrimSpace(category.Name) == "" {
+		return errors.New("category name is required")
+	}
+	if category.ParentID != nil {
+		var inSubtree int64
+		err := db.QueryRow(
+			subtreeCTE+` SELECT COUNT(*) FROM tree WHERE id = ?`,
+			category.ID,
+			*category.ParentID,
+		).Scan(&inSubtree)
+		if err != nil {
+			return err
+		}
+		if inSubtree > 0 {
+			return ErrCategoryCycle
+		}
+	}
+	_, err := db.Exec(`UPDATE categories SET name = ?, parent_id = ? WHERE id = ?`, category.Name, category.ParentID, category.ID)
+	return err
+}
+
+// DeleteCategory removes an empty category: one with no subcategories and
+// no products.
+func DeleteCategory(db *sql.DB, id int64) error {
+	var children, products int64
+	if err := db.QueryRow(`SELECT COUNT(*) FROM categories WHERE parent_id = ?`, id).Scan(&children); err != nil {
+		return err
+	}
+	if err := db.QueryRow(`SELECT COUNT(*) FROM products WHERE category_id = ?`, id).Scan(&products); err != nil {
+		return err
+	}
+	if children > 0 || products > 0 {
+		return fmt.Errorf("%w: %d subcategories, %d products", ErrCategoryInUse, children, products)
+	}
+	_, err := db.Exec(`DELETE FROM categories WHERE id = ?`, id)
+	return err
+}
+
+// ListProductsInCategory returns the products in a category or any of its
+// subcategories, ordered like ListProducts.
+func ListProductsInCategory(db *sql.DB, categoryID int64) ([]Product, error) {
+	rows, err := db.Query(
+		subtreeCTE+` SELECT `+productColumns+` FROM products
+		WHERE category_id IN (SELECT id FROM tree)
+		ORDER BY COALESCE(parent_id, id), parent_id IS NOT NULL, id`,
+		categoryID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []Product
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+// CategoryPath renders a category as "Clothing > Shirts" from a list
+// loaded with ListCategories.
+func CategoryPath(categories []Category, id int64) string {
+	byID := make(map[int64]Category, len(categories))
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+	var names []string
+	for seen := 0; seen <= len(categories); seen++ {
+		category, ok := byID[id]
+		if !ok {
+			break
+		}
+		names = append([]string{category.Name}, names...)
+		if category.ParentID == nil {
+			break
+		}
+		id = *category.ParentID
+	}
+	return strings.Join(names, " > ")
+}
@@ -0,0 +1,260 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+
+	"pos-system/internal/money"
+	"pos-system/internal/quantity"
+)
+
+// CostMethod decides a product's cost price and what goods cost as they go
+// out.
+type CostMethod string
+
+const (
+	// CostLast costs everything at the unit cost of the latest receipt.
+	CostLast CostMethod = "last"
+	// CostAverage keeps a moving average of the cost of goods on hand.
+	CostAverage CostMethod = "average"
+	// CostFIFO costs goods out from the oldest receipts still on hand.
+	CostFIFO CostMethod = "fifo"
+)
+
+var CostMethods = []CostMethod{CostLast, CostAverage, CostFIFO}
+
+const costMethodKey = "costing_method"
+
+// CostingMethod is the costing method in use, last cost unless set.
+func CostingMethod(db *sql.DB) (CostMethod, error) {
+	return costingMethod(db)
+}
+
+func costingMethod(q queryer) (CostMethod, error) {
+	value, err := getSetting(q, costMethodKey)
+	if err == sql.ErrNoRows {
+		return CostLast, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return parseCostMethod(CostMethod(value))
+}
+
+// SetCostingMethod switches the costing method. Under moving average and
+// FIFO, cost prices are worked out again from the goods on hand.
+func SetCostingMethod(db *sql.DB, method CostMethod) error {
+	if _, err := parseCostMethod(method); err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.Exec(
+		`INSERT INTO settings (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value`,
+		costMethodKey,
+		string(method),
+	); err != nil {
+		return err
+	}
+	var layerCost string
+	switch method {
+	case CostAverage:
+		layerCost = `SELECT CAST(ROUND(1.0 * SUM(remaining * unit_cost_cents) / SUM(remaining)) AS INTEGER)
+			FROM cost_layers WHERE product_id = products.id AND remaining > 0`
+	case CostFIFO:
+		layerCost = `SELECT unit_cost_cents FROM cost_layers
+			WHERE product_id = products.id AND remaining > 0 ORDER BY id LIMIT 1`
+	}
+	if layerCost != "" {
+		if _, err := tx.Exec(`UPDATE products SET cost_cents = COALESCE((` + layerCost + `), cost_cents)`); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func parseCostMethod(method CostMethod) (CostMethod, error) {
+	for _, known := range CostMethods {
+		if method == known {
+			return method, nil
+		}
+	}
+	return "", fmt.Errorf("unknown costing method %q", method)
+}
+
+// costMovement keeps cost layers and the product's cost price in step with
+// a movement, before it is applied to products.stock. Goods in add a layer
+// at the movement's unit cost, or the cost price when it has none; goods
+// out use up layers oldest first. Transfers only move goods between
+// locations and leave costs alone.
+func costMovement(q queryer, movement StockMovement) error {
+	if movement.Kind == MovementTransfer {
+		return nil
+	}
+	method, err := costingMethod(q)
+	if err != nil {
+		return err
+	}
+	var (
+		stock    quantity.Quantity
+		cents    int64
+		currency string
+	)
+	err = q.QueryRow(`SELECT stock, cost_cents, currency FROM products WHERE id = ?`, movement.ProductID).Scan(&stock, &cents, &currency)
+	if err != nil {
+		return err
+	}
+	cost := money.New(cents, currency)
+
+	if movement.Quantity > 0 {
+		unitCost := cost
+		if movement.UnitCost != nil {
+			unitCost = money.New(movement.UnitCost.Amount, currency)
+		}
+		if _, err := q.Exec(
+			`INSERT INTO cost_layers (product_id, quantity, remaining, unit_cost_cents, currency, reference) VALUES (?, ?, ?, ?, ?, ?)`,
+			movement.ProductID,
+			movement.Quantity,
+			movement.Quantity,
+			unitCost.Amount,
+			currency,
+			movement.Reference,
+		); err != nil {
+			return err
+		}
+		switch method {
+		case CostLast:
+			if movement.Kind == MovementReceipt && movement.UnitCost != nil {
+				cost = unitCost
+			}
+		case CostAverage:
+			if stock <= 0 {
+				cost = unitCost
+			} else {
+				total := stock.Times(cost).Add(movement.Quantity.Times(unitCost))
+				cost = total.MulDiv(int64(quantity.Of(1)), int64(stock+movement.Quantity))
+			}
+		}
+	} else if err := useLayers(q, movement.ProductID, -movement.Quantity); err != nil {
+		return err
+	}
+
+	if method == CostFIFO {
+		err := q.QueryRow(
+			`SELECT unit_cost_cents FROM cost_layers WHERE product_id = ? AND remaining > 0 ORDER BY id LIMIT 1`,
+			movement.ProductID,
+		).Scan(&cost.Amount)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+	}
+	_, err = q.Exec(`UPDATE products SET cost_cents = ? WHERE id = ?`, cost.Amount, movement.ProductID)
+	return err
+}
+
+// revalueStock puts the goods on hand at a product's new cost price after
+// it was edited by hand. Under moving average and FIFO the cost price is
+// read from the cost layers, so they take the new cost with it; under last
+// cost they keep what was paid.
+func revalueStock(q queryer, productID int64, cost money.Money) error {
+	method, err := costingMethod(q)
+	if err != nil {
+		return err
+	}
+	if method == CostLast {
+		return nil
+	}
+	_, err = q.Exec(`UPDATE cost_layers SET unit_cost_cents = ? WHERE product_id = ? AND remaining > 0`, cost.Amount, productID)
+	return err
+}
+
+// useLayers takes qty out of a product's cost layers, oldest first.
+func useLayers(q queryer, productID int64, qty quantity.Quantity) error {
+	layers, err := openLayers(q, productID)
+	if err != nil {
+		return err
+	}
+	for _, layer := range layers {
+		if qty <= 0 {
+			break
+		}
+		take := min(layer.remaining, qty)
+		if _, err := q.Exec(`UPDATE cost_layers SET remaining = remaining - ? WHERE id = ?`, take, layer.id); err != nil {
+			return err
+		}
+		qty -= take
+	}
+	return nil
+}
+
+type costLayer struct {
+	id        int64
+	remaining quantity.Quantity
+	unitCost  int64
+}
+
+func openLayers(q queryer, productID int64) ([]costLayer, error) {
+	rows, err := q.Query(
+		`SELECT id, remaining, unit_cost_cents FROM cost_layers WHERE product_id = ? AND remaining > 0 ORDER BY id`,
+		productID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var layers []costLayer
+	for rows.Next() {
+		var layer costLayer
+		if err := rows.Scan(&layer.id, &layer.remaining, &layer.unitCost); err != nil {
+			return nil, err
+		}
+		layers = append(layers, layer)
+	}
+	return layers, rows.Err()
+}
+
+// issueCost is what qty of a product going out now costs. Under FIFO it
+// comes from the oldest layers, and anything beyond them at the cost price.
+func issueCost(q queryer, productID int64, qty quantity.Quantity) (money.Money, error) {
+	method, err := costingMethod(q)
+	if err != nil {
+		return money.Money{}, err
+	}
+	var (
+		cents    int64
+		currency string
+	)
+	if err := q.QueryRow(`SELECT cost_cents, currency FROM products WHERE id = ?`, productID).Scan(&cents, &currency); err != nil {
+		return money.Money{}, err
+	}
+	cost := money.New(cents, currency)
+	if method != CostFIFO {
+		return qty.Times(cost), nil
+	}
+
+	layers, err := openLayers(q, productID)
+	if err != nil {
+		return money.Money{}, err
+	}
+	total := money.Zero(currency)
+	for _, layer := range layers {
+		if qty <= 0 {
+			break
+		}
+		take := min(layer.remaining, qty)
+		total = total.Add(take.Times(money.New(layer.unitCost, currency)))
+		qty -= take
+	}
+	if qty > 0 {
+		total = total.Add(qty.Times(cost))
+	}
+	return total, nil
+}
@@ -0,0 +1,136 @@
+package store
+
+import (
+	"database/sql"
+	"testing"
+
+	"pos-system/internal/money"
+	"pos-system/internal/quantity"
+)
+
+// openLayerTotals sums what is left in a product's cost layers and what it
+// is worth.
+func openLayerTotals(t *testing.T, db *sql.DB, productID int64) (quantity.Quantity, money.Money) {
+	t.Helper()
+	layers, err := openLayers(db, productID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		remaining quantity.Quantity
+		value     = money.Zero(money.DefaultCurrency)
+	)
+	for _, layer := range layers {
+		remaining += layer.remaining
+		value = value.Add(layer.remaining.Times(money.New(layer.unitCost, money.DefaultCurrency)))
+	}
+	return remaining, value
+}
+
+func TestSaleCost(t *testing.T) {
+	usd := func(cents int64) money.Money { return money.New(cents, money.DefaultCurrency) }
+	tests := []struct {
+		method CostMethod
+		// Ten units come in at 1.00 and ten at 2.00, then sell fifteen
+		// and then five.
+		wantFirst, wantSecond money.Money
+		wantCost              money.Money
+	}{
+		{CostFIFO, usd(2000), usd(1000), usd(200)},
+		{CostAverage, usd(2250), usd(750), usd(150)},
+		{CostLast, usd(3000), usd(1000), usd(200)},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.method), func(t *testing.T) {
+			db := openTestDB(t)
+			if err := SetCostingMethod(db, tt.method); err != nil {
+				t.Fatal(err)
+			}
+			productID := createTestProduct(t, db, "Widget", usd(500), nil)
+			receiveTestStock(t, db, productID, 10, usd(100))
+			receiveTestStock(t, db, productID, 10, usd(200))
+
+			first := sellTestStock(t, db, productID, 15)
+			if got := first.Lines[0].Cost; got != tt.wantFirst {
+				t.Errorf("first sale cost = %s, want %s", got, tt.wantFirst)
+			}
+			product, err := GetProduct(db, productID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if product.Cost != tt.wantCost {
+				t.Errorf("cost price = %s, want %s", product.Cost, tt.wantCost)
+			}
+			if remaining, _ := openLayerTotals(t, db, productID); remaining != quantity.Of(5) {
+				t.Errorf("layers hold %s, want 5", remaining)
+			}
+
+			second := sellTestStock(t, db, productID, 5)
+			if got := second.Lines[0].Cost; got != tt.wantSecond {
+				t.Errorf("second sale cost = %s, want %s", got, tt.wantSecond)
+			}
+			if remaining, _ := openLayerTotals(t, db, productID); remaining != 0 {
+				t.Errorf("layers hold %s after selling out, want 0", remaining)
+			}
+		})
+	}
+}
+
+func TestUndoneSaleRestoresCost(t *testing.T) {
+	usd := func(cents int64) money.Money { return money.New(cents, money.DefaultCurrency) }
+	void := func(db *sql.DB, sale Sale) error {
+		return VoidSale(db, sale.ID, "manager", "test")
+	}
+	returnFive := func(restock bool) func(*sql.DB, Sale) error {
+		return func(db *sql.DB, sale Sale) error {
+			refund := Tender{Type: TenderCash, Amount: usd(2500)}
+			lines := []ReturnLine{{SaleItemID: sale.Lines[0].ID, Quantity: quantity.Of(5)}}
+			_, err := CreateReturn(db, sale.ID, lines, restock, refund, "test")
+			return err
+		}
+	}
+	tests := []struct {
+		name   string
+		method CostMethod
+		undo   func(*sql.DB, Sale) error
+		// Ten units come in at 1.00 and ten at 2.00, and all twenty sell
+		// at an average of 1.50 before the sale is undone.
+		wantStock quantity.Quantity
+		wantValue money.Money
+	}{
+		{"void fifo", CostFIFO, void, quantity.Of(20), usd(3000)},
+		{"void average", CostAverage, void, quantity.Of(20), usd(3000)},
+		{"restocked return", CostFIFO, returnFive(true), quantity.Of(5), usd(750)},
+		{"return not restocked", CostFIFO, returnFive(false), 0, usd(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			if err := SetCostingMethod(db, tt.method); err != nil {
+				t.Fatal(err)
+			}
+			productID := createTestProduct(t, db, "Widget", usd(500), nil)
+			receiveTestStock(t, db, productID, 10, usd(100))
+			receiveTestStock(t, db, productID, 10, usd(200))
+			sale := sellTestStock(t, db, productID, 20)
+			if got := sale.Lines[0].UnitCost; got != usd(150) {
+				t.Fatalf("unit cost = %s, want 1.50", got)
+			}
+
+			if err := tt.undo(db, sale); err != nil {
+				t.Fatal(err)
+			}
+			product, err := GetProduct(db, productID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if product.Stock != tt.wantStock {
+				t.Errorf("stock = %s, want %s", product.Stock, tt.wantStock)
+			}
+			remaining, value := openLayerTotals(t, db, productID)
+			if remaining != tt.wantStock || value != tt.wantValue {
+				t.Errorf("layers hold %s worth %s, want %s worth %s", remaining, value, tt.wantStock, tt.wantValue)
+			}
+		})
+	}
+}
@@ -1,9 +1,13 @@
 package store
 
-import "database/sql"
+import (
+	"database/sql"
+
+	"pos-system/internal/money"
+)
 
 func ListCustomers(db *sql.DB) ([]Customer, error) {
-	rows, err := db.Query(`SELECT id, name, email, phone FROM customers ORDER BY id`)
+	rows, err := db.Query(`SELECT id, name, email, phone, store_credit_cents, price_list_id FROM customers ORDER BY id`)
 	if err != nil {
 		return nil, err
 	}
@@ -12,9 +16,15 @@ func ListCustomers(db *sql.DB) ([]Customer, error) {
 	var customers []Customer
 	for rows.Next() {
 		var customer Customer
-		if err := rows.Scan(&customer.ID, &customer.Name, &customer.Email, &customer.Phone); err != nil {
+		var storeCredit int64
+		var priceListID sql.NullInt64
+		if err := rows.Scan(&customer.ID, &customer.Name, &customer.Email, &customer.Phone, &storeCredit, &priceListID); err != nil {
 			return nil, err
 		}
+		customer.StoreCredit = money.New(storeCredit, money.DefaultCurrency)
+		if priceListID.Valid {
+			customer.PriceListID = &priceListID.Int64
+		}
 		customers = append(customers, customer)
 	}
 	if err := rows.Err(); err != nil {
@@ -25,7 +35,13 @@ func ListCustomers(db *sql.DB) ([]Customer, error) {
 }
 
 func CreateCustomer(db *sql.DB, customer Customer) (int64, error) {
-	result, err := db.Exec(`INSERT INTO customers (name, email, phone) VALUES (?, ?, ?)`, customer.Name, customer.Email, customer.Phone)
+	result, err := db.Exec(
+		`INSERT INTO customers (name, email, phone, price_list_id) VALUES (?, ?, ?, ?)`,
+		customer.Name,
+		customer.Email,
+		customer.Phone,
+		customer.PriceListID,
+	)
 	if err != nil {
 		return 0, err
 	}
@@ -33,7 +49,14 @@ func CreateCustomer(db *sql.DB, customer Customer) (int64, error) {
 }
 
 func UpdateCustomer(db *sql.DB, customer Customer) error {
-	_, err := db.Exec(`UPDATE customers SET name = ?, email = ?, phone = ? WHERE id = ?`, customer.Name, customer.Email, customer.Phone, customer.ID)
+	_, err := db.Exec(
+		`UPDATE customers SET name = ?, email = ?, phone = ?, price_list_id = ? WHERE id = ?`,
+		customer.Name,
+		customer.Email,
+		customer.Phone,
+		customer.PriceListID,
+		customer.ID,
+	)
 	return err
 }
 
@@ -1,10 +1,34 @@
 package store
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	_ "modernc.org/sqlite"
 )
 
+var (
+	ErrSchemaTooNew      = errors.New("database schema is newer than this binary")
+	ErrMigrationChecksum = errors.New("applied migration does not match its definition")
+	ErrUnknownMigration  = errors.New("unknown migration recorded in database")
+)
+
+type migration struct {
+	version    int
+	name       string
+	statements []string
+}
+
+func (m migration) checksum() string {
+	sum := sha256.Sum256([]byte(strings.Join(m.statements, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
 func OpenDB(path string) (*sql.DB, error) {
 	return sql.Open("sqlite", path)
 }
@@ -14,44 +38,130 @@ func Migrate(db *sql.DB) error {
 		return err
 	}
 
-	statements := []string{
-		`CREATE TABLE IF NOT EXISTS customers (
-			id INTEGER PRIMARY KEY,
-			name TEXT NOT NULL,
-			email TEXT NOT NULL,
-			phone TEXT NOT NULL
-		);`,
-		`CREATE TABLE IF NOT EXISTS products (
-			id INTEGER PRIMARY KEY,
-			name TEXT NOT NULL,
-			barcode TEXT,
-			price REAL NOT NULL,
-			stock INTEGER NOT NULL
-		);`,
-		`CREATE TABLE IF NOT EXISTS sales (
-			id INTEGER PRIMARY KEY,
-			customer_id INTEGER NOT NULL,
-			created_at DATETIME NOT NULL,
-			FOREIGN KEY(customer_id) REFERENCES customers(id)
-		);`,
-		`CREATE TABLE IF NOT EXISTS sale_items (
-			id INTEGER PRIMARY KEY,
-			sale_id INTEGER NOT NULL,
-			product_id INTEGER NOT NULL,
-			quantity INTEGER NOT NULL,
-			price REAL NOT NULL,
-			FOREIGN KEY(sale_id) REFERENCES sales(id),
-			FOREIGN KEY(product_id) REFERENCES products(id)
-		);`,
-		`CREATE UNIQUE INDEX IF NOT EXISTS idx_products_barcode ON products(barcode);`,
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at DATETIME NOT NULL
+	);`); err != nil {
+		return err
 	}
 
-	for _, statement := range statements {
-		if _, err := db.Exec(statement); err != nil {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		if err := upgradeLegacySchema(db); err != nil {
+			return err
+		}
+	}
+
+	latest := migrations[len(migrations)-1].version
+	for version := range applied {
+		if version > latest {
+			return fmt.Errorf("%w: database is at version %d, binary supports up to %d", ErrSchemaTooNew, version, latest)
+		}
+	}
+
+	known := make(map[int]migration, len(migrations))
+	for _, m := range migrations {
+		known[m.version] = m
+	}
+	for version, checksum := range applied {
+		m, ok := known[version]
+		if !ok {
+			return fmt.Errorf("%w: version %d", ErrUnknownMigration, version)
+		}
+		if checksum != m.checksum() {
+			return fmt.Errorf("%w: version %d (%s)", ErrMigrationChecksum, m.version, m.name)
+		}
+	}
+
+	for _, m := range migrations {
+		if _, ok := applied[m.version]; ok {
+			continue
+		}
+		if err := applyMigration(db, m); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+	}
+
+	return nil
+}
+
+func CurrentVersion(db *sql.DB) (int, error) {
+	exists, err := tableExists(db, "schema_migrations")
+	if err != nil || !exists {
+		return 0, err
+	}
+
+	var version int
+	err = db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+func appliedMigrations(db *sql.DB) (map[int]string, error) {
+	rows, err := db.Query(`SELECT version, checksum FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]string)
+	for rows.Next() {
+		var (
+			version  int
+			checksum string
+		)
+		if err := rows.Scan(&version, &checksum); err != nil {
+			return nil, err
+		}
+		applied[version] = checksum
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for _, statement := range m.statements {
+		if _, err := tx.Exec(statement); err != nil {
 			return err
 		}
 	}
 
+	if _, err := tx.Exec(
+		`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`,
+		m.version,
+		m.name,
+		m.checksum(),
+		time.Now(),
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// upgradeLegacySchema patches databases created before schema_migrations
+// existed so that the initial migration can adopt them as-is.
+func upgradeLegacySchema(db *sql.DB) error {
+	hasProducts, err := tableExists(db, "products")
+	if err != nil || !hasProducts {
+		return err
+	}
+
 	hasBarcode, err := columnExists(db, "products", "barcode")
 	if err != nil {
 		return err
@@ -62,9 +172,54 @@ func Migrate(db *sql.DB) error {
 		}
 	}
 
+	for _, tableName := range []string{"products", "sale_items"} {
+		if err := convertLegacyPrice(db, tableName); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// convertLegacyPrice replaces the old REAL price column with integer cents.
+func convertLegacyPrice(db *sql.DB, tableName string) error {
+	hasLegacyPrice, err := columnExists(db, tableName, "price")
+	if err != nil || !hasLegacyPrice {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	statements := []string{
+		`ALTER TABLE ` + tableName + ` ADD COLUMN price_cents INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE ` + tableName + ` ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD'`,
+		`UPDATE ` + tableName + ` SET price_cents = CAST(ROUND(price * 100) AS INTEGER)`,
+		`ALTER TABLE ` + tableName + ` DROP COLUMN price`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func tableExists(db *sql.DB, tableName string) (bool, error) {
+	var count int
+	err := db.QueryRow(
+		`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`,
+		tableName,
+	).Scan(&count)
+	return count > 0, err
+}
+
 func columnExists(db *sql.DB, tableName, columnName string) (bool, error) {
 	rows, err := db.Query(`PRAGMA table_info(` + tableName + `)`)
 	if err != nil {
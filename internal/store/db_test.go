@@ -0,0 +1,83 @@
+package store
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"pos-system/internal/money"
+	"pos-system/internal/quantity"
+)
+
+// exemptTaxClass keeps tax out of the figures tests check.
+const exemptTaxClass = 3
+
+// openTestDB opens a migrated database in a temporary directory.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := OpenDB(filepath.Join(t.TempDir(), "pos.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// createTestProduct adds a tax-exempt product sold by the unit, without
+// stock. Its name doubles as its barcode.
+func createTestProduct(t *testing.T, db *sql.DB, name string, price money.Money, categoryID *int64) int64 {
+	t.Helper()
+	id, err := CreateProduct(db, Product{
+		Name:       name,
+		Barcode:    name,
+		Price:      price,
+		Unit:       quantity.Each,
+		TaxClassID: exemptTaxClass,
+		CategoryID: categoryID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// receiveTestStock books units of a product into stock at a unit cost.
+func receiveTestStock(t *testing.T, db *sql.DB, productID int64, units int64, unitCost money.Money) {
+	t.Helper()
+	err := AdjustStock(db, StockMovement{
+		ProductID: productID,
+		Kind:      MovementReceipt,
+		Quantity:  quantity.Of(units),
+		Reason:    "test receipt",
+		UnitCost:  &unitCost,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// sellTestStock sells units of a product for exact cash and returns the sale.
+func sellTestStock(t *testing.T, db *sql.DB, productID int64, units int64) Sale {
+	t.Helper()
+	customerID, err := CreateCustomer(db, Customer{Name: "Test customer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := []SaleItem{{ProductID: productID, Quantity: quantity.Of(units)}}
+	cart, err := PriceCart(db, &customerID, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saleID, err := CreateSale(db, DefaultLocationID, &customerID, items, []Tender{{Type: TenderCash, Amount: cart.Total}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sale, err := GetSale(db, saleID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sale
+}
@@ -0,0 +1,219 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"pos-system/internal/quantity"
+)
+
+// DefaultLocationID is where stock is kept unless a location is named.
+// Stock held before locations existed was moved there.
+const DefaultLocationID int64 = 1
+
+var ErrLocationInUse = errors.New("location still holds stock")
+
+type Location struct {
+	ID   int64
+	Name string
+}
+
+// LocationStock is a product's stock at one location.
+type LocationStock struct {
+	LocationID int64
+	Location   string
+	Quantity   quantity.Quantity
+}
+
+func ListLocations(db *sql.DB) ([]Location, error) {
+	rows, err := db.Query(`SELECT id, name FROM locations ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var locations []Location
+	for rows.Next() {
+		var location Location
+		if err := rows.Scan(&location.ID, &location.Name); err != nil {
+			return nil, err
+		}
+		locations = append(locations, location)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return locations, nil
+}
+
+func GetLocation(db *sql.DB, id int64) (Location, error) {
+	location := Location{ID: id}
+	err := db.QueryRow(`SELECT name FROM locations WHERE id = ?`, id).Scan(&location.Name)
+	return location, err
+}
+
+func CreateLocation(db *sql.DB, name string) (int64, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return 0, errors.New("location requires a name")
+	}
+	result, err := db.Exec(`INSERT INTO locations (name) VALUES (?)`, name)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func RenameLocation(db *sql.DB, id int64, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("location requires a name")
+	}
+	_, err := db.Exec(`UPDATE locations SET name = ? WHERE id = ?`, name, id)
+	return err
+}
+
+// DeleteLocation removes an empty location. The default location stays.
+func DeleteLocation(db *sql.DB, id int64) error {
+	if id == DefaultLocationID {
+		return errors.New("the default location cannot be deleted")
+	}
+	var products int64
+	if err := db.QueryRow(`SELECT COUNT(*) FROM product_stock WHERE location_id = ? AND quantity <> 0`, id).Scan(&products); err != nil {
+		return err
+	}
+	if products > 0 {
+		return fmt.Errorf("%w: %d products", ErrLocationInUse, products)
+	}
+	if _, err := db.Exec(`DELETE FROM product_stock WHERE location_id = ?`, id); err != nil {
+		return err
+	}
+	_, err := db.Exec(`DELETE FROM locations WHERE id = ?`, id)
+	return err
+}
+
+// ProductStockByLocation breaks a product's stock down by location,
+// listing every location including those holding none.
+func ProductStockByLocation(db *sql.DB, productID int64) ([]LocationStock, error) {
+	rows, err := db.Query(
+		`SELECT l.id, l.name, COALESCE(ps.quantity, 0)
+		FROM locations l LEFT JOIN product_stock ps ON ps.location_id = l.id AND ps.product_id = ?
+		ORDER BY l.id`,
+		productID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stock []LocationStock
+	for rows.Next() {
+		var line LocationStock
+		if err := rows.Scan(&line.LocationID, &line.Location, &line.Quantity); err != nil {
+			return nil, err
+		}
+		stock = append(stock, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stock, nil
+}
+
+// checkLocation accepts zero, the default location, or any existing one.
+func checkLocation(q queryer, id int64) error {
+	if id == 0 {
+		return nil
+	}
+	var exists int64
+	err := q.QueryRow(`SELECT COUNT(*) FROM locations WHERE id = ?`, id).Scan(&exists)
+	if err == nil && exists == 0 {
+		err = fmt.Errorf("unknown location %d", id)
+	}
+	return err
+}
+
+func stockAt(q queryer, productID, locationID int64) (quantity.Quantity, error) {
+	var stock quantity.Quantity
+	err := q.QueryRow(
+		`SELECT quantity FROM product_stock WHERE product_id = ? AND location_id = ?`,
+		productID,
+		locationID,
+	).Scan(&stock)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return stock, err
+}
+
+// SellableStock is what a location can sell of a product: its stock there,
+// or just the named lot when lot is set. Expired lots are left out while
+// they are blocked from sale.
+func SellableStock(db *sql.DB, productID, locationID int64, lot string) (quantity.Quantity, error) {
+	return sellableStock(db, productID, locationID, lot)
+}
+
+func sellableStock(q queryer, productID, locationID int64, lot string) (quantity.Quantity, error) {
+	stock, err := stockAt(q, productID, locationID)
+	if err != nil {
+		return 0, err
+	}
+	block, err := blockExpiredLots(q)
+	if err != nil {
+		return 0, err
+	}
+	lots, err := locationLots(q, productID, locationID)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	if lot != "" {
+		for _, held := range lots {
+			if held.Number == lot && !(block && held.Expired(now)) {
+				return min(held.Quantity, max(stock, 0)), nil
+			}
+		}
+		return 0, nil
+	}
+	for _, held := range lots {
+		if block && held.Expired(now) {
+			stock -= held.Quantity
+		}
+	}
+	return max(stock, 0), nil
+}
+
+// TerminalLocation is the location a terminal sells from, the default
+// location until one is set.
+func TerminalLocation(db *sql.DB, terminal string) (Location, error) {
+	id := DefaultLocationID
+	var value int64
+	err := db.QueryRow(`SELECT value FROM settings WHERE key = ?`, terminalLocationKey(terminal)).Scan(&value)
+	if err == nil {
+		id = value
+	} else if err != sql.ErrNoRows {
+		return Location{}, err
+	}
+	location, err := GetLocation(db, id)
+	if err == sql.ErrNoRows && id != DefaultLocationID {
+		return GetLocation(db, DefaultLocationID)
+	}
+	return location, err
+}
+
+func SetTerminalLocation(db *sql.DB, terminal string, locationID int64) error {
+	if _, err := GetLocation(db, locationID); err != nil {
+		return err
+	}
+	return SetSetting(db, terminalLocationKey(terminal), strconv.FormatInt(locationID, 10))
+}
+
+func terminalLocationKey(terminal string) string {
+	return "terminal_location:" + terminal
+}
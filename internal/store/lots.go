@@ -0,0 +1,355 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"pos-system/internal/quantity"
+)
+
+var (
+	ErrLotRequired = errors.New("product is tracked by lot")
+	ErrLotExpired  = errors.New("lot has expired")
+)
+
+const blockExpiredLotsKey = "block_expired_lots"
+
+// Lot is what is held of one batch of a product at one location. Stock
+// received without a lot is held outside any lot, so a location's lots
+// never add up to more than its stock.
+type Lot struct {
+	ID         int64
+	ProductID  int64
+	Name       string
+	Unit       quantity.Unit
+	LocationID int64
+	Location   string
+	Number     string
+	// Expiry is the last day the lot may be sold, nil if it does not expire.
+	Expiry     *time.Time
+	Quantity   quantity.Quantity
+	ReceivedAt time.Time
+}
+
+// Expired reports whether the lot's expiry date is before now's date.
+func (l Lot) Expired(now time.Time) bool {
+	return l.Expiry != nil && l.Expiry.Before(lotDate(now))
+}
+
+// LotAllocation is how much of a sale line came from one lot.
+type LotAllocation struct {
+	LotID    int64
+	Lot      string
+	Expiry   *time.Time
+	Quantity quantity.Quantity
+}
+
+// lotDate keeps only the calendar date of t, so expiry dates compare and
+// sort the same whatever time zone they were read in.
+func lotDate(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+const lotColumns = `lt.id, lt.product_id, p.name, p.unit, lt.location_id, COALESCE(loc.name, ''), lt.number, lt.expiry, lt.quantity, lt.received_at
+	FROM lots lt
+	JOIN products p ON p.id = lt.product_id
+	LEFT JOIN locations loc ON loc.id = lt.location_id`
+
+// lotOrder is first-expiry-first-out, with lots that never expire last.
+const lotOrder = `lt.expiry IS NULL, lt.expiry, lt.id`
+
+func queryLots(q queryer, query string, args ...any) ([]Lot, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lots []Lot
+	for rows.Next() {
+		var (
+			lot    Lot
+			unit   string
+			expiry sql.NullTime
+		)
+		err := rows.Scan(
+			&lot.ID, &lot.ProductID, &lot.Name, &unit, &lot.LocationID, &lot.Location,
+			&lot.Number, &expiry, &lot.Quantity, &lot.ReceivedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		lot.Unit = quantity.Unit(unit)
+		if expiry.Valid {
+			date := lotDate(expiry.Time)
+			lot.Expiry = &date
+		}
+		lots = append(lots, lot)
+	}
+	return lots, rows.Err()
+}
+
+// ListLots returns the lots of a product still in stock, at every
+// location, first to expire first.
+func ListLots(db *sql.DB, productID int64) ([]Lot, error) {
+	return queryLots(db, `SELECT `+lotColumns+`
+		WHERE lt.product_id = ? AND lt.quantity > 0
+		ORDER BY `+lotOrder,
+		productID,
+	)
+}
+
+// NearExpiryReport lists lots in stock that expire within days from today,
+// including those already expired, soonest first.
+func NearExpiryReport(db *sql.DB, days int) ([]Lot, error) {
+	if days < 0 {
+		return nil, errors.New("days cannot be negative")
+	}
+	return queryLots(db, `SELECT `+lotColumns+`
+		WHERE lt.quantity > 0 AND lt.expiry IS NOT NULL AND lt.expiry <= ?
+		ORDER BY `+lotOrder+`, p.name`,
+		lotDate(time.Now()).AddDate(0, 0, days),
+	)
+}
+
+func locationLots(q queryer, productID, locationID int64) ([]Lot, error) {
+	return queryLots(q, `SELECT `+lotColumns+`
+		WHERE lt.product_id = ? AND lt.location_id = ? AND lt.quantity > 0
+		ORDER BY `+lotOrder,
+		productID,
+		locationID,
+	)
+}
+
+// moveLot applies a movement to the lot it names, creating the lot on
+// first receipt.
+func moveLot(q queryer, movement StockMovement) error {
+	if movement.Quantity > 0 {
+		var expiry *time.Time
+		if movement.Expiry != nil {
+			date := lotDate(*movement.Expiry)
+			expiry = &date
+		}
+		_, err := q.Exec(
+			`INSERT INTO lots (product_id, location_id, number, expiry, quantity, received_at) VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(product_id, location_id, number) DO UPDATE SET
+				quantity = quantity + excluded.quantity,
+				expiry = COALESCE(excluded.expiry, expiry)`,
+			movement.ProductID,
+			movement.LocationID,
+			movement.Lot,
+			expiry,
+			movement.Quantity,
+			time.Now(),
+		)
+		return err
+	}
+
+	result, err := q.Exec(
+		`UPDATE lots SET quantity = quantity + ?
+		WHERE product_id = ? AND location_id = ? AND number = ? AND quantity + ? >= 0`,
+		movement.Quantity,
+		movement.ProductID,
+		movement.LocationID,
+		movement.Lot,
+		movement.Quantity,
+	)
+	if err != nil {
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return fmt.Errorf("%w in lot %s of product %d at location %d", ErrInsufficientStock, movement.Lot, movement.ProductID, movement.LocationID)
+	}
+	return nil
+}
+
+// trimLots takes stock that left a location without naming a lot out of
+// its lots, first to expire first, once stock held outside lots runs out.
+func trimLots(q queryer, productID, locationID int64) error {
+	stock, err := stockAt(q, productID, locationID)
+	if err != nil {
+		return err
+	}
+	lots, err := locationLots(q, productID, locationID)
+	if err != nil {
+		return err
+	}
+	excess := -stock
+	for _, lot := range lots {
+		excess += lot.Quantity
+	}
+	for _, lot := range lots {
+		if excess <= 0 {
+			break
+		}
+		take := min(lot.Quantity, excess)
+		if _, err := q.Exec(`UPDATE lots SET quantity = quantity - ? WHERE id = ?`, take, lot.ID); err != nil {
+			return err
+		}
+		excess -= take
+	}
+	return nil
+}
+
+// allocateLots picks the lots a sale of qty at a location comes from. A
+// named lot supplies all of it. Otherwise lots go first-expiry-first-out,
+// and whatever they do not cover comes from stock held outside lots.
+// Products not tracked by lot need no allocation.
+func allocateLots(q queryer, productID, locationID int64, qty quantity.Quantity, number string) ([]LotAllocation, error) {
+	var track bool
+	if err := q.QueryRow(`SELECT track_lots FROM products WHERE id = ?`, productID).Scan(&track); err != nil {
+		return nil, err
+	}
+	if !track {
+		return nil, nil
+	}
+	block, err := blockExpiredLots(q)
+	if err != nil {
+		return nil, err
+	}
+	lots, err := locationLots(q, productID, locationID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	if number != "" {
+		for _, lot := range lots {
+			if lot.Number != number {
+				continue
+			}
+			if block && lot.Expired(now) {
+				return nil, fmt.Errorf("%w: lot %s of %s expired on %s", ErrLotExpired, lot.Number, lot.Name, lot.Expiry.Format(time.DateOnly))
+			}
+			return []LotAllocation{{LotID: lot.ID, Lot: lot.Number, Expiry: lot.Expiry, Quantity: qty}}, nil
+		}
+		return nil, fmt.Errorf("%w: lot %s of product %d at location %d", ErrInsufficientStock, number, productID, locationID)
+	}
+
+	var (
+		allocations   []LotAllocation
+		held, expired quantity.Quantity
+		remaining     = qty
+	)
+	for _, lot := range lots {
+		held += lot.Quantity
+		if block && lot.Expired(now) {
+			expired += lot.Quantity
+			continue
+		}
+		take := min(lot.Quantity, remaining)
+		if take <= 0 {
+			continue
+		}
+		allocations = append(allocations, LotAllocation{LotID: lot.ID, Lot: lot.Number, Expiry: lot.Expiry, Quantity: take})
+		remaining -= take
+	}
+	if remaining > 0 && expired > 0 {
+		stock, err := stockAt(q, productID, locationID)
+		if err != nil {
+			return nil, err
+		}
+		if remaining > stock-held {
+			return nil, fmt.Errorf("%w: only expired lots are left of product %d at location %d", ErrLotExpired, productID, locationID)
+		}
+	}
+	return allocations, nil
+}
+
+func saleItemLots(q queryer, saleItemID int64) ([]LotAllocation, error) {
+	return queryLotAllocations(q,
+		`SELECT sl.lot_id, lt.number, lt.expiry, sl.quantity
+		FROM sale_item_lots sl JOIN lots lt ON lt.id = sl.lot_id
+		WHERE sl.sale_item_id = ? ORDER BY sl.id`,
+		saleItemID,
+	)
+}
+
+// transferLots picks the lots a transfer of qty out of a location takes,
+// first to expire first, before any stock held outside lots. Expired lots
+// move like any other; only their sale is blocked.
+func transferLots(q queryer, productID, locationID int64, qty quantity.Quantity) ([]LotAllocation, error) {
+	var track bool
+	if err := q.QueryRow(`SELECT track_lots FROM products WHERE id = ?`, productID).Scan(&track); err != nil {
+		return nil, err
+	}
+	if !track {
+		return nil, nil
+	}
+	lots, err := locationLots(q, productID, locationID)
+	if err != nil {
+		return nil, err
+	}
+	var allocations []LotAllocation
+	for _, lot := range lots {
+		take := min(lot.Quantity, qty)
+		if take <= 0 {
+			break
+		}
+		allocations = append(allocations, LotAllocation{LotID: lot.ID, Lot: lot.Number, Expiry: lot.Expiry, Quantity: take})
+		qty -= take
+	}
+	return allocations, nil
+}
+
+func transferLineLots(q queryer, lineID int64) ([]LotAllocation, error) {
+	return queryLotAllocations(q,
+		`SELECT tl.lot_id, lt.number, lt.expiry, tl.quantity
+		FROM transfer_line_lots tl JOIN lots lt ON lt.id = tl.lot_id
+		WHERE tl.transfer_line_id = ? ORDER BY tl.id`,
+		lineID,
+	)
+}
+
+func queryLotAllocations(q queryer, query string, args ...any) ([]LotAllocation, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var allocations []LotAllocation
+	for rows.Next() {
+		var (
+			allocation LotAllocation
+			expiry     sql.NullTime
+		)
+		if err := rows.Scan(&allocation.LotID, &allocation.Lot, &expiry, &allocation.Quantity); err != nil {
+			return nil, err
+		}
+		if expiry.Valid {
+			date := lotDate(expiry.Time)
+			allocation.Expiry = &date
+		}
+		allocations = append(allocations, allocation)
+	}
+	return allocations, rows.Err()
+}
+
+// BlockExpiredLots reports whether sales of expired lots are refused.
+func BlockExpiredLots(db *sql.DB) (bool, error) {
+	return blockExpiredLots(db)
+}
+
+func blockExpiredLots(q queryer) (bool, error) {
+	value, err := getSetting(q, blockExpiredLotsKey)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return value == "1", err
+}
+
+func SetBlockExpiredLots(db *sql.DB, block bool) error {
+	value := "0"
+	if block {
+		value = "1"
+	}
+	return SetSetting(db, blockExpiredLotsKey, value)
+}
@@ -0,0 +1,608 @@
+package store
+
+// migrations are applied in order and recorded in schema_migrations.
+// Never edit an entry once it has shipped; append a new version instead.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "initial schema",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS customers (
+				id INTEGER PRIMARY KEY,
+				name TEXT NOT NULL,
+				email TEXT NOT NULL,
+				phone TEXT NOT NULL
+			);`,
+			`CREATE TABLE IF NOT EXISTS products (
+				id INTEGER PRIMARY KEY,
+				name TEXT NOT NULL,
+				barcode TEXT,
+				price_cents INTEGER NOT NULL DEFAULT 0,
+				currency TEXT NOT NULL DEFAULT 'USD',
+				stock INTEGER NOT NULL
+			);`,
+			`CREATE TABLE IF NOT EXISTS sales (
+				id INTEGER PRIMARY KEY,
+				customer_id INTEGER NOT NULL,
+				created_at DATETIME NOT NULL,
+				FOREIGN KEY(customer_id) REFERENCES customers(id)
+			);`,
+			`CREATE TABLE IF NOT EXISTS sale_items (
+				id INTEGER PRIMARY KEY,
+				sale_id INTEGER NOT NULL,
+				product_id INTEGER NOT NULL,
+				quantity INTEGER NOT NULL,
+				price_cents INTEGER NOT NULL DEFAULT 0,
+				currency TEXT NOT NULL DEFAULT 'USD',
+				FOREIGN KEY(sale_id) REFERENCES sales(id),
+				FOREIGN KEY(product_id) REFERENCES products(id)
+			);`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_products_barcode ON products(barcode);`,
+		},
+	},
+	{
+		version: 2,
+		name:    "tax classes and sale tax lines",
+		statements: []string{
+			`CREATE TABLE settings (
+				key TEXT PRIMARY KEY,
+				value TEXT NOT NULL
+			);`,
+			`INSERT INTO settings (key, value) VALUES ('tax_mode', 'inclusive');`,
+			`CREATE TABLE tax_classes (
+				id INTEGER PRIMARY KEY,
+				code TEXT NOT NULL UNIQUE,
+				name TEXT NOT NULL,
+				rate_bp INTEGER NOT NULL
+			);`,
+			`INSERT INTO tax_classes (id, code, name, rate_bp) VALUES
+				(1, 'standard', 'Standard', 2000),
+				(2, 'reduced', 'Reduced', 500),
+				(3, 'exempt', 'Exempt', 0);`,
+			`ALTER TABLE products ADD COLUMN tax_class_id INTEGER NOT NULL DEFAULT 1;`,
+			`ALTER TABLE sales ADD COLUMN tax_mode TEXT NOT NULL DEFAULT 'inclusive';`,
+			`ALTER TABLE sales ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD';`,
+			`ALTER TABLE sales ADD COLUMN subtotal_cents INTEGER NOT NULL DEFAULT 0;`,
+			`ALTER TABLE sales ADD COLUMN tax_cents INTEGER NOT NULL DEFAULT 0;`,
+			`ALTER TABLE sales ADD COLUMN total_cents INTEGER NOT NULL DEFAULT 0;`,
+			`ALTER TABLE sale_items ADD COLUMN tax_class_id INTEGER NOT NULL DEFAULT 3;`,
+			`ALTER TABLE sale_items ADD COLUMN tax_rate_bp INTEGER NOT NULL DEFAULT 0;`,
+			`ALTER TABLE sale_items ADD COLUMN net_cents INTEGER NOT NULL DEFAULT 0;`,
+			`ALTER TABLE sale_items ADD COLUMN tax_cents INTEGER NOT NULL DEFAULT 0;`,
+			`ALTER TABLE sale_items ADD COLUMN total_cents INTEGER NOT NULL DEFAULT 0;`,
+			`UPDATE sale_items SET
+				net_cents = quantity * price_cents,
+				total_cents = quantity * price_cents;`,
+			`UPDATE sales SET
+				subtotal_cents = (SELECT COALESCE(SUM(net_cents), 0) FROM sale_items WHERE sale_id = sales.id),
+				total_cents = (SELECT COALESCE(SUM(total_cents), 0) FROM sale_items WHERE sale_id = sales.id);`,
+			`CREATE TABLE sale_taxes (
+				id INTEGER PRIMARY KEY,
+				sale_id INTEGER NOT NULL,
+				tax_class_id INTEGER NOT NULL,
+				rate_bp INTEGER NOT NULL,
+				net_cents INTEGER NOT NULL,
+				tax_cents INTEGER NOT NULL,
+				FOREIGN KEY(sale_id) REFERENCES sales(id),
+				FOREIGN KEY(tax_class_id) REFERENCES tax_classes(id)
+			);`,
+			`INSERT INTO sale_taxes (sale_id, tax_class_id, rate_bp, net_cents, tax_cents)
+				SELECT sale_id, tax_class_id, tax_rate_bp, SUM(net_cents), SUM(tax_cents)
+				FROM sale_items GROUP BY sale_id, tax_class_id, tax_rate_bp;`,
+		},
+	},
+	{
+		version: 3,
+		name:    "promotions and line discounts",
+		statements: []string{
+			`CREATE TABLE promotions (
+				id INTEGER PRIMARY KEY,
+				name TEXT NOT NULL,
+				kind TEXT NOT NULL,
+				active INTEGER NOT NULL DEFAULT 1,
+				percent_bp INTEGER NOT NULL DEFAULT 0,
+				amount_cents INTEGER NOT NULL DEFAULT 0,
+				buy_qty INTEGER NOT NULL DEFAULT 0,
+				get_qty INTEGER NOT NULL DEFAULT 0,
+				threshold_cents INTEGER NOT NULL DEFAULT 0,
+				currency TEXT NOT NULL DEFAULT 'USD',
+				starts_at DATETIME,
+				ends_at DATETIME
+			);`,
+			`CREATE TABLE promotion_products (
+				promotion_id INTEGER NOT NULL,
+				product_id INTEGER NOT NULL,
+				PRIMARY KEY(promotion_id, product_id),
+				FOREIGN KEY(promotion_id) REFERENCES promotions(id),
+				FOREIGN KEY(product_id) REFERENCES products(id)
+			);`,
+			`ALTER TABLE sales ADD COLUMN discount_cents INTEGER NOT NULL DEFAULT 0;`,
+			`ALTER TABLE sale_items ADD COLUMN discount_cents INTEGER NOT NULL DEFAULT 0;`,
+			`CREATE TABLE sale_item_discounts (
+				id INTEGER PRIMARY KEY,
+				sale_item_id INTEGER NOT NULL,
+				promotion_id INTEGER NOT NULL,
+				name TEXT NOT NULL,
+				amount_cents INTEGER NOT NULL,
+				FOREIGN KEY(sale_item_id) REFERENCES sale_items(id),
+				FOREIGN KEY(promotion_id) REFERENCES promotions(id)
+			);`,
+		},
+	},
+	{
+		version: 4,
+		name:    "payments and store credit",
+		statements: []string{
+			`CREATE TABLE payments (
+				id INTEGER PRIMARY KEY,
+				sale_id INTEGER NOT NULL,
+				tender TEXT NOT NULL,
+				amount_cents INTEGER NOT NULL,
+				change_cents INTEGER NOT NULL DEFAULT 0,
+				currency TEXT NOT NULL DEFAULT 'USD',
+				reference TEXT NOT NULL DEFAULT '',
+				created_at DATETIME NOT NULL,
+				FOREIGN KEY(sale_id) REFERENCES sales(id)
+			);`,
+			`CREATE INDEX idx_payments_sale ON payments(sale_id);`,
+			`ALTER TABLE customers ADD COLUMN store_credit_cents INTEGER NOT NULL DEFAULT 0;`,
+		},
+	},
+	{
+		version: 5,
+		name:    "returns",
+		statements: []string{
+			`CREATE TABLE returns (
+				id INTEGER PRIMARY KEY,
+				sale_id INTEGER NOT NULL,
+				created_at DATETIME NOT NULL,
+				reason TEXT NOT NULL DEFAULT '',
+				restock INTEGER NOT NULL,
+				tender TEXT NOT NULL,
+				refund_cents INTEGER NOT NULL,
+				tax_cents INTEGER NOT NULL,
+				currency TEXT NOT NULL DEFAULT 'USD',
+				reference TEXT NOT NULL DEFAULT '',
+				FOREIGN KEY(sale_id) REFERENCES sales(id)
+			);`,
+			`CREATE TABLE return_items (
+				id INTEGER PRIMARY KEY,
+				return_id INTEGER NOT NULL,
+				sale_item_id INTEGER NOT NULL,
+				quantity INTEGER NOT NULL,
+				refund_cents INTEGER NOT NULL,
+				tax_cents INTEGER NOT NULL,
+				FOREIGN KEY(return_id) REFERENCES returns(id),
+				FOREIGN KEY(sale_item_id) REFERENCES sale_items(id)
+			);`,
+			`CREATE INDEX idx_returns_sale ON returns(sale_id);`,
+			`CREATE INDEX idx_return_items_sale_item ON return_items(sale_item_id);`,
+		},
+	},
+	{
+		version: 6,
+		name:    "sale_status",
+		statements: []string{
+			`ALTER TABLE sales ADD COLUMN status TEXT NOT NULL DEFAULT 'completed';`,
+			`ALTER TABLE sales ADD COLUMN voided_at DATETIME;`,
+			`ALTER TABLE sales ADD COLUMN voided_by TEXT NOT NULL DEFAULT '';`,
+			`ALTER TABLE sales ADD COLUMN void_reason TEXT NOT NULL DEFAULT '';`,
+			`CREATE INDEX idx_sales_status ON sales(status);`,
+		},
+	},
+	{
+		version: 7,
+		name:    "suspended_sales",
+		statements: []string{
+			`CREATE TABLE suspended_sales (
+				id INTEGER PRIMARY KEY,
+				label TEXT NOT NULL,
+				customer_id INTEGER,
+				created_at DATETIME NOT NULL,
+				FOREIGN KEY(customer_id) REFERENCES customers(id)
+			);`,
+			`CREATE TABLE suspended_sale_items (
+				id INTEGER PRIMARY KEY,
+				suspended_sale_id INTEGER NOT NULL,
+				product_id INTEGER NOT NULL,
+				quantity INTEGER NOT NULL,
+				FOREIGN KEY(suspended_sale_id) REFERENCES suspended_sales(id),
+				FOREIGN KEY(product_id) REFERENCES products(id)
+			);`,
+			`CREATE INDEX idx_suspended_sale_items_sale ON suspended_sale_items(suspended_sale_id);`,
+		},
+	},
+	{
+		version: 8,
+		name:    "stock_movements",
+		statements: []string{
+			`CREATE TABLE stock_movements (
+				id INTEGER PRIMARY KEY,
+				product_id INTEGER NOT NULL,
+				kind TEXT NOT NULL,
+				quantity INTEGER NOT NULL,
+				reason TEXT NOT NULL DEFAULT '',
+				reference TEXT NOT NULL DEFAULT '',
+				user TEXT NOT NULL DEFAULT '',
+				created_at DATETIME NOT NULL,
+				FOREIGN KEY(product_id) REFERENCES products(id)
+			);`,
+			`CREATE INDEX idx_stock_movements_product ON stock_movements(product_id);`,
+			`INSERT INTO stock_movements (product_id, kind, quantity, reason, created_at)
+			SELECT id, 'adjustment', stock, 'opening balance', CURRENT_TIMESTAMP FROM products WHERE stock <> 0;`,
+		},
+	},
+	{
+		version: 9,
+		name:    "purchasing",
+		statements: []string{
+			`CREATE TABLE suppliers (
+				id INTEGER PRIMARY KEY,
+				name TEXT NOT NULL,
+				email TEXT NOT NULL DEFAULT '',
+				phone TEXT NOT NULL DEFAULT ''
+			);`,
+			`CREATE TABLE purchase_orders (
+				id INTEGER PRIMARY KEY,
+				supplier_id INTEGER NOT NULL,
+				status TEXT NOT NULL DEFAULT 'draft',
+				reference TEXT NOT NULL DEFAULT '',
+				currency TEXT NOT NULL DEFAULT 'USD',
+				created_at DATETIME NOT NULL,
+				sent_at DATETIME,
+				closed_at DATETIME,
+				FOREIGN KEY(supplier_id) REFERENCES suppliers(id)
+			);`,
+			`CREATE TABLE purchase_order_lines (
+				id INTEGER PRIMARY KEY,
+				purchase_order_id INTEGER NOT NULL,
+				product_id INTEGER NOT NULL,
+				quantity INTEGER NOT NULL,
+				cost_cents INTEGER NOT NULL,
+				received INTEGER NOT NULL DEFAULT 0,
+				FOREIGN KEY(purchase_order_id) REFERENCES purchase_orders(id),
+				FOREIGN KEY(product_id) REFERENCES products(id)
+			);`,
+			`CREATE INDEX idx_purchase_orders_supplier ON purchase_orders(supplier_id);`,
+			`CREATE INDEX idx_purchase_order_lines_order ON purchase_order_lines(purchase_order_id);`,
+		},
+	},
+	{
+		version: 10,
+		name:    "product_variants",
+		statements: []string{
+			`ALTER TABLE products ADD COLUMN parent_id INTEGER REFERENCES products(id);`,
+			`ALTER TABLE products ADD COLUMN price_override INTEGER NOT NULL DEFAULT 0;`,
+			`CREATE INDEX idx_products_parent ON products(parent_id);`,
+			`CREATE TABLE variant_attributes (
+				product_id INTEGER NOT NULL,
+				name TEXT NOT NULL,
+				value TEXT NOT NULL,
+				position INTEGER NOT NULL,
+				PRIMARY KEY(product_id, name),
+				FOREIGN KEY(product_id) REFERENCES products(id)
+			);`,
+		},
+	},
+	{
+		version: 11,
+		name:    "product_barcodes",
+		statements: []string{
+			`CREATE TABLE product_barcodes (
+				code TEXT PRIMARY KEY,
+				product_id INTEGER NOT NULL,
+				type TEXT NOT NULL,
+				FOREIGN KEY(product_id) REFERENCES products(id)
+			);`,
+			`CREATE INDEX idx_product_barcodes_product ON product_barcodes(product_id);`,
+		},
+	},
+	{
+		version: 12,
+		name:    "categories",
+		statements: []string{
+			`CREATE TABLE categories (
+				id INTEGER PRIMARY KEY,
+				name TEXT NOT NULL,
+				parent_id INTEGER REFERENCES categories(id)
+			);`,
+			`CREATE INDEX idx_categories_parent ON categories(parent_id);`,
+			`ALTER TABLE products ADD COLUMN category_id INTEGER REFERENCES categories(id);`,
+			`CREATE INDEX idx_products_category ON products(category_id);`,
+		},
+	},
+	{
+		version: 13,
+		name:    "decimal_quantities",
+		// Quantities are stored in thousandths of the product's unit.
+		statements: []string{
+			`ALTER TABLE products ADD COLUMN unit TEXT NOT NULL DEFAULT 'each';`,
+			`UPDATE products SET stock = stock * 1000;`,
+			`UPDATE sale_items SET quantity = quantity * 1000;`,
+			`UPDATE return_items SET quantity = quantity * 1000;`,
+			`UPDATE suspended_sale_items SET quantity = quantity * 1000;`,
+			`UPDATE stock_movements SET quantity = quantity * 1000;`,
+			`UPDATE purchase_order_lines SET quantity = quantity * 1000, received = received * 1000;`,
+		},
+	},
+	{
+		version: 14,
+		name:    "product_packs",
+		statements: []string{
+			`CREATE TABLE product_packs (
+				id INTEGER PRIMARY KEY,
+				product_id INTEGER NOT NULL,
+				name TEXT NOT NULL,
+				barcode TEXT NOT NULL UNIQUE,
+				factor INTEGER NOT NULL,
+				price_cents INTEGER,
+				FOREIGN KEY(product_id) REFERENCES products(id)
+			);`,
+			`CREATE INDEX idx_product_packs_product ON product_packs(product_id);`,
+			`ALTER TABLE sale_items ADD COLUMN pack_id INTEGER REFERENCES product_packs(id);`,
+			`ALTER TABLE sale_items ADD COLUMN packs INTEGER NOT NULL DEFAULT 0;`,
+			`ALTER TABLE sale_items ADD COLUMN pack_price_cents INTEGER NOT NULL DEFAULT 0;`,
+			`ALTER TABLE suspended_sale_items ADD COLUMN pack_id INTEGER REFERENCES product_packs(id);`,
+		},
+	},
+	{
+		version: 15,
+		name:    "reorder_levels",
+		statements: []string{
+			`ALTER TABLE products ADD COLUMN min_stock INTEGER NOT NULL DEFAULT 0;`,
+			`ALTER TABLE products ADD COLUMN reorder_point INTEGER NOT NULL DEFAULT 0;`,
+			`ALTER TABLE products ADD COLUMN reorder_qty INTEGER NOT NULL DEFAULT 0;`,
+			`ALTER TABLE products ADD COLUMN supplier_id INTEGER REFERENCES suppliers(id);`,
+		},
+	},
+	{
+		version: 16,
+		name:    "stock_counts",
+		statements: []string{
+			`CREATE TABLE stock_counts (
+				id INTEGER PRIMARY KEY,
+				name TEXT NOT NULL,
+				category_id INTEGER,
+				status TEXT NOT NULL,
+				created_at DATETIME NOT NULL,
+				closed_at DATETIME,
+				closed_by TEXT NOT NULL DEFAULT '',
+				FOREIGN KEY(category_id) REFERENCES categories(id)
+			);`,
+			`CREATE TABLE stock_count_lines (
+				id INTEGER PRIMARY KEY,
+				count_id INTEGER NOT NULL,
+				product_id INTEGER NOT NULL,
+				expected INTEGER NOT NULL,
+				counted INTEGER,
+				FOREIGN KEY(count_id) REFERENCES stock_counts(id),
+				FOREIGN KEY(product_id) REFERENCES products(id),
+				UNIQUE(count_id, product_id)
+			);`,
+		},
+	},
+	{
+		version: 17,
+		name:    "locations",
+		statements: []string{
+			`CREATE TABLE locations (
+				id INTEGER PRIMARY KEY,
+				name TEXT NOT NULL UNIQUE
+			);`,
+			`INSERT INTO locations (id, name) VALUES (1, 'Main');`,
+			`CREATE TABLE product_stock (
+				product_id INTEGER NOT NULL,
+				location_id INTEGER NOT NULL,
+				quantity INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY(product_id, location_id),
+				FOREIGN KEY(product_id) REFERENCES products(id),
+				FOREIGN KEY(location_id) REFERENCES locations(id)
+			);`,
+			`INSERT INTO product_stock (product_id, location_id, quantity) SELECT id, 1, stock FROM products;`,
+			`ALTER TABLE stock_movements ADD COLUMN location_id INTEGER NOT NULL DEFAULT 1;`,
+			`ALTER TABLE sales ADD COLUMN location_id INTEGER NOT NULL DEFAULT 1;`,
+			`ALTER TABLE stock_counts ADD COLUMN location_id INTEGER NOT NULL DEFAULT 1;`,
+		},
+	},
+	{
+		version: 18,
+		name:    "stock_transfers",
+		statements: []string{
+			`CREATE TABLE transfers (
+				id INTEGER PRIMARY KEY,
+				from_location_id INTEGER NOT NULL,
+				to_location_id INTEGER NOT NULL,
+				status TEXT NOT NULL,
+				reference TEXT NOT NULL DEFAULT '',
+				created_at DATETIME NOT NULL,
+				dispatched_at DATETIME,
+				dispatched_by TEXT NOT NULL DEFAULT '',
+				received_at DATETIME,
+				received_by TEXT NOT NULL DEFAULT '',
+				FOREIGN KEY(from_location_id) REFERENCES locations(id),
+				FOREIGN KEY(to_location_id) REFERENCES locations(id)
+			);`,
+			`CREATE TABLE transfer_lines (
+				id INTEGER PRIMARY KEY,
+				transfer_id INTEGER NOT NULL,
+				product_id INTEGER NOT NULL,
+				quantity INTEGER NOT NULL,
+				received INTEGER,
+				note TEXT NOT NULL DEFAULT '',
+				FOREIGN KEY(transfer_id) REFERENCES transfers(id),
+				FOREIGN KEY(product_id) REFERENCES products(id)
+			);`,
+			`CREATE INDEX idx_transfer_lines_transfer ON transfer_lines(transfer_id);`,
+		},
+	},
+	{
+		version: 19,
+		name:    "lots",
+		statements: []string{
+			`ALTER TABLE products ADD COLUMN track_lots INTEGER NOT NULL DEFAULT 0;`,
+			`CREATE TABLE lots (
+				id INTEGER PRIMARY KEY,
+				product_id INTEGER NOT NULL,
+				location_id INTEGER NOT NULL,
+				number TEXT NOT NULL,
+				expiry DATETIME,
+				quantity INTEGER NOT NULL DEFAULT 0,
+				received_at DATETIME NOT NULL,
+				UNIQUE(product_id, location_id, number),
+				FOREIGN KEY(product_id) REFERENCES products(id),
+				FOREIGN KEY(location_id) REFERENCES locations(id)
+			);`,
+			`CREATE TABLE sale_item_lots (
+				id INTEGER PRIMARY KEY,
+				sale_item_id INTEGER NOT NULL,
+				lot_id INTEGER NOT NULL,
+				quantity INTEGER NOT NULL,
+				FOREIGN KEY(sale_item_id) REFERENCES sale_items(id),
+				FOREIGN KEY(lot_id) REFERENCES lots(id)
+			);`,
+			`CREATE INDEX idx_sale_item_lots_item ON sale_item_lots(sale_item_id);`,
+			`ALTER TABLE stock_movements ADD COLUMN lot TEXT NOT NULL DEFAULT '';`,
+			`ALTER TABLE suspended_sale_items ADD COLUMN lot TEXT NOT NULL DEFAULT '';`,
+		},
+	},
+	{
+		version: 20,
+		name:    "serial_numbers",
+		statements: []string{
+			`ALTER TABLE products ADD COLUMN track_serials INTEGER NOT NULL DEFAULT 0;`,
+			`CREATE TABLE serials (
+				id INTEGER PRIMARY KEY,
+				product_id INTEGER NOT NULL,
+				serial TEXT NOT NULL,
+				status TEXT NOT NULL,
+				received_at DATETIME NOT NULL,
+				UNIQUE(product_id, serial),
+				FOREIGN KEY(product_id) REFERENCES products(id)
+			);`,
+			`CREATE INDEX idx_serials_serial ON serials(serial);`,
+			`CREATE TABLE sale_item_serials (
+				id INTEGER PRIMARY KEY,
+				sale_item_id INTEGER NOT NULL,
+				serial_id INTEGER NOT NULL,
+				return_id INTEGER,
+				FOREIGN KEY(sale_item_id) REFERENCES sale_items(id),
+				FOREIGN KEY(serial_id) REFERENCES serials(id),
+				FOREIGN KEY(return_id) REFERENCES returns(id)
+			);`,
+			`CREATE INDEX idx_sale_item_serials_item ON sale_item_serials(sale_item_id);`,
+			`CREATE INDEX idx_sale_item_serials_serial ON sale_item_serials(serial_id);`,
+			`ALTER TABLE suspended_sale_items ADD COLUMN serials TEXT NOT NULL DEFAULT '';`,
+		},
+	},
+	{
+		version: 21,
+		name:    "price_lists",
+		statements: []string{
+			`CREATE TABLE price_lists (
+				id INTEGER PRIMARY KEY,
+				name TEXT NOT NULL UNIQUE
+			);`,
+			`CREATE TABLE price_list_prices (
+				id INTEGER PRIMARY KEY,
+				price_list_id INTEGER NOT NULL,
+				product_id INTEGER NOT NULL,
+				min_quantity INTEGER NOT NULL DEFAULT 0,
+				price_cents INTEGER NOT NULL,
+				currency TEXT NOT NULL,
+				UNIQUE(price_list_id, product_id, min_quantity),
+				FOREIGN KEY(price_list_id) REFERENCES price_lists(id),
+				FOREIGN KEY(product_id) REFERENCES products(id)
+			);`,
+			`ALTER TABLE customers ADD COLUMN price_list_id INTEGER REFERENCES price_lists(id);`,
+			`ALTER TABLE sale_items ADD COLUMN price_list TEXT NOT NULL DEFAULT '';`,
+		},
+	},
+	{
+		version: 22,
+		name:    "price_history",
+		statements: []string{
+			`CREATE TABLE price_history (
+				id INTEGER PRIMARY KEY,
+				product_id INTEGER NOT NULL,
+				old_price_cents INTEGER NOT NULL,
+				new_price_cents INTEGER NOT NULL,
+				currency TEXT NOT NULL,
+				changed_at DATETIME NOT NULL,
+				user TEXT NOT NULL DEFAULT '',
+				note TEXT NOT NULL DEFAULT '',
+				FOREIGN KEY(product_id) REFERENCES products(id)
+			);`,
+			`CREATE INDEX idx_price_history_product ON price_history(product_id, changed_at);`,
+			`CREATE TABLE scheduled_prices (
+				id INTEGER PRIMARY KEY,
+				product_id INTEGER NOT NULL,
+				price_cents INTEGER NOT NULL,
+				currency TEXT NOT NULL,
+				effective_at DATETIME NOT NULL,
+				user TEXT NOT NULL DEFAULT '',
+				created_at DATETIME NOT NULL,
+				applied_at DATETIME,
+				FOREIGN KEY(product_id) REFERENCES products(id)
+			);`,
+			`CREATE INDEX idx_scheduled_prices_due ON scheduled_prices(applied_at, effective_at);`,
+		},
+	},
+	{
+		version: 23,
+		name:    "cost_prices",
+		statements: []string{
+			`ALTER TABLE products ADD COLUMN cost_cents INTEGER NOT NULL DEFAULT 0;`,
+			`CREATE TABLE cost_layers (
+				id INTEGER PRIMARY KEY,
+				product_id INTEGER NOT NULL,
+				quantity INTEGER NOT NULL,
+				remaining INTEGER NOT NULL,
+				unit_cost_cents INTEGER NOT NULL,
+				currency TEXT NOT NULL,
+				reference TEXT NOT NULL DEFAULT '',
+				FOREIGN KEY(product_id) REFERENCES products(id)
+			);`,
+			`CREATE INDEX idx_cost_layers_open ON cost_layers(product_id, remaining);`,
+			`UPDATE products SET cost_cents = COALESCE((
+				SELECT l.cost_cents FROM purchase_order_lines l
+				JOIN purchase_orders o ON o.id = l.purchase_order_id
+				WHERE l.product_id = products.id AND l.received > 0 AND o.currency = products.currency
+				ORDER BY l.id DESC LIMIT 1
+			), 0);`,
+			`INSERT INTO cost_layers (product_id, quantity, remaining, unit_cost_cents, currency, reference)
+			SELECT id, stock, stock, cost_cents, currency, 'opening stock' FROM products WHERE stock > 0;`,
+			`ALTER TABLE sale_items ADD COLUMN unit_cost_cents INTEGER NOT NULL DEFAULT 0;`,
+			`ALTER TABLE sale_items ADD COLUMN cost_cents INTEGER NOT NULL DEFAULT 0;`,
+		},
+	},
+	{
+		version: 24,
+		name:    "transfer_lots",
+		statements: []string{
+			`CREATE TABLE transfer_line_lots (
+				id INTEGER PRIMARY KEY,
+				transfer_line_id INTEGER NOT NULL,
+				lot_id INTEGER NOT NULL,
+				quantity INTEGER NOT NULL,
+				FOREIGN KEY(transfer_line_id) REFERENCES transfer_lines(id),
+				FOREIGN KEY(lot_id) REFERENCES lots(id)
+			);`,
+			`CREATE INDEX idx_transfer_line_lots_line ON transfer_line_lots(transfer_line_id);`,
+		},
+	},
+	{
+		version: 25,
+		name:    "serial_locations",
+		statements: []string{
+			`ALTER TABLE serials ADD COLUMN location_id INTEGER NOT NULL DEFAULT 1;`,
+			`CREATE TABLE transfer_line_serials (
+				id INTEGER PRIMARY KEY,
+				transfer_line_id INTEGER NOT NULL,
+				serial_id INTEGER NOT NULL,
+				FOREIGN KEY(transfer_line_id) REFERENCES transfer_lines(id),
+				FOREIGN KEY(serial_id) REFERENCES serials(id)
+			);`,
+			`CREATE INDEX idx_transfer_line_serials_line ON transfer_line_serials(transfer_line_id);`,
+		},
+	},
+}
@@ -1,6 +1,26 @@
 package store
 
+import (
+	"time"
+
+	"pos-system/internal/money"
+	"pos-system/internal/promo"
+	"pos-system/internal/quantity"
+	"pos-system/internal/tax"
+)
+
 type Customer struct {
+	ID          int64
+	Name        string
+	Email       string
+	Phone       string
+	StoreCredit money.Money
+	// PriceListID is the price list the customer pays, nil for product
+	// prices.
+	PriceListID *int64
+}
+
+type Supplier struct {
 	ID    int64
 	Name  string
 	Email string
@@ -11,11 +31,254 @@ type Product struct {
 	ID      int64
 	Name    string
 	Barcode string
-	Price   float64
-	Stock   int64
+	Price   money.Money
+	// Cost is what one base unit costs to buy, kept up to date by the
+	// costing method as stock comes in and goes out.
+	Cost       money.Money
+	Unit       quantity.Unit
+	Stock      quantity.Quantity
+	TaxClassID int64
+	// ParentID is set on variants. A variant without PriceOverride follows
+	// its parent's price.
+	ParentID      *int64
+	PriceOverride bool
+	CategoryID    *int64
+	// TrackLots products are received by lot and sold first-expiry-first-out.
+	TrackLots bool
+	// TrackSerials products record the serial number of every unit received
+	// and sold.
+	TrackSerials bool
+	// InTransit is stock dispatched on transfers that have not arrived. It
+	// is held at no location and so is not part of Stock.
+	InTransit quantity.Quantity
+	ReorderLevels
+}
+
+// ReorderLevels say when a product should be ordered again and from whom.
+// Zero levels are not set.
+type ReorderLevels struct {
+	SupplierID   *int64
+	MinStock     quantity.Quantity
+	ReorderPoint quantity.Quantity
+	ReorderQty   quantity.Quantity
+}
+
+type StockLevel int
+
+const (
+	StockOK StockLevel = iota
+	StockLow
+	StockBelowMinimum
+	StockOut
+)
+
+// StockLevel grades stock on hand against the product's reorder levels.
+// Stock in transit between locations counts towards the levels, though
+// not against running out.
+func (p Product) StockLevel() StockLevel {
+	switch {
+	case p.Stock <= 0:
+		return StockOut
+	case p.Stock+p.InTransit < p.MinStock:
+		return StockBelowMinimum
+	case p.Stock+p.InTransit <= p.ReorderPoint:
+		return StockLow
+	}
+	return StockOK
 }
 
+type Category struct {
+	ID       int64
+	Name     string
+	ParentID *int64
+}
+
+// Pack is a multiple of a product sold or received under its own barcode,
+// such as a case of 24.
+type Pack struct {
+	ID        int64
+	ProductID int64
+	Name      string
+	Barcode   string
+	// Factor is the number of base units in one pack.
+	Factor quantity.Quantity
+	// Price is what the pack sells for; nil means Factor times the unit price.
+	Price *money.Money
+}
+
+type VariantAttribute struct {
+	Name  string
+	Value string
+}
+
+type Variant struct {
+	Product
+	Attributes []VariantAttribute
+}
+
+type TaxClass struct {
+	ID   int64
+	Code string
+	Name string
+	Rate tax.Rate
+}
+
+// SaleItem is a cart line. With PackID set, Quantity counts packs rather
+// than base units.
 type SaleItem struct {
 	ProductID int64
-	Quantity  int64
+	Quantity  quantity.Quantity
+	PackID    *int64
+	// Lot sells from a particular lot, such as one read from a GS1 label,
+	// instead of first-expiry-first-out.
+	Lot string
+	// Serials are the units sold of a product tracked by serial number, one
+	// per unit.
+	Serials []string
+}
+
+type Promotion struct {
+	promo.Promotion
+	Active   bool
+	StartsAt *time.Time
+	EndsAt   *time.Time
+}
+
+type LineDiscount struct {
+	PromotionID int64
+	Name        string
+	Amount      money.Money
+}
+
+type PricedLine struct {
+	ProductID  int64
+	Name       string
+	Quantity   quantity.Quantity
+	Unit       quantity.Unit
+	UnitPrice  money.Money
+	Discount   money.Money
+	Discounts  []LineDiscount
+	TaxClassID int64
+	TaxRate    tax.Rate
+	Net        money.Money
+	Tax        money.Money
+	Total      money.Money
+	// Pack lines sell Packs at PackPrice each; Quantity is then the base
+	// units they hold.
+	PackID    *int64
+	PackName  string
+	Packs     quantity.Quantity
+	PackPrice money.Money
+	// PriceList names the customer's price list when it set UnitPrice.
+	PriceList string
+}
+
+// Gross is the line value before discounts.
+func (l PricedLine) Gross() money.Money {
+	if l.PackID != nil {
+		return l.Packs.Times(l.PackPrice)
+	}
+	return l.Quantity.Times(l.UnitPrice)
+}
+
+type TaxLine struct {
+	TaxClassID int64
+	Rate       tax.Rate
+	Net        money.Money
+	Tax        money.Money
+}
+
+type PricedCart struct {
+	Mode     tax.Mode
+	Lines    []PricedLine
+	Taxes    []TaxLine
+	Subtotal money.Money
+	Discount money.Money
+	Tax      money.Money
+	Total    money.Money
+}
+
+type SaleLine struct {
+	ID int64
+	PricedLine
+	// Lots are the lots the line was taken from.
+	Lots []LotAllocation
+	// Serials are the units sold on the line.
+	Serials []SoldSerial
+	// UnitCost and Cost are what the goods cost per base unit and in all
+	// when they were sold.
+	UnitCost money.Money
+	Cost     money.Money
+}
+
+// Margin is the line's net revenue less the cost of its goods.
+func (l SaleLine) Margin() money.Money {
+	return l.Net.Sub(l.Cost)
+}
+
+// SaleStatus is where a recorded sale is in its life. Carts still being
+// rung up are not sales yet; they are parked as suspended sales.
+type SaleStatus string
+
+const (
+	SaleCompleted SaleStatus = "completed"
+	SaleVoided    SaleStatus = "voided"
+)
+
+type Sale struct {
+	ID         int64
+	CustomerID int64
+	LocationID int64
+	CreatedAt  time.Time
+	Status     SaleStatus
+	VoidedAt   *time.Time
+	VoidedBy   string
+	VoidReason string
+	Mode       tax.Mode
+	Lines      []SaleLine
+	Taxes      []TaxLine
+	Subtotal   money.Money
+	Discount   money.Money
+	Tax        money.Money
+	Total      money.Money
+	Payments   []Payment
+	Change     money.Money
+}
+
+type SuspendedSale struct {
+	ID         int64
+	Label      string
+	CustomerID *int64
+	CreatedAt  time.Time
+	Items      []SaleItem
+}
+
+type StockShortage struct {
+	ProductID int64
+	Name      string
+	Requested quantity.Quantity
+	Available quantity.Quantity
+}
+
+type ReturnLine struct {
+	SaleItemID int64
+	Quantity   quantity.Quantity
+	// Serials name the units coming back of a product tracked by serial
+	// number, one per unit.
+	Serials []string
+}
+
+type ReturnableLine struct {
+	SaleLine
+	Returned quantity.Quantity
+}
+
+type Return struct {
+	ID        int64
+	SaleID    int64
+	CreatedAt time.Time
+	Reason    string
+	Restock   bool
+	Refund    Tender
+	Tax       money.Money
 }
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"pos-system/internal/money"
+	"pos-system/internal/quantity"
+)
+
+const packQuery = `SELECT pp.id, pp.product_id, pp.name, pp.barcode, pp.factor, pp.price_cents, p.currency
+	FROM product_packs pp JOIN products p ON p.id = pp.product_id`
+
+func scanPack(row rowScanner) (Pack, error) {
+	var (
+		pack     Pack
+		price    sql.NullInt64
+		currency string
+	)
+	err := row.Scan(&pack.ID, &pack.ProductID, &pack.Name, &pack.Barcode, &pack.Factor, &price, &currency)
+	if price.Valid {
+		amount := money.New(price.Int64, currency)
+		pack.Price = &amount
+	}
+	return pack, err
+}
+
+func ListPacks(db *sql.DB, productID int64) ([]Pack, error) {
+	rows, err := db.Query(packQuery+` WHERE pp.product_id = ? ORDER BY pp.factor`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var packs []Pack
+	for rows.Next() {
+		pack, err := scanPack(rows)
+		if err != nil {
+			return nil, err
+		}
+		packs = append(packs, pack)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return packs, nil
+}
+
+// CreatePack defines a pack of the product. Its barcode must not already
+// scan as anything else.
+func CreatePack(db *sql.DB, pack Pack) (int64, error) {
+	if pack.Name == "" {
+		return 0, errors.New("pack requires a name")
+	}
+	if pack.Barcode == "" {
+		return 0, errors.New("pack requires its own barcode")
+	}
+	if pack.Factor <= 0 {
+		return 0, errors.New("pack must hold more than zero units")
+	}
+	if pack.Price != nil && pack.Price.Amount < 0 {
+		return 0, errors.New("pack price cannot be negative")
+	}
+
+	product, err := GetProduct(db, pack.ProductID)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkQuantity(productUnit(product), pack.Factor); err != nil {
+		return 0, err
+	}
+	if err := checkScannable(pack.Barcode); err != nil {
+		return 0, err
+	}
+	existing, err := GetProductByBarcode(db, pack.Barcode)
+	if err == nil {
+		return 0, fmt.Errorf("%w by product %d", ErrBarcodeInUse, existing.ID)
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+	if err := checkPackFree(db, pack.Barcode); err != nil {
+		return 0, err
+	}
+
+	var price any
+	if pack.Price != nil {
+		price = pack.Price.Amount
+	}
+	result, err := db.Exec(
+		`INSERT INTO product_packs (product_id, name, barcode, factor, price_cents) VALUES (?, ?, ?, ?, ?)`,
+		pack.ProductID,
+		pack.Name,
+		pack.Barcode,
+		pack.Factor,
+		price,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// DeletePack removes a pack definition. Past sales keep their pack
+// quantities and prices.
+func DeletePack(db *sql.DB, id int64) error {
+	_, err := db.Exec(`DELETE FROM product_packs WHERE id = ?`, id)
+	return err
+}
+
+func GetPack(db *sql.DB, id int64) (Pack, error) {
+	return scanPack(db.QueryRow(packQuery+` WHERE pp.id = ?`, id))
+}
+
+// GetPackByBarcode finds the pack a scanned code belongs to.
+func GetPackByBarcode(db *sql.DB, code string) (Pack, error) {
+	return scanPack(db.QueryRow(packQuery+` WHERE pp.barcode = ?`, code))
+}
+
+// packOf loads a pack of productID for pricing, with its effective price.
+func packOf(q queryer, packID, productID int64, unitPrice money.Money) (Pack, money.Money, error) {
+	pack, err := scanPack(q.QueryRow(packQuery+` WHERE pp.id = ? AND pp.product_id = ?`, packID, productID))
+	if err == sql.ErrNoRows {
+		return Pack{}, money.Money{}, fmt.Errorf("pack %d is not a pack of product %d", packID, productID)
+	}
+	if err != nil {
+		return Pack{}, money.Money{}, err
+	}
+	return pack, pack.PriceFor(unitPrice), nil
+}
+
+// PriceFor is what one pack sells for when a single unit sells at unitPrice.
+func (p Pack) PriceFor(unitPrice money.Money) money.Money {
+	if p.Price != nil {
+		return *p.Price
+	}
+	return p.Factor.Times(unitPrice)
+}
+
+// WholePacks is how many complete packs can be made up from stock.
+func (p Pack) WholePacks(stock quantity.Quantity) quantity.Quantity {
+	if stock <= 0 || p.Factor <= 0 {
+		return 0
+	}
+	return quantity.Of(int64(stock) / int64(p.Factor))
+}
+
+func checkPackFree(q queryer, code string) error {
+	var owner int64
+	err := q.QueryRow(`SELECT product_id FROM product_packs WHERE barcode = ?`, code).Scan(&owner)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w by a pack of product %d", ErrBarcodeInUse, owner)
+}
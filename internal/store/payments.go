@@ -0,0 +1,185 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"pos-system/internal/money"
+)
+
+type TenderType string
+
+const (
+	TenderCash        TenderType = "cash"
+	TenderCard        TenderType = "card"
+	TenderVoucher     TenderType = "voucher"
+	TenderStoreCredit TenderType = "store_credit"
+)
+
+var TenderTypes = []TenderType{TenderCash, TenderCard, TenderVoucher, TenderStoreCredit}
+
+var (
+	ErrUnderpaid          = errors.New("tenders do not cover the sale total")
+	ErrChangeFromNonCash  = errors.New("only cash can be overpaid")
+	ErrInvalidTender      = errors.New("invalid tender")
+	ErrInsufficientCredit = errors.New("insufficient store credit")
+)
+
+type Tender struct {
+	Type      TenderType
+	Amount    money.Money
+	Reference string
+}
+
+type Payment struct {
+	ID     int64
+	Tender Tender
+	Change money.Money
+}
+
+// SettleTenders checks that tenders cover total and returns the change due.
+// Change can only be given back out of cash, and every tender must be in
+// the sale's currency.
+func SettleTenders(total money.Money, tenders []Tender) (money.Money, error) {
+	paid := money.Zero(total.Currency)
+	cash := money.Zero(total.Currency)
+	for _, tender := range tenders {
+		if err := validateTender(tender); err != nil {
+			return money.Money{}, err
+		}
+		if tender.Amount.Currency != "" && tender.Amount.Currency != total.Currency {
+			return money.Money{}, fmt.Errorf(
+				"%w: %s tender on a %s sale", money.ErrCurrencyMismatch, tender.Amount.Currency, total.Currency,
+			)
+		}
+		paid = paid.Add(tender.Amount)
+		if tender.Type == TenderCash {
+			cash = cash.Add(tender.Amount)
+		}
+	}
+
+	if paid.Amount < total.Amount {
+		return money.Money{}, fmt.Errorf("%w: %s outstanding", ErrUnderpaid, total.Sub(paid))
+	}
+	change := paid.Sub(total)
+	if change.Amount > cash.Amount {
+		return money.Money{}, fmt.Errorf("%w: overpaid by %s", ErrChangeFromNonCash, change.Sub(cash))
+	}
+	return change, nil
+}
+
+func validTenderType(tenderType TenderType) bool {
+	for _, known := range TenderTypes {
+		if tenderType == known {
+			return true
+		}
+	}
+	return false
+}
+
+func validateTender(tender Tender) error {
+	if !validTenderType(tender.Type) {
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidTender, tender.Type)
+	}
+	if tender.Amount.Amount <= 0 {
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidTender)
+	}
+	return nil
+}
+
+// recordPayments stores tenders against a sale. Change is booked on the
+// last cash tender, and store credit is drawn from the customer's balance.
+func recordPayments(tx *sql.Tx, saleID, customerID int64, tenders []Tender, change money.Money) error {
+	lastCash := -1
+	for i, tender := range tenders {
+		if tender.Type == TenderCash {
+			lastCash = i
+		}
+	}
+
+	now := time.Now()
+	for i, tender := range tenders {
+		if tender.Type == TenderStoreCredit {
+			if err := adjustStoreCredit(tx, customerID, tender.Amount.Neg()); err != nil {
+				return err
+			}
+		}
+
+		tenderChange := money.Zero(tender.Amount.Currency)
+		if i == lastCash {
+			tenderChange = change
+		}
+		_, err := tx.Exec(
+			`INSERT INTO payments (sale_id, tender, amount_cents, change_cents, currency, reference, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			saleID,
+			string(tender.Type),
+			tender.Amount.Amount,
+			tenderChange.Amount,
+			tender.Amount.Currency,
+			tender.Reference,
+			now,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func adjustStoreCredit(tx *sql.Tx, customerID int64, delta money.Money) error {
+	result, err := tx.Exec(
+		`UPDATE customers SET store_credit_cents = store_credit_cents + ?
+		WHERE id = ? AND store_credit_cents + ? >= 0`,
+		delta.Amount,
+		customerID,
+		delta.Amount,
+	)
+	if err != nil {
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return fmt.Errorf("%w for customer %d", ErrInsufficientCredit, customerID)
+	}
+	return nil
+}
+
+func salePayments(db *sql.DB, saleID int64) ([]Payment, error) {
+	rows, err := db.Query(
+		`SELECT id, tender, amount_cents, change_cents, currency, reference
+		FROM payments WHERE sale_id = ? ORDER BY id`,
+		saleID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []Payment
+	for rows.Next() {
+		var (
+			payment        Payment
+			tender         string
+			amount, change int64
+			currency       string
+		)
+		if err := rows.Scan(&payment.ID, &tender, &amount, &change, &currency, &payment.Tender.Reference); err != nil {
+			return nil, err
+		}
+		payment.Tender.Type = TenderType(tender)
+		payment.Tender.Amount = money.New(amount, currency)
+		payment.Change = money.New(change, currency)
+		payments = append(payments, payment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return payments, nil
+}
@@ -0,0 +1,224 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"pos-system/internal/money"
+	"pos-system/internal/quantity"
+)
+
+var ErrPriceListInUse = errors.New("price list is assigned to customers")
+
+// PriceList is a named set of prices, such as wholesale or staff, that
+// customers assigned to it pay instead of the product price.
+type PriceList struct {
+	ID   int64
+	Name string
+}
+
+// ListPrice is what a product sells for on a price list once a cart holds
+// at least MinQuantity of it. Several prices of one product make quantity
+// breaks.
+type ListPrice struct {
+	ID          int64
+	PriceListID int64
+	ProductID   int64
+	Name        string
+	Unit        quantity.Unit
+	MinQuantity quantity.Quantity
+	Price       money.Money
+}
+
+func ListPriceLists(db *sql.DB) ([]PriceList, error) {
+	rows, err := db.Query(`SELECT id, name FROM price_lists ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lists []PriceList
+	for rows.Next() {
+		var list PriceList
+		if err := rows.Scan(&list.ID, &list.Name); err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return lists, nil
+}
+
+func CreatePriceList(db *sql.DB, name string) (int64, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return 0, errors.New("price list requires a name")
+	}
+	result, err := db.Exec(`INSERT INTO price_lists (name) VALUES (?)`, name)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func RenamePriceList(db *sql.DB, id int64, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("price list requires a name")
+	}
+	_, err := db.Exec(`UPDATE price_lists SET name = ? WHERE id = ?`, name, id)
+	return err
+}
+
+// DeletePriceList removes a price list and its prices. Lists still
+// assigned to customers stay.
+func DeletePriceList(db *sql.DB, id int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var customers int64
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM customers WHERE price_list_id = ?`, id).Scan(&customers); err != nil {
+		return err
+	}
+	if customers > 0 {
+		return fmt.Errorf("%w: %d customers", ErrPriceListInUse, customers)
+	}
+	if _, err := tx.Exec(`DELETE FROM price_list_prices WHERE price_list_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM price_lists WHERE id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListPrices returns the prices on a price list by product, lowest
+// quantity break first.
+func ListPrices(db *sql.DB, priceListID int64) ([]ListPrice, error) {
+	rows, err := db.Query(
+		`SELECT lp.id, lp.price_list_id, lp.product_id, p.name, p.unit, lp.min_quantity, lp.price_cents, lp.currency
+		FROM price_list_prices lp JOIN products p ON p.id = lp.product_id
+		WHERE lp.price_list_id = ?
+		ORDER BY p.name, lp.product_id, lp.min_quantity`,
+		priceListID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prices []ListPrice
+	for rows.Next() {
+		var (
+			price          ListPrice
+			unit, currency string
+			cents          int64
+		)
+		err := rows.Scan(&price.ID, &price.PriceListID, &price.ProductID, &price.Name, &unit, &price.MinQuantity, &cents, &currency)
+		if err != nil {
+			return nil, err
+		}
+		price.Unit = quantity.Unit(unit)
+		price.Price = money.New(cents, currency)
+		prices = append(prices, price)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return prices, nil
+}
+
+// SetListPrice puts a product on a price list from a quantity up,
+// replacing any price already set for that quantity. The price is kept in
+// the product's currency.
+func SetListPrice(db *sql.DB, price ListPrice) error {
+	if price.Price.Amount < 0 {
+		return errors.New("price cannot be negative")
+	}
+	if price.MinQuantity < 0 {
+		return errors.New("minimum quantity cannot be negative")
+	}
+	var unit, currency string
+	if err := db.QueryRow(`SELECT unit, currency FROM products WHERE id = ?`, price.ProductID).Scan(&unit, &currency); err != nil {
+		return err
+	}
+	if err := checkQuantity(quantity.Unit(unit), price.MinQuantity); err != nil {
+		return err
+	}
+	_, err := db.Exec(
+		`INSERT INTO price_list_prices (price_list_id, product_id, min_quantity, price_cents, currency) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(price_list_id, product_id, min_quantity) DO UPDATE SET
+			price_cents = excluded.price_cents,
+			currency = excluded.currency`,
+		price.PriceListID,
+		price.ProductID,
+		price.MinQuantity,
+		price.Price.Amount,
+		currency,
+	)
+	return err
+}
+
+func RemoveListPrice(db *sql.DB, id int64) error {
+	_, err := db.Exec(`DELETE FROM price_list_prices WHERE id = ?`, id)
+	return err
+}
+
+// customerPriceList is the price list a customer is assigned to, or nil
+// when they pay product prices.
+func customerPriceList(q queryer, customerID *int64) (*PriceList, error) {
+	if customerID == nil {
+		return nil, nil
+	}
+	var list PriceList
+	err := q.QueryRow(
+		`SELECT pl.id, pl.name FROM customers c JOIN price_lists pl ON pl.id = c.price_list_id WHERE c.id = ?`,
+		*customerID,
+	).Scan(&list.ID, &list.Name)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// listPrice finds a product's price on a price list for qty, taking the
+// highest quantity break reached. Variants without a price of their own
+// take their parent's. ok is false when the list does not price the product.
+func listPrice(q queryer, priceListID, productID, parentID int64, qty quantity.Quantity) (price money.Money, ok bool, err error) {
+	var (
+		cents    int64
+		currency string
+	)
+	err = q.QueryRow(
+		`SELECT price_cents, currency FROM price_list_prices
+		WHERE price_list_id = ? AND product_id IN (?, ?) AND min_quantity <= ?
+		ORDER BY product_id = ? DESC, min_quantity DESC
+		LIMIT 1`,
+		priceListID,
+		productID,
+		parentID,
+		qty,
+		productID,
+	).Scan(&cents, &currency)
+	if err == sql.ErrNoRows {
+		return money.Money{}, false, nil
+	}
+	if err != nil {
+		return money.Money{}, false, err
+	}
+	return money.New(cents, currency), true, nil
+}
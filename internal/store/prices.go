@@ -0,0 +1,307 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"pos-system/internal/money"
+)
+
+var ErrPriceChangeApplied = errors.New("price change has already been applied")
+
+// PriceChange is one edit of a product's price.
+type PriceChange struct {
+	ID        int64
+	ProductID int64
+	OldPrice  money.Money
+	NewPrice  money.Money
+	ChangedAt time.Time
+	User      string
+	// Note says where a change came from when it was not edited directly,
+	// such as a scheduled change or a variant following its parent.
+	Note string
+}
+
+// ScheduledPrice is a price a product moves to at EffectiveAt. AppliedAt
+// is set once it has taken effect.
+type ScheduledPrice struct {
+	ID          int64
+	ProductID   int64
+	Price       money.Money
+	EffectiveAt time.Time
+	User        string
+	CreatedAt   time.Time
+	AppliedAt   *time.Time
+}
+
+// familyPrices reads the prices of a product, the parent it belongs to and
+// all the parent's variants, which a price edit can change together.
+func familyPrices(q queryer, productID int64) (map[int64]money.Money, error) {
+	rows, err := q.Query(
+		`SELECT id, price_cents, currency FROM products
+		WHERE id = (SELECT COALESCE(parent_id, id) FROM products WHERE id = ?)
+			OR parent_id = (SELECT COALESCE(parent_id, id) FROM products WHERE id = ?)`,
+		productID,
+		productID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := make(map[int64]money.Money)
+	for rows.Next() {
+		var (
+			id, cents int64
+			currency  string
+		)
+		if err := rows.Scan(&id, &cents, &currency); err != nil {
+			return nil, err
+		}
+		prices[id] = money.New(cents, currency)
+	}
+	return prices, rows.Err()
+}
+
+// recordPriceChanges logs every price in productID's family that differs
+// from before as changed at the given time. Variants that moved with their
+// parent are noted as such.
+func recordPriceChanges(q queryer, productID int64, before map[int64]money.Money, user, note string, at time.Time) error {
+	after, err := familyPrices(q, productID)
+	if err != nil {
+		return err
+	}
+	for id, price := range after {
+		old, ok := before[id]
+		if !ok || old == price {
+			continue
+		}
+		changeNote := note
+		if id != productID {
+			changeNote = fmt.Sprintf("follows product %d", productID)
+		}
+		if _, err := q.Exec(
+			`INSERT INTO price_history (product_id, old_price_cents, new_price_cents, currency, changed_at, user, note)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			id,
+			old.Amount,
+			price.Amount,
+			price.Currency,
+			at,
+			user,
+			changeNote,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PriceHistory lists every change of a product's price, newest first.
+func PriceHistory(db *sql.DB, productID int64) ([]PriceChange, error) {
+	rows, err := db.Query(
+		`SELECT id, product_id, old_price_cents, new_price_cents, currency, changed_at, user, note
+		FROM price_history WHERE product_id = ? ORDER BY changed_at DESC, id DESC`,
+		productID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []PriceChange
+	for rows.Next() {
+		var (
+			change             PriceChange
+			oldCents, newCents int64
+			currency           string
+		)
+		if err := rows.Scan(
+			&change.ID, &change.ProductID, &oldCents, &newCents, &currency, &change.ChangedAt, &change.User, &change.Note,
+		); err != nil {
+			return nil, err
+		}
+		change.OldPrice = money.New(oldCents, currency)
+		change.NewPrice = money.New(newCents, currency)
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// PriceAt is what a product sold for at a moment: the price replaced by the
+// first change after it, or the current price if it has not changed since.
+// Prices from before the history was kept read as the earliest one known.
+func PriceAt(db *sql.DB, productID int64, at time.Time) (money.Money, error) {
+	var (
+		cents    int64
+		currency string
+	)
+	err := db.QueryRow(
+		`SELECT old_price_cents, currency FROM price_history
+		WHERE product_id = ? AND changed_at > ?
+		ORDER BY changed_at, id LIMIT 1`,
+		productID,
+		at,
+	).Scan(&cents, &currency)
+	if err == sql.ErrNoRows {
+		err = db.QueryRow(`SELECT price_cents, currency FROM products WHERE id = ?`, productID).Scan(&cents, &currency)
+	}
+	if err != nil {
+		return money.Money{}, err
+	}
+	return money.New(cents, currency), nil
+}
+
+// SchedulePriceChange sets a product's price to change at a future time.
+func SchedulePriceChange(db *sql.DB, change ScheduledPrice) (int64, error) {
+	if change.Price.Amount < 0 {
+		return 0, errors.New("price cannot be negative")
+	}
+	if !change.EffectiveAt.After(time.Now()) {
+		return 0, errors.New("scheduled price change must take effect in the future")
+	}
+	var currency string
+	if err := db.QueryRow(`SELECT currency FROM products WHERE id = ?`, change.ProductID).Scan(&currency); err != nil {
+		return 0, err
+	}
+	result, err := db.Exec(
+		`INSERT INTO scheduled_prices (product_id, price_cents, currency, effective_at, user, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		change.ProductID,
+		change.Price.Amount,
+		currency,
+		change.EffectiveAt,
+		change.User,
+		time.Now(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// ListScheduledPrices returns a product's price changes still to come,
+// soonest first.
+func ListScheduledPrices(db *sql.DB, productID int64) ([]ScheduledPrice, error) {
+	rows, err := db.Query(
+		`SELECT id, product_id, price_cents, currency, effective_at, user, created_at, applied_at
+		FROM scheduled_prices WHERE product_id = ? AND applied_at IS NULL
+		ORDER BY effective_at, id`,
+		productID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanScheduledPrices(rows)
+}
+
+func scanScheduledPrices(rows *sql.Rows) ([]ScheduledPrice, error) {
+	var changes []ScheduledPrice
+	for rows.Next() {
+		var (
+			change    ScheduledPrice
+			cents     int64
+			currency  string
+			appliedAt sql.NullTime
+		)
+		if err := rows.Scan(
+			&change.ID, &change.ProductID, &cents, &currency, &change.EffectiveAt, &change.User, &change.CreatedAt, &appliedAt,
+		); err != nil {
+			return nil, err
+		}
+		change.Price = money.New(cents, currency)
+		if appliedAt.Valid {
+			change.AppliedAt = &appliedAt.Time
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// CancelScheduledPrice drops a price change that has not taken effect yet.
+func CancelScheduledPrice(db *sql.DB, id int64) error {
+	result, err := db.Exec(`DELETE FROM scheduled_prices WHERE id = ? AND applied_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return fmt.Errorf("%w: change %d", ErrPriceChangeApplied, id)
+	}
+	return nil
+}
+
+// ApplyDuePriceChanges puts into effect every scheduled price change due by
+// now, oldest first, and returns how many it applied. Each is recorded in
+// the price history as of when it fell due, against the user who scheduled
+// it.
+func ApplyDuePriceChanges(db *sql.DB, now time.Time) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	applied, err := applyDuePriceChanges(tx, now)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return applied, nil
+}
+
+func applyDuePriceChanges(tx *sql.Tx, now time.Time) (int, error) {
+	rows, err := tx.Query(
+		`SELECT id, product_id, price_cents, currency, effective_at, user, created_at, applied_at
+		FROM scheduled_prices WHERE applied_at IS NULL AND effective_at <= ?
+		ORDER BY effective_at, id`,
+		now,
+	)
+	if err != nil {
+		return 0, err
+	}
+	due, err := scanScheduledPrices(rows)
+	rows.Close()
+	if err != nil {
+		return 0, err
+	}
+	if len(due) == 0 {
+		return 0, nil
+	}
+
+	for _, change := range due {
+		product, err := scanProduct(tx.QueryRow(`SELECT `+productColumns+` FROM products WHERE id = ?`, change.ProductID))
+		if err != nil {
+			return 0, err
+		}
+		before, err := familyPrices(tx, product.ID)
+		if err != nil {
+			return 0, err
+		}
+		product.Price = change.Price
+		if err := updateProduct(tx, product); err != nil {
+			return 0, err
+		}
+		note := fmt.Sprintf("scheduled change #%d", change.ID)
+		if err := recordPriceChanges(tx, product.ID, before, change.User, note, change.EffectiveAt); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`UPDATE scheduled_prices SET applied_at = ? WHERE id = ?`, now, change.ID); err != nil {
+			return 0, err
+		}
+	}
+	return len(due), nil
+}
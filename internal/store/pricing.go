@@ -0,0 +1,202 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"pos-system/internal/money"
+	"pos-system/internal/promo"
+	"pos-system/internal/quantity"
+	"pos-system/internal/tax"
+)
+
+type queryer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// PriceCart prices items exactly as CreateSale would, without touching stock.
+// Customers assigned to a price list pay its prices. Scheduled price changes
+// that have fallen due count, though they are left for a sale or
+// ApplyDuePriceChanges to put into effect.
+func PriceCart(db *sql.DB, customerID *int64, items []SaleItem) (PricedCart, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return PricedCart{}, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := applyDuePriceChanges(tx, time.Now()); err != nil {
+		return PricedCart{}, err
+	}
+	return priceCart(tx, customerID, items)
+}
+
+func priceCart(q queryer, customerID *int64, items []SaleItem) (PricedCart, error) {
+	mode, err := taxMode(q)
+	if err != nil {
+		return PricedCart{}, err
+	}
+	priceList, err := customerPriceList(q, customerID)
+	if err != nil {
+		return PricedCart{}, err
+	}
+	promotions, err := activePromotions(q, time.Now())
+	if err != nil {
+		return PricedCart{}, err
+	}
+
+	cart := PricedCart{Mode: mode}
+	promoLines := make([]promo.Line, 0, len(items))
+	packs := make([]*Pack, 0, len(items))
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return PricedCart{}, errors.New("quantity must be greater than zero")
+		}
+
+		var (
+			line       = PricedLine{ProductID: item.ProductID, Quantity: item.Quantity}
+			priceCents int64
+			currency   string
+			unit       string
+			rate       int64
+			parentID   sql.NullInt64
+		)
+		err := q.QueryRow(
+			`SELECT p.name, p.price_cents, p.currency, p.unit, p.tax_class_id, t.rate_bp, p.parent_id
+			FROM products p JOIN tax_classes t ON t.id = p.tax_class_id
+			WHERE p.id = ?`,
+			item.ProductID,
+		).Scan(&line.Name, &priceCents, &currency, &unit, &line.TaxClassID, &rate, &parentID)
+		if err != nil {
+			return PricedCart{}, err
+		}
+		line.Unit = quantity.Unit(unit)
+		line.UnitPrice = money.New(priceCents, currency)
+		line.Discount = money.Zero(currency)
+		line.TaxRate = tax.Rate(rate)
+
+		// Promotions see a pack as one item at the pack price.
+		promoLine := promo.Line{
+			ProductID: line.ProductID,
+			ParentID:  parentID.Int64,
+			Quantity:  line.Quantity,
+			UnitPrice: line.UnitPrice,
+		}
+		if item.PackID != nil {
+			pack, price, err := packOf(q, *item.PackID, item.ProductID, line.UnitPrice)
+			if err != nil {
+				return PricedCart{}, err
+			}
+			if err := checkQuantity(quantity.Each, item.Quantity); err != nil {
+				return PricedCart{}, fmt.Errorf("%s: %w", pack.Name, err)
+			}
+			line.PackID = item.PackID
+			line.PackName = pack.Name
+			line.Packs = item.Quantity
+			line.PackPrice = price
+			line.Quantity = item.Quantity.Mul(pack.Factor)
+			promoLine.Quantity = line.Packs
+			promoLine.UnitPrice = line.PackPrice
+			packs = append(packs, &pack)
+		} else if err := checkQuantity(line.Unit, line.Quantity); err != nil {
+			return PricedCart{}, fmt.Errorf("%s: %w", line.Name, err)
+		}
+
+		if item.PackID == nil {
+			packs = append(packs, nil)
+		}
+		cart.Lines = append(cart.Lines, line)
+		promoLines = append(promoLines, promoLine)
+	}
+
+	// Quantity breaks count everything in the cart of a product, loose and
+	// in packs. Packs with a fixed price keep it.
+	if priceList != nil {
+		totals := make(map[int64]quantity.Quantity, len(cart.Lines))
+		for _, line := range cart.Lines {
+			totals[line.ProductID] += line.Quantity
+		}
+		for i := range cart.Lines {
+			line := &cart.Lines[i]
+			if packs[i] != nil && packs[i].Price != nil {
+				continue
+			}
+			price, ok, err := listPrice(q, priceList.ID, line.ProductID, promoLines[i].ParentID, totals[line.ProductID])
+			if err != nil {
+				return PricedCart{}, err
+			}
+			if !ok {
+				continue
+			}
+			line.UnitPrice = money.New(price.Amount, line.UnitPrice.Currency)
+			line.PriceList = priceList.Name
+			promoLines[i].UnitPrice = line.UnitPrice
+			if packs[i] != nil {
+				line.PackPrice = packs[i].PriceFor(line.UnitPrice)
+				promoLines[i].UnitPrice = line.PackPrice
+			}
+		}
+	}
+
+	rules := make([]promo.Promotion, 0, len(promotions))
+	names := make(map[int64]string, len(promotions))
+	for _, promotion := range promotions {
+		rules = append(rules, promotion.Promotion)
+		names[promotion.ID] = promotion.Name
+	}
+	for _, discount := range promo.Apply(rules, promoLines) {
+		line := &cart.Lines[discount.LineIndex]
+		line.Discount = line.Discount.Add(discount.Amount)
+		line.Discounts = append(line.Discounts, LineDiscount{
+			PromotionID: discount.PromotionID,
+			Name:        names[discount.PromotionID],
+			Amount:      discount.Amount,
+		})
+	}
+
+	for i := range cart.Lines {
+		line := &cart.Lines[i]
+		amounts := tax.Compute(mode, line.Gross().Sub(line.Discount), line.TaxRate)
+		line.Net = amounts.Net
+		line.Tax = amounts.Tax
+		line.Total = amounts.Gross
+
+		cart.Subtotal = cart.Subtotal.Add(line.Net)
+		cart.Discount = cart.Discount.Add(line.Discount)
+		cart.Tax = cart.Tax.Add(line.Tax)
+		cart.Total = cart.Total.Add(line.Total)
+	}
+	cart.Taxes = summarizeTaxes(cart.Lines)
+
+	return cart, nil
+}
+
+func summarizeTaxes(lines []PricedLine) []TaxLine {
+	var taxes []TaxLine
+	for _, line := range lines {
+		found := false
+		for i := range taxes {
+			if taxes[i].TaxClassID == line.TaxClassID && taxes[i].Rate == line.TaxRate {
+				taxes[i].Net = taxes[i].Net.Add(line.Net)
+				taxes[i].Tax = taxes[i].Tax.Add(line.Tax)
+				found = true
+				break
+			}
+		}
+		if !found {
+			taxes = append(taxes, TaxLine{
+				TaxClassID: line.TaxClassID,
+				Rate:       line.TaxRate,
+				Net:        line.Net,
+				Tax:        line.Tax,
+			})
+		}
+	}
+	return taxes
+}
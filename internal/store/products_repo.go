@@ -1,9 +1,72 @@
 package store
 
-import "database/sql"
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
+	"pos-system/internal/money"
+	"pos-system/internal/quantity"
+)
+
+var (
+	ErrProductHasVariants = errors.New("product has variants")
+	ErrProductHasHistory  = errors.New("product has sales or stock history")
+	ErrNestedVariant      = errors.New("variants cannot have variants")
+	ErrFractionalQuantity = errors.New("product is sold in whole units")
+)
+
+const productColumns = `id, name, barcode, price_cents, currency, unit, stock, tax_class_id, parent_id, price_override, category_id,
+	min_stock, reorder_point, reorder_qty, supplier_id, track_lots, track_serials, cost_cents,
+	(SELECT COALESCE(SUM(l.quantity), 0) FROM transfer_lines l JOIN transfers t ON t.id = l.transfer_id
+		WHERE l.product_id = products.id AND t.status = 'dispatched')`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanProduct(row rowScanner) (Product, error) {
+	var (
+		product    Product
+		barcode    sql.NullString
+		priceCents int64
+		costCents  int64
+		currency   string
+		unit       string
+		parentID   sql.NullInt64
+		categoryID sql.NullInt64
+		supplierID sql.NullInt64
+	)
+	err := row.Scan(
+		&product.ID, &product.Name, &barcode, &priceCents, &currency, &unit, &product.Stock, &product.TaxClassID,
+		&parentID, &product.PriceOverride, &categoryID,
+		&product.MinStock, &product.ReorderPoint, &product.ReorderQty, &supplierID, &product.TrackLots, &product.TrackSerials,
+		&costCents, &product.InTransit,
+	)
+	product.Barcode = barcode.String
+	product.Price = money.New(priceCents, currency)
+	product.Cost = money.New(costCents, currency)
+	product.Unit = quantity.Unit(unit)
+	if parentID.Valid {
+		product.ParentID = &parentID.Int64
+	}
+	if categoryID.Valid {
+		product.CategoryID = &categoryID.Int64
+	}
+	if supplierID.Valid {
+		product.SupplierID = &supplierID.Int64
+	}
+	return product, err
+}
+
+// ListProducts returns every product with each parent's variants right after it.
 func ListProducts(db *sql.DB) ([]Product, error) {
-	rows, err := db.Query(`SELECT id, name, barcode, price, stock FROM products ORDER BY id`)
+	rows, err := db.Query(
+		`SELECT ` + productColumns + ` FROM products
+		ORDER BY COALESCE(parent_id, id), parent_id IS NOT NULL, id`,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -11,8 +74,8 @@ func ListProducts(db *sql.DB) ([]Product, error) {
 
 	var products []Product
 	for rows.Next() {
-		var product Product
-		if err := rows.Scan(&product.ID, &product.Name, &product.Barcode, &product.Price, &product.Stock); err != nil {
+		product, err := scanProduct(rows)
+		if err != nil {
 			return nil, err
 		}
 		products = append(products, product)
@@ -24,42 +87,457 @@ func ListProducts(db *sql.DB) ([]Product, error) {
 	return products, nil
 }
 
+// CreateProduct adds a product. Its starting stock is booked as an opening
+// adjustment at its cost price so the ledger accounts for it.
 func CreateProduct(db *sql.DB, product Product) (int64, error) {
-	result, err := db.Exec(
-		`INSERT INTO products (name, barcode, price, stock) VALUES (?, ?, ?, ?)`,
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := checkAliasFree(tx, product.Barcode, 0); err != nil {
+		return 0, err
+	}
+	if err := checkQuantity(productUnit(product), product.Stock); err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec(
+		`INSERT INTO products (name, barcode, price_cents, cost_cents, currency, unit, stock, tax_class_id, category_id, track_lots, track_serials)
+		VALUES (?, ?, ?, ?, ?, ?, 0, ?, ?, ?, ?)`,
 		product.Name,
 		product.Barcode,
-		product.Price,
-		product.Stock,
+		product.Price.Amount,
+		product.Cost.Amount,
+		productCurrency(product),
+		string(productUnit(product)),
+		productTaxClass(product),
+		product.CategoryID,
+		product.TrackLots,
+		product.TrackSerials,
 	)
 	if err != nil {
 		return 0, err
 	}
-	return result.LastInsertId()
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	err = moveStock(tx, StockMovement{
+		ProductID: id,
+		Kind:      MovementAdjustment,
+		Quantity:  product.Stock,
+		Reason:    "opening stock",
+		UnitCost:  &product.Cost,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// UpdateProduct saves a product's details. Stock is left alone; it only
+// changes through stock movements. Changes to a parent carry over to its
+// variants, except where a variant overrides the price. Price changes are
+// recorded in the price history against user. A new cost price revalues
+// the goods on hand under moving average and FIFO costing.
+func UpdateProduct(db *sql.DB, product Product, user string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	before, err := familyPrices(tx, product.ID)
+	if err != nil {
+		return err
+	}
+	var cost int64
+	if err := tx.QueryRow(`SELECT cost_cents FROM products WHERE id = ?`, product.ID).Scan(&cost); err != nil {
+		return err
+	}
+	if err := updateProduct(tx, product); err != nil {
+		return err
+	}
+	if product.Cost.Amount != cost {
+		if err := revalueStock(tx, product.ID, product.Cost); err != nil {
+			return err
+		}
+	}
+	if err := recordPriceChanges(tx, product.ID, before, user, "", time.Now()); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func UpdateProduct(db *sql.DB, product Product) error {
-	_, err := db.Exec(
-		`UPDATE products SET name = ?, barcode = ?, price = ?, stock = ? WHERE id = ?`,
+func updateProduct(tx *sql.Tx, product Product) error {
+	var parentID sql.NullInt64
+	if err := tx.QueryRow(`SELECT parent_id FROM products WHERE id = ?`, product.ID).Scan(&parentID); err != nil {
+		return err
+	}
+	if err := checkAliasFree(tx, product.Barcode, product.ID); err != nil {
+		return err
+	}
+
+	if parentID.Valid {
+		parent, err := scanProduct(tx.QueryRow(`SELECT `+productColumns+` FROM products WHERE id = ?`, parentID.Int64))
+		if err != nil {
+			return err
+		}
+		override := product.Price != parent.Price
+		if _, err := tx.Exec(
+			`UPDATE products SET barcode = ?, price_cents = ?, cost_cents = ?, currency = ?, price_override = ? WHERE id = ?`,
+			product.Barcode,
+			product.Price.Amount,
+			product.Cost.Amount,
+			productCurrency(product),
+			override,
+			product.ID,
+		); err != nil {
+			return err
+		}
+		return refreshVariants(tx, parent)
+	}
+
+	if _, err := tx.Exec(
+		`UPDATE products SET name = ?, barcode = ?, price_cents = ?, cost_cents = ?, currency = ?, unit = ?, tax_class_id = ?, category_id = ?,
+		track_lots = ?, track_serials = ? WHERE id = ?`,
 		product.Name,
 		product.Barcode,
-		product.Price,
-		product.Stock,
+		product.Price.Amount,
+		product.Cost.Amount,
+		productCurrency(product),
+		string(productUnit(product)),
+		productTaxClass(product),
+		product.CategoryID,
+		product.TrackLots,
+		product.TrackSerials,
 		product.ID,
-	)
-	return err
+	); err != nil {
+		return err
+	}
+	product.Price.Currency = productCurrency(product)
+	product.Unit = productUnit(product)
+	product.TaxClassID = productTaxClass(product)
+	return refreshVariants(tx, product)
 }
 
+// productHistory names the records that keep a product from being deleted:
+// deleting it would drop lines from sales, documents and the stock ledger.
+var productHistory = []struct {
+	table, what string
+}{
+	{"sale_items", "sale lines"},
+	{"stock_movements", "stock movements"},
+	{"purchase_order_lines", "purchase order lines"},
+	{"transfer_lines", "transfer lines"},
+	{"stock_count_lines", "stock count lines"},
+	{"suspended_sale_items", "parked cart lines"},
+}
+
+// DeleteProduct removes a product that was never stocked, sold or put on a
+// document, along with its barcodes, packs, prices and other settings.
+// Products with history fail with ErrProductHasHistory.
 func DeleteProduct(db *sql.DB, id int64) error {
-	_, err := db.Exec(`DELETE FROM products WHERE id = ?`, id)
-	return err
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var variants int64
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM products WHERE parent_id = ?`, id).Scan(&variants); err != nil {
+		return err
+	}
+	if variants > 0 {
+		return fmt.Errorf("%w: delete its %d variants first", ErrProductHasVariants, variants)
+	}
+	for _, history := range productHistory {
+		var count int64
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM `+history.table+` WHERE product_id = ?`, id).Scan(&count); err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("%w: %d %s", ErrProductHasHistory, count, history.what)
+		}
+	}
+	for _, table := range []string{
+		"variant_attributes", "product_barcodes", "product_packs", "promotion_products",
+		"price_list_prices", "scheduled_prices", "price_history", "cost_layers",
+		"product_stock", "lots", "serials",
+	} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE product_id = ?`, id); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM products WHERE id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateVariant adds a variant under parentID. Name, unit, tax class,
+// category and lot and serial tracking come from the parent; the price
+// does too unless PriceOverride is set, and the cost price unless the
+// variant has its own.
+func CreateVariant(db *sql.DB, parentID int64, variant Variant) (int64, error) {
+	if len(variant.Attributes) == 0 {
+		return 0, errors.New("variant requires at least one attribute")
+	}
+	if variant.Barcode == "" {
+		return 0, errors.New("variant requires its own barcode")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	parent, err := scanProduct(tx.QueryRow(`SELECT `+productColumns+` FROM products WHERE id = ?`, parentID))
+	if err != nil {
+		return 0, err
+	}
+	if parent.ParentID != nil {
+		return 0, ErrNestedVariant
+	}
+	if err := checkAliasFree(tx, variant.Barcode, 0); err != nil {
+		return 0, err
+	}
+	if err := checkQuantity(productUnit(parent), variant.Stock); err != nil {
+		return 0, err
+	}
+
+	price := parent.Price
+	if variant.PriceOverride {
+		price = variant.Price
+	}
+	cost := parent.Cost
+	if !variant.Cost.IsZero() {
+		cost = variant.Cost
+	}
+	result, err := tx.Exec(
+		`INSERT INTO products (name, barcode, price_cents, cost_cents, currency, unit, stock, tax_class_id, parent_id, price_override, category_id,
+		track_lots, track_serials)
+		VALUES (?, ?, ?, ?, ?, ?, 0, ?, ?, ?, ?, ?, ?)`,
+		variantName(parent.Name, variant.Attributes),
+		variant.Barcode,
+		price.Amount,
+		cost.Amount,
+		productCurrency(parent),
+		string(productUnit(parent)),
+		productTaxClass(parent),
+		parentID,
+		variant.PriceOverride,
+		parent.CategoryID,
+		parent.TrackLots,
+		parent.TrackSerials,
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for position, attribute := range variant.Attributes {
+		if attribute.Name == "" || attribute.Value == "" {
+			return 0, errors.New("variant attributes need a name and a value")
+		}
+		if _, err := tx.Exec(
+			`INSERT INTO variant_attributes (product_id, name, value, position) VALUES (?, ?, ?, ?)`,
+			id,
+			attribute.Name,
+			attribute.Value,
+			position,
+		); err != nil {
+			return 0, err
+		}
+	}
+
+	err = moveStock(tx, StockMovement{
+		ProductID: id,
+		Kind:      MovementAdjustment,
+		Quantity:  variant.Stock,
+		Reason:    "opening stock",
+		UnitCost:  &cost,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func ListVariants(db *sql.DB, parentID int64) ([]Variant, error) {
+	rows, err := db.Query(`SELECT `+productColumns+` FROM products WHERE parent_id = ? ORDER BY id`, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var variants []Variant
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, Variant{Product: product})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range variants {
+		attributes, err := variantAttributes(db, variants[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		variants[i].Attributes = attributes
+	}
+	return variants, nil
+}
+
+func variantAttributes(q queryer, productID int64) ([]VariantAttribute, error) {
+	rows, err := q.Query(`SELECT name, value FROM variant_attributes WHERE product_id = ? ORDER BY position`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attributes []VariantAttribute
+	for rows.Next() {
+		var attribute VariantAttribute
+		if err := rows.Scan(&attribute.Name, &attribute.Value); err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, attribute)
+	}
+	return attributes, rows.Err()
+}
+
+// refreshVariants re-derives the inherited fields of parent's variants.
+func refreshVariants(q queryer, parent Product) error {
+	if _, err := q.Exec(
+		`UPDATE products SET tax_class_id = ?, currency = ?, unit = ?, category_id = ?, track_lots = ?, track_serials = ?
+		WHERE parent_id = ?`,
+		parent.TaxClassID,
+		parent.Price.Currency,
+		string(parent.Unit),
+		parent.CategoryID,
+		parent.TrackLots,
+		parent.TrackSerials,
+		parent.ID,
+	); err != nil {
+		return err
+	}
+	if _, err := q.Exec(
+		`UPDATE products SET price_cents = ? WHERE parent_id = ? AND price_override = 0`,
+		parent.Price.Amount,
+		parent.ID,
+	); err != nil {
+		return err
+	}
+
+	rows, err := q.Query(`SELECT id FROM products WHERE parent_id = ?`, parent.ID)
+	if err != nil {
+		return err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		attributes, err := variantAttributes(q, id)
+		if err != nil {
+			return err
+		}
+		if _, err := q.Exec(`UPDATE products SET name = ? WHERE id = ?`, variantName(parent.Name, attributes), id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func variantName(parentName string, attributes []VariantAttribute) string {
+	values := make([]string, 0, len(attributes))
+	for _, attribute := range attributes {
+		values = append(values, attribute.Value)
+	}
+	return fmt.Sprintf("%s (%s)", parentName, strings.Join(values, ", "))
 }
 
+func GetProduct(db *sql.DB, id int64) (Product, error) {
+	return scanProduct(db.QueryRow(
+		`SELECT `+productColumns+` FROM products WHERE id = ?`,
+		id,
+	))
+}
+
+// GetProductByBarcode finds the product a scanned code belongs to, by its
+// primary barcode or any alias.
 func GetProductByBarcode(db *sql.DB, barcode string) (Product, error) {
-	var product Product
-	err := db.QueryRow(
-		`SELECT id, name, barcode, price, stock FROM products WHERE barcode = ?`,
+	return scanProduct(db.QueryRow(
+		`SELECT `+productColumns+` FROM products
+		WHERE barcode = ? OR id = (SELECT product_id FROM product_barcodes WHERE code = ?)
+		LIMIT 1`,
 		barcode,
-	).Scan(&product.ID, &product.Name, &product.Barcode, &product.Price, &product.Stock)
-	return product, err
+		barcode,
+	))
+}
+
+func productCurrency(product Product) string {
+	if product.Price.Currency == "" {
+		return money.DefaultCurrency
+	}
+	return product.Price.Currency
+}
+
+func productTaxClass(product Product) int64 {
+	if product.TaxClassID == 0 {
+		return DefaultTaxClassID
+	}
+	return product.TaxClassID
+}
+
+func productUnit(product Product) quantity.Unit {
+	if product.Unit == "" {
+		return quantity.Each
+	}
+	return product.Unit
+}
+
+// checkQuantity rejects fractions of products counted each.
+func checkQuantity(unit quantity.Unit, q quantity.Quantity) error {
+	if unit.Whole() && !q.IsWhole() {
+		return fmt.Errorf("%w: %s", ErrFractionalQuantity, q)
+	}
+	return nil
 }
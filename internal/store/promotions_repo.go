@@ -0,0 +1,211 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"pos-system/internal/money"
+	"pos-system/internal/promo"
+)
+
+func ListPromotions(db *sql.DB) ([]Promotion, error) {
+	return listPromotions(db, `SELECT id, name, kind, active, percent_bp, amount_cents, buy_qty, get_qty,
+		threshold_cents, currency, starts_at, ends_at
+		FROM promotions ORDER BY id`)
+}
+
+func activePromotions(q queryer, now time.Time) ([]Promotion, error) {
+	return listPromotions(q, `SELECT id, name, kind, active, percent_bp, amount_cents, buy_qty, get_qty,
+		threshold_cents, currency, starts_at, ends_at
+		FROM promotions
+		WHERE active = 1 AND (starts_at IS NULL OR starts_at <= ?) AND (ends_at IS NULL OR ends_at > ?)
+		ORDER BY id`, now, now)
+}
+
+func listPromotions(q queryer, query string, args ...any) ([]Promotion, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var promotions []Promotion
+	for rows.Next() {
+		var (
+			promotion         Promotion
+			kind, currency    string
+			amount, threshold int64
+			startsAt, endsAt  sql.NullTime
+		)
+		if err := rows.Scan(
+			&promotion.ID, &promotion.Name, &kind, &promotion.Active, &promotion.PercentBP, &amount,
+			&promotion.BuyQty, &promotion.GetQty, &threshold, &currency, &startsAt, &endsAt,
+		); err != nil {
+			return nil, err
+		}
+		promotion.Kind = promo.Kind(kind)
+		promotion.Amount = money.New(amount, currency)
+		promotion.Threshold = money.New(threshold, currency)
+		if startsAt.Valid {
+			promotion.StartsAt = &startsAt.Time
+		}
+		if endsAt.Valid {
+			promotion.EndsAt = &endsAt.Time
+		}
+		promotions = append(promotions, promotion)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range promotions {
+		productIDs, err := promotionProducts(q, promotions[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		promotions[i].ProductIDs = productIDs
+	}
+
+	return promotions, nil
+}
+
+func promotionProducts(q queryer, promotionID int64) ([]int64, error) {
+	rows, err := q.Query(`SELECT product_id FROM promotion_products WHERE promotion_id = ? ORDER BY product_id`, promotionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var productIDs []int64
+	for rows.Next() {
+		var productID int64
+		if err := rows.Scan(&productID); err != nil {
+			return nil, err
+		}
+		productIDs = append(productIDs, productID)
+	}
+	return productIDs, rows.Err()
+}
+
+func CreatePromotion(db *sql.DB, promotion Promotion) (int64, error) {
+	if err := promotion.Validate(); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	result, err := tx.Exec(
+		`INSERT INTO promotions (
+			name, kind, active, percent_bp, amount_cents, buy_qty, get_qty,
+			threshold_cents, currency, starts_at, ends_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		promotion.Name,
+		string(promotion.Kind),
+		promotion.Active,
+		promotion.PercentBP,
+		promotion.Amount.Amount,
+		promotion.BuyQty,
+		promotion.GetQty,
+		promotion.Threshold.Amount,
+		promotionCurrency(promotion),
+		promotion.StartsAt,
+		promotion.EndsAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	promotionID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := setPromotionProducts(tx, promotionID, promotion.ProductIDs); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return promotionID, nil
+}
+
+func UpdatePromotion(db *sql.DB, promotion Promotion) error {
+	if err := promotion.Validate(); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = tx.Exec(
+		`UPDATE promotions SET
+			name = ?, kind = ?, active = ?, percent_bp = ?, amount_cents = ?, buy_qty = ?, get_qty = ?,
+			threshold_cents = ?, currency = ?, starts_at = ?, ends_at = ?
+		WHERE id = ?`,
+		promotion.Name,
+		string(promotion.Kind),
+		promotion.Active,
+		promotion.PercentBP,
+		promotion.Amount.Amount,
+		promotion.BuyQty,
+		promotion.GetQty,
+		promotion.Threshold.Amount,
+		promotionCurrency(promotion),
+		promotion.StartsAt,
+		promotion.EndsAt,
+		promotion.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := setPromotionProducts(tx, promotion.ID, promotion.ProductIDs); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeactivatePromotion keeps the row because past sales reference it.
+func DeactivatePromotion(db *sql.DB, id int64) error {
+	_, err := db.Exec(`UPDATE promotions SET active = 0 WHERE id = ?`, id)
+	return err
+}
+
+func setPromotionProducts(tx *sql.Tx, promotionID int64, productIDs []int64) error {
+	if _, err := tx.Exec(`DELETE FROM promotion_products WHERE promotion_id = ?`, promotionID); err != nil {
+		return err
+	}
+	for _, productID := range productIDs {
+		if _, err := tx.Exec(
+			`INSERT OR IGNORE INTO promotion_products (promotion_id, product_id) VALUES (?, ?)`,
+			promotionID,
+			productID,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func promotionCurrency(promotion Promotion) string {
+	switch {
+	case promotion.Amount.Currency != "":
+		return promotion.Amount.Currency
+	case promotion.Threshold.Currency != "":
+		return promotion.Threshold.Currency
+	}
+	return money.DefaultCurrency
+}
@@ -0,0 +1,413 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"pos-system/internal/money"
+	"pos-system/internal/quantity"
+)
+
+type PurchaseOrderStatus string
+
+const (
+	PODraft             PurchaseOrderStatus = "draft"
+	POSent              PurchaseOrderStatus = "sent"
+	POPartiallyReceived PurchaseOrderStatus = "partially_received"
+	POClosed            PurchaseOrderStatus = "closed"
+)
+
+var (
+	ErrPurchaseOrderState = errors.New("purchase order is not in the right state")
+	ErrOverReceipt        = errors.New("received quantity exceeds quantity ordered")
+)
+
+type PurchaseOrder struct {
+	ID         int64
+	SupplierID int64
+	Supplier   string
+	Status     PurchaseOrderStatus
+	Reference  string
+	CreatedAt  time.Time
+	SentAt     *time.Time
+	ClosedAt   *time.Time
+	Lines      []PurchaseOrderLine
+}
+
+type PurchaseOrderLine struct {
+	ID        int64
+	ProductID int64
+	Name      string
+	Quantity  quantity.Quantity
+	Cost      money.Money
+	Received  quantity.Quantity
+}
+
+// Outstanding is what is still expected from the supplier.
+func (l PurchaseOrderLine) Outstanding() quantity.Quantity {
+	return l.Quantity - l.Received
+}
+
+// Total is the order value at the agreed costs.
+func (o PurchaseOrder) Total() money.Money {
+	total := money.Zero(money.DefaultCurrency)
+	for _, line := range o.Lines {
+		total = total.Add(line.Quantity.Times(line.Cost))
+	}
+	return total
+}
+
+// ReceiptLine is goods received against an order line. With PackID set,
+// Quantity counts packs, which are booked as the base units they hold.
+// Goods go into LocationID, or the default location when it is zero.
+// Products tracked by lot need the Lot they arrived in, and Expiry if it
+// has one; both are ignored for other products.
+type ReceiptLine struct {
+	LineID     int64
+	Quantity   quantity.Quantity
+	PackID     *int64
+	LocationID int64
+	Lot        string
+	Expiry     *time.Time
+	// Serials name each unit received of a product tracked by serial
+	// number, and are ignored for other products.
+	Serials []string
+}
+
+func CreatePurchaseOrder(db *sql.DB, supplierID int64, reference string) (int64, error) {
+	result, err := db.Exec(
+		`INSERT INTO purchase_orders (supplier_id, status, reference, created_at) VALUES (?, ?, ?, ?)`,
+		supplierID,
+		string(PODraft),
+		reference,
+		time.Now(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func ListPurchaseOrders(db *sql.DB) ([]PurchaseOrder, error) {
+	rows, err := db.Query(
+		`SELECT po.id, po.supplier_id, s.name, po.status, po.reference, po.created_at, po.sent_at, po.closed_at
+		FROM purchase_orders po JOIN suppliers s ON s.id = po.supplier_id
+		ORDER BY po.id DESC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []PurchaseOrder
+	for rows.Next() {
+		order, err := scanPurchaseOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range orders {
+		lines, err := purchaseOrderLines(db, orders[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		orders[i].Lines = lines
+	}
+	return orders, nil
+}
+
+func GetPurchaseOrder(db *sql.DB, id int64) (PurchaseOrder, error) {
+	order, err := scanPurchaseOrder(db.QueryRow(
+		`SELECT po.id, po.supplier_id, s.name, po.status, po.reference, po.created_at, po.sent_at, po.closed_at
+		FROM purchase_orders po JOIN suppliers s ON s.id = po.supplier_id
+		WHERE po.id = ?`,
+		id,
+	))
+	if err != nil {
+		return PurchaseOrder{}, err
+	}
+	order.Lines, err = purchaseOrderLines(db, id)
+	if err != nil {
+		return PurchaseOrder{}, err
+	}
+	return order, nil
+}
+
+func scanPurchaseOrder(row rowScanner) (PurchaseOrder, error) {
+	var (
+		order          PurchaseOrder
+		status         string
+		sentAt, closed sql.NullTime
+	)
+	err := row.Scan(&order.ID, &order.SupplierID, &order.Supplier, &status, &order.Reference, &order.CreatedAt, &sentAt, &closed)
+	order.Status = PurchaseOrderStatus(status)
+	if sentAt.Valid {
+		order.SentAt = &sentAt.Time
+	}
+	if closed.Valid {
+		order.ClosedAt = &closed.Time
+	}
+	return order, err
+}
+
+func purchaseOrderLines(q queryer, orderID int64) ([]PurchaseOrderLine, error) {
+	rows, err := q.Query(
+		`SELECT l.id, l.product_id, p.name, l.quantity, l.cost_cents, po.currency, l.received
+		FROM purchase_order_lines l
+		JOIN purchase_orders po ON po.id = l.purchase_order_id
+		JOIN products p ON p.id = l.product_id
+		WHERE l.purchase_order_id = ? ORDER BY l.id`,
+		orderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []PurchaseOrderLine
+	for rows.Next() {
+		var (
+			line     PurchaseOrderLine
+			cost     int64
+			currency string
+		)
+		if err := rows.Scan(&line.ID, &line.ProductID, &line.Name, &line.Quantity, &cost, &currency, &line.Received); err != nil {
+			return nil, err
+		}
+		line.Cost = money.New(cost, currency)
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
+
+func purchaseOrderStatus(q queryer, orderID int64) (PurchaseOrderStatus, error) {
+	var status string
+	err := q.QueryRow(`SELECT status FROM purchase_orders WHERE id = ?`, orderID).Scan(&status)
+	return PurchaseOrderStatus(status), err
+}
+
+// AddPurchaseOrderLine adds a product to a draft order at the agreed unit cost.
+func AddPurchaseOrderLine(db *sql.DB, orderID, productID int64, ordered quantity.Quantity, cost money.Money) (int64, error) {
+	if ordered <= 0 {
+		return 0, errors.New("quantity must be greater than zero")
+	}
+	if cost.Amount < 0 {
+		return 0, errors.New("cost cannot be negative")
+	}
+	status, err := purchaseOrderStatus(db, orderID)
+	if err != nil {
+		return 0, err
+	}
+	if status != PODraft {
+		return 0, fmt.Errorf("%w: only draft orders can be edited", ErrPurchaseOrderState)
+	}
+	var unit string
+	if err := db.QueryRow(`SELECT unit FROM products WHERE id = ?`, productID).Scan(&unit); err != nil {
+		return 0, err
+	}
+	if err := checkQuantity(quantity.Unit(unit), ordered); err != nil {
+		return 0, err
+	}
+
+	result, err := db.Exec(
+		`INSERT INTO purchase_order_lines (purchase_order_id, product_id, quantity, cost_cents) VALUES (?, ?, ?, ?)`,
+		orderID,
+		productID,
+		ordered,
+		cost.Amount,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func RemovePurchaseOrderLine(db *sql.DB, orderID, lineID int64) error {
+	status, err := purchaseOrderStatus(db, orderID)
+	if err != nil {
+		return err
+	}
+	if status != PODraft {
+		return fmt.Errorf("%w: only draft orders can be edited", ErrPurchaseOrderState)
+	}
+	_, err = db.Exec(`DELETE FROM purchase_order_lines WHERE id = ? AND purchase_order_id = ?`, lineID, orderID)
+	return err
+}
+
+// SendPurchaseOrder marks a draft as sent to the supplier. Its lines are
+// fixed from then on.
+func SendPurchaseOrder(db *sql.DB, orderID int64) error {
+	var lines int64
+	if err := db.QueryRow(`SELECT COUNT(*) FROM purchase_order_lines WHERE purchase_order_id = ?`, orderID).Scan(&lines); err != nil {
+		return err
+	}
+	if lines == 0 {
+		return errors.New("purchase order has no lines")
+	}
+	return setPurchaseOrderStatus(db, orderID, []PurchaseOrderStatus{PODraft}, POSent, "sent_at")
+}
+
+// ClosePurchaseOrder closes an order that will not be delivered in full.
+func ClosePurchaseOrder(db *sql.DB, orderID int64) error {
+	return setPurchaseOrderStatus(db, orderID, []PurchaseOrderStatus{PODraft, POSent, POPartiallyReceived}, POClosed, "closed_at")
+}
+
+func setPurchaseOrderStatus(q queryer, orderID int64, from []PurchaseOrderStatus, to PurchaseOrderStatus, stampColumn string) error {
+	status, err := purchaseOrderStatus(q, orderID)
+	if err != nil {
+		return err
+	}
+	allowed := false
+	for _, candidate := range from {
+		if status == candidate {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("%w: order %d is %s", ErrPurchaseOrderState, orderID, status)
+	}
+	_, err = q.Exec(
+		`UPDATE purchase_orders SET status = ?, `+stampColumn+` = ? WHERE id = ?`,
+		string(to),
+		time.Now(),
+		orderID,
+	)
+	return err
+}
+
+// ReceivePurchaseOrder books goods delivered against a sent order. Each
+// line may be received in several deliveries; stock goes up through the
+// movement ledger. The order closes itself once every line is complete.
+func ReceivePurchaseOrder(db *sql.DB, orderID int64, receipts []ReceiptLine, user string) error {
+	if len(receipts) == 0 {
+		return errors.New("receipt requires at least one line")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	status, err := purchaseOrderStatus(tx, orderID)
+	if err != nil {
+		return err
+	}
+	if status != POSent && status != POPartiallyReceived {
+		return fmt.Errorf("%w: order %d is %s", ErrPurchaseOrderState, orderID, status)
+	}
+
+	for _, receipt := range receipts {
+		if receipt.Quantity <= 0 {
+			return errors.New("received quantity must be greater than zero")
+		}
+		if err := checkLocation(tx, receipt.LocationID); err != nil {
+			return err
+		}
+		var (
+			productID, costCents int64
+			currency             string
+			ordered, received    quantity.Quantity
+			trackLots            bool
+		)
+		err := tx.QueryRow(
+			`SELECT l.product_id, l.quantity, l.received, p.track_lots, l.cost_cents, po.currency
+			FROM purchase_order_lines l
+			JOIN products p ON p.id = l.product_id
+			JOIN purchase_orders po ON po.id = l.purchase_order_id
+			WHERE l.id = ? AND l.purchase_order_id = ?`,
+			receipt.LineID,
+			orderID,
+		).Scan(&productID, &ordered, &received, &trackLots, &costCents, &currency)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("line %d does not belong to purchase order %d", receipt.LineID, orderID)
+		}
+		if err != nil {
+			return err
+		}
+		units := receipt.Quantity
+		if receipt.PackID != nil {
+			var factor quantity.Quantity
+			err := tx.QueryRow(
+				`SELECT factor FROM product_packs WHERE id = ? AND product_id = ?`,
+				*receipt.PackID,
+				productID,
+			).Scan(&factor)
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("pack %d is not a pack of product %d", *receipt.PackID, productID)
+			}
+			if err != nil {
+				return err
+			}
+			if err := checkQuantity(quantity.Each, receipt.Quantity); err != nil {
+				return err
+			}
+			units = receipt.Quantity.Mul(factor)
+		}
+		if received+units > ordered {
+			return fmt.Errorf("%w: line %d has %s outstanding", ErrOverReceipt, receipt.LineID, ordered-received)
+		}
+		if trackLots && receipt.Lot == "" {
+			return fmt.Errorf("%w: line %d needs a lot number", ErrLotRequired, receipt.LineID)
+		}
+		if !trackLots {
+			receipt.Lot, receipt.Expiry = "", nil
+		}
+		serials, err := requireSerials(tx, productID, units, receipt.Serials)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", receipt.LineID, err)
+		}
+
+		cost := money.New(costCents, currency)
+		if _, err := tx.Exec(
+			`UPDATE purchase_order_lines SET received = received + ? WHERE id = ?`,
+			units,
+			receipt.LineID,
+		); err != nil {
+			return err
+		}
+		err = moveStock(tx, StockMovement{
+			ProductID:  productID,
+			LocationID: receipt.LocationID,
+			Kind:       MovementReceipt,
+			Quantity:   units,
+			Reference:  fmt.Sprintf("PO #%d", orderID),
+			User:       user,
+			Lot:        receipt.Lot,
+			Expiry:     receipt.Expiry,
+			Serials:    serials,
+			UnitCost:   &cost,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var outstanding int64
+	err = tx.QueryRow(
+		`SELECT COUNT(*) FROM purchase_order_lines WHERE purchase_order_id = ? AND received < quantity`,
+		orderID,
+	).Scan(&outstanding)
+	if err != nil {
+		return err
+	}
+	if outstanding == 0 {
+		err = setPurchaseOrderStatus(tx, orderID, []PurchaseOrderStatus{POSent, POPartiallyReceived}, POClosed, "closed_at")
+	} else if status == POSent {
+		_, err = tx.Exec(`UPDATE purchase_orders SET status = ? WHERE id = ?`, string(POPartiallyReceived), orderID)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,246 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"pos-system/internal/money"
+	"pos-system/internal/quantity"
+)
+
+var ErrInvalidReorderLevels = errors.New("invalid reorder levels")
+
+// ReorderLine proposes an order quantity for one product.
+type ReorderLine struct {
+	ProductID    int64
+	Name         string
+	Unit         quantity.Unit
+	Stock        quantity.Quantity
+	InTransit    quantity.Quantity
+	OnOrder      quantity.Quantity
+	MinStock     quantity.Quantity
+	ReorderPoint quantity.Quantity
+	Quantity     quantity.Quantity
+	// Cost is the unit cost last agreed for the product, zero if it was
+	// never ordered.
+	Cost money.Money
+}
+
+// SupplierReorder groups the proposals for one supplier. Products without a
+// preferred supplier have a nil SupplierID.
+type SupplierReorder struct {
+	SupplierID *int64
+	Supplier   string
+	Lines      []ReorderLine
+}
+
+// SetReorderLevels saves a product's thresholds and preferred supplier.
+func SetReorderLevels(db *sql.DB, productID int64, levels ReorderLevels) error {
+	product, err := GetProduct(db, productID)
+	if err != nil {
+		return err
+	}
+	if levels.MinStock < 0 || levels.ReorderPoint < 0 || levels.ReorderQty < 0 {
+		return fmt.Errorf("%w: levels cannot be negative", ErrInvalidReorderLevels)
+	}
+	if levels.ReorderPoint > 0 && levels.MinStock > levels.ReorderPoint {
+		return fmt.Errorf("%w: minimum %s is above the reorder point %s", ErrInvalidReorderLevels, levels.MinStock, levels.ReorderPoint)
+	}
+	for _, level := range []quantity.Quantity{levels.MinStock, levels.ReorderPoint, levels.ReorderQty} {
+		if err := checkQuantity(productUnit(product), level); err != nil {
+			return err
+		}
+	}
+	_, err = db.Exec(
+		`UPDATE products SET min_stock = ?, reorder_point = ?, reorder_qty = ?, supplier_id = ? WHERE id = ?`,
+		levels.MinStock,
+		levels.ReorderPoint,
+		levels.ReorderQty,
+		levels.SupplierID,
+		productID,
+	)
+	return err
+}
+
+// ReorderReport proposes what to order for every product whose stock, with
+// what is in transit between locations and already on order, has fallen to its reorder point or below its
+// minimum. Proposals are whole multiples of the reorder quantity when one is
+// set, and otherwise just enough to get back to the reorder point.
+func ReorderReport(db *sql.DB) ([]SupplierReorder, error) {
+	rows, err := db.Query(
+		`SELECT ` + productColumns + ` FROM products
+		WHERE reorder_point > 0 OR min_stock > 0
+		ORDER BY supplier_id IS NULL, supplier_id, name, id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []Product
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var report []SupplierReorder
+	for _, product := range products {
+		onOrder, err := quantityOnOrder(db, product.ID)
+		if err != nil {
+			return nil, err
+		}
+		proposed := reorderQuantity(product, product.Stock+product.InTransit+onOrder)
+		if proposed <= 0 {
+			continue
+		}
+		cost, err := lastCost(db, product)
+		if err != nil {
+			return nil, err
+		}
+		line := ReorderLine{
+			ProductID:    product.ID,
+			Name:         product.Name,
+			Unit:         productUnit(product),
+			Stock:        product.Stock,
+			InTransit:    product.InTransit,
+			OnOrder:      onOrder,
+			MinStock:     product.MinStock,
+			ReorderPoint: product.ReorderPoint,
+			Quantity:     proposed,
+			Cost:         cost,
+		}
+
+		if n := len(report); n > 0 && sameSupplier(report[n-1].SupplierID, product.SupplierID) {
+			report[n-1].Lines = append(report[n-1].Lines, line)
+			continue
+		}
+		group := SupplierReorder{SupplierID: product.SupplierID}
+		if product.SupplierID != nil {
+			if err := db.QueryRow(`SELECT name FROM suppliers WHERE id = ?`, *product.SupplierID).Scan(&group.Supplier); err != nil {
+				return nil, err
+			}
+		}
+		group.Lines = []ReorderLine{line}
+		report = append(report, group)
+	}
+	return report, nil
+}
+
+// CreateReorderPurchaseOrder drafts a purchase order for a supplier's
+// proposals at their last agreed costs.
+func CreateReorderPurchaseOrder(db *sql.DB, group SupplierReorder) (int64, error) {
+	if group.SupplierID == nil {
+		return 0, errors.New("products have no preferred supplier")
+	}
+	if len(group.Lines) == 0 {
+		return 0, errors.New("nothing to order")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	result, err := tx.Exec(
+		`INSERT INTO purchase_orders (supplier_id, status, reference, created_at) VALUES (?, ?, ?, ?)`,
+		*group.SupplierID,
+		string(PODraft),
+		"Reorder",
+		time.Now(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range group.Lines {
+		if _, err := tx.Exec(
+			`INSERT INTO purchase_order_lines (purchase_order_id, product_id, quantity, cost_cents) VALUES (?, ?, ?, ?)`,
+			id,
+			line.ProductID,
+			line.Quantity,
+			line.Cost.Amount,
+		); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func reorderQuantity(product Product, available quantity.Quantity) quantity.Quantity {
+	target := max(product.ReorderPoint, product.MinStock)
+	if available > product.ReorderPoint && available >= product.MinStock {
+		return 0
+	}
+	short := max(target-available, 0)
+	if product.ReorderQty <= 0 {
+		if productUnit(product).Whole() {
+			short = (short + quantity.Scale - 1) / quantity.Scale * quantity.Scale
+		}
+		return short
+	}
+	packs := max((short+product.ReorderQty-1)/product.ReorderQty, 1)
+	return packs * product.ReorderQty
+}
+
+// quantityOnOrder is what open purchase orders still expect of a product.
+func quantityOnOrder(q queryer, productID int64) (quantity.Quantity, error) {
+	var onOrder quantity.Quantity
+	err := q.QueryRow(
+		`SELECT COALESCE(SUM(l.quantity - l.received), 0)
+		FROM purchase_order_lines l JOIN purchase_orders po ON po.id = l.purchase_order_id
+		WHERE l.product_id = ? AND po.status <> ?`,
+		productID,
+		string(POClosed),
+	).Scan(&onOrder)
+	return onOrder, err
+}
+
+// lastCost is the cost on the product's most recent purchase order line,
+// preferring orders from its own supplier.
+func lastCost(q queryer, product Product) (money.Money, error) {
+	var (
+		cost     int64
+		currency string
+	)
+	err := q.QueryRow(
+		`SELECT l.cost_cents, po.currency
+		FROM purchase_order_lines l JOIN purchase_orders po ON po.id = l.purchase_order_id
+		WHERE l.product_id = ?
+		ORDER BY po.supplier_id IS NOT ?, l.id DESC
+		LIMIT 1`,
+		product.ID,
+		product.SupplierID,
+	).Scan(&cost, &currency)
+	if err == sql.ErrNoRows {
+		return money.Zero(productCurrency(product)), nil
+	}
+	if err != nil {
+		return money.Money{}, err
+	}
+	return money.New(cost, currency), nil
+}
+
+func sameSupplier(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,494 @@
+package store
+
+import (
+	"database/sql"
+	"slices"
+	"sort"
+	"time"
+
+	"pos-system/internal/money"
+	"pos-system/internal/quantity"
+)
+
+// TaxReport totals sale tax lines per class and rate for sales created in
+// [from, to). Voided sales are left out, as in every report below.
+func TaxReport(db *sql.DB, from, to time.Time) ([]TaxLine, error) {
+	rows, err := db.Query(
+		`SELECT st.tax_class_id, st.rate_bp, SUM(st.net_cents), SUM(st.tax_cents), s.currency
+		FROM sale_taxes st JOIN sales s ON s.id = st.sale_id
+		WHERE s.created_at >= ? AND s.created_at < ? AND s.status <> ?
+		GROUP BY st.tax_class_id, st.rate_bp, s.currency
+		ORDER BY st.rate_bp DESC, st.tax_class_id`,
+		from,
+		to,
+		string(SaleVoided),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTaxLines(rows)
+}
+
+type SalesSummary struct {
+	Sales    int64
+	Subtotal money.Money
+	Discount money.Money
+	Tax      money.Money
+	Total    money.Money
+	Refunds  money.Money
+	// Voided sales are not part of the totals above.
+	Voided      int64
+	VoidedTotal money.Money
+}
+
+func SalesReport(db *sql.DB, from, to time.Time) (SalesSummary, error) {
+	var (
+		summary                          SalesSummary
+		subtotal, discount, taxes, total int64
+	)
+	err := db.QueryRow(
+		`SELECT COUNT(*), COALESCE(SUM(subtotal_cents), 0), COALESCE(SUM(discount_cents), 0),
+			COALESCE(SUM(tax_cents), 0), COALESCE(SUM(total_cents), 0)
+		FROM sales WHERE created_at >= ? AND created_at < ? AND status = ?`,
+		from,
+		to,
+		string(SaleCompleted),
+	).Scan(&summary.Sales, &subtotal, &discount, &taxes, &total)
+	if err != nil {
+		return SalesSummary{}, err
+	}
+
+	var voided int64
+	err = db.QueryRow(
+		`SELECT COUNT(*), COALESCE(SUM(total_cents), 0)
+		FROM sales WHERE created_at >= ? AND created_at < ? AND status = ?`,
+		from,
+		to,
+		string(SaleVoided),
+	).Scan(&summary.Voided, &voided)
+	if err != nil {
+		return SalesSummary{}, err
+	}
+	summary.VoidedTotal = money.New(voided, money.DefaultCurrency)
+
+	var refunds int64
+	err = db.QueryRow(
+		`SELECT COALESCE(SUM(refund_cents), 0) FROM returns WHERE created_at >= ? AND created_at < ?`,
+		from,
+		to,
+	).Scan(&refunds)
+	if err != nil {
+		return SalesSummary{}, err
+	}
+	summary.Refunds = money.New(refunds, money.DefaultCurrency)
+	summary.Subtotal = money.New(subtotal, money.DefaultCurrency)
+	summary.Discount = money.New(discount, money.DefaultCurrency)
+	summary.Tax = money.New(taxes, money.DefaultCurrency)
+	summary.Total = money.New(total, money.DefaultCurrency)
+	return summary, nil
+}
+
+type PromotionTotal struct {
+	PromotionID int64
+	Name        string
+	Lines       int64
+	Amount      money.Money
+}
+
+func PromotionReport(db *sql.DB, from, to time.Time) ([]PromotionTotal, error) {
+	rows, err := db.Query(
+		`SELECT d.promotion_id, d.name, COUNT(*), SUM(d.amount_cents), si.currency
+		FROM sale_item_discounts d
+		JOIN sale_items si ON si.id = d.sale_item_id
+		JOIN sales s ON s.id = si.sale_id
+		WHERE s.created_at >= ? AND s.created_at < ? AND s.status <> ?
+		GROUP BY d.promotion_id, si.currency
+		ORDER BY SUM(d.amount_cents) DESC`,
+		from,
+		to,
+		string(SaleVoided),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []PromotionTotal
+	for rows.Next() {
+		var (
+			total    PromotionTotal
+			amount   int64
+			currency string
+		)
+		if err := rows.Scan(&total.PromotionID, &total.Name, &total.Lines, &amount, &currency); err != nil {
+			return nil, err
+		}
+		total.Amount = money.New(amount, currency)
+		totals = append(totals, total)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return totals, nil
+}
+
+type TenderTotal struct {
+	Type     TenderType
+	Payments int64
+	Amount   money.Money
+}
+
+// PaymentReport totals what was taken per tender type, net of change given.
+func PaymentReport(db *sql.DB, from, to time.Time) ([]TenderTotal, error) {
+	rows, err := db.Query(
+		`SELECT p.tender, COUNT(*), SUM(p.amount_cents - p.change_cents), p.currency
+		FROM payments p JOIN sales s ON s.id = p.sale_id
+		WHERE s.created_at >= ? AND s.created_at < ? AND s.status <> ?
+		GROUP BY p.tender, p.currency
+		ORDER BY p.tender`,
+		from,
+		to,
+		string(SaleVoided),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []TenderTotal
+	for rows.Next() {
+		var (
+			total    TenderTotal
+			tender   string
+			amount   int64
+			currency string
+		)
+		if err := rows.Scan(&tender, &total.Payments, &amount, &currency); err != nil {
+			return nil, err
+		}
+		total.Type = TenderType(tender)
+		total.Amount = money.New(amount, currency)
+		totals = append(totals, total)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return totals, nil
+}
+
+// Uncategorised is what reports call products without a category.
+const Uncategorised = "Uncategorised"
+
+// categoryNode is a category in tree order together with the IDs of it and
+// every category beneath it, for reports that roll subcategories up into
+// their parents.
+type categoryNode struct {
+	Category
+	Depth   int
+	Subtree []int64
+}
+
+func categoryTree(categories []Category) []categoryNode {
+	children := make(map[int64][]Category)
+	for _, category := range categories {
+		parent := int64(0)
+		if category.ParentID != nil {
+			parent = *category.ParentID
+		}
+		children[parent] = append(children[parent], category)
+	}
+
+	var nodes []categoryNode
+	var walk func(category Category, depth int) []int64
+	walk = func(category Category, depth int) []int64 {
+		index := len(nodes)
+		nodes = append(nodes, categoryNode{Category: category, Depth: depth})
+		subtree := []int64{category.ID}
+		for _, child := range children[category.ID] {
+			subtree = append(subtree, walk(child, depth+1)...)
+		}
+		nodes[index].Subtree = subtree
+		return subtree
+	}
+	for _, root := range children[0] {
+		walk(root, 0)
+	}
+	return nodes
+}
+
+type CategoryTotal struct {
+	CategoryID int64
+	Path       string
+	Depth      int
+	Quantity   quantity.Quantity
+	Net        money.Money
+	Tax        money.Money
+	Total      money.Money
+}
+
+// CategoryReport totals sale lines per category. Each category includes
+// its subcategories, so the top-level rows add up to all categorised
+// sales; uncategorised lines are reported under CategoryID 0. Rows come in
+// tree order.
+func CategoryReport(db *sql.DB, from, to time.Time) ([]CategoryTotal, error) {
+	categories, err := ListCategories(db)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(
+		`SELECT COALESCE(p.category_id, 0), SUM(si.quantity), SUM(si.net_cents), SUM(si.tax_cents), SUM(si.total_cents)
+		FROM sale_items si
+		JOIN sales s ON s.id = si.sale_id
+		JOIN products p ON p.id = si.product_id
+		WHERE s.created_at >= ? AND s.created_at < ? AND s.status <> ?
+		GROUP BY COALESCE(p.category_id, 0)`,
+		from,
+		to,
+		string(SaleVoided),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type sums struct {
+		quantity        quantity.Quantity
+		net, tax, total int64
+	}
+	direct := make(map[int64]sums)
+	for rows.Next() {
+		var (
+			categoryID int64
+			s          sums
+		)
+		if err := rows.Scan(&categoryID, &s.quantity, &s.net, &s.tax, &s.total); err != nil {
+			return nil, err
+		}
+		direct[categoryID] = s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var totals []CategoryTotal
+	for _, node := range categoryTree(categories) {
+		var s sums
+		for _, id := range node.Subtree {
+			s.quantity += direct[id].quantity
+			s.net += direct[id].net
+			s.tax += direct[id].tax
+			s.total += direct[id].total
+		}
+		totals = append(totals, CategoryTotal{
+			CategoryID: node.ID,
+			Path:       CategoryPath(categories, node.ID),
+			Depth:      node.Depth,
+			Quantity:   s.quantity,
+			Net:        money.New(s.net, money.DefaultCurrency),
+			Tax:        money.New(s.tax, money.DefaultCurrency),
+			Total:      money.New(s.total, money.DefaultCurrency),
+		})
+	}
+
+	if s, ok := direct[0]; ok {
+		totals = append(totals, CategoryTotal{
+			Path:     Uncategorised,
+			Quantity: s.quantity,
+			Net:      money.New(s.net, money.DefaultCurrency),
+			Tax:      money.New(s.tax, money.DefaultCurrency),
+			Total:    money.New(s.total, money.DefaultCurrency),
+		})
+	}
+	return totals, nil
+}
+
+// MarginLine is the revenue, net of tax and discounts, and the cost of
+// goods sold for one product, category or day.
+type MarginLine struct {
+	// ID is the product or category; days have none.
+	ID       int64
+	Name     string
+	Quantity quantity.Quantity
+	Revenue  money.Money
+	Cost     money.Money
+}
+
+func (l MarginLine) Margin() money.Money {
+	return l.Revenue.Sub(l.Cost)
+}
+
+// Percent is the margin as a percentage of revenue.
+func (l MarginLine) Percent() float64 {
+	if l.Revenue.Amount == 0 {
+		return 0
+	}
+	return float64(l.Margin().Amount) * 100 / float64(l.Revenue.Amount)
+}
+
+// MarginByProduct reports margin per product for sales created in
+// [from, to), highest margin first. Returns are not netted off.
+func MarginByProduct(db *sql.DB, from, to time.Time) ([]MarginLine, error) {
+	return marginLines(db,
+		`SELECT si.product_id, p.name, SUM(si.quantity), SUM(si.net_cents), SUM(si.cost_cents), si.currency
+		FROM sale_items si
+		JOIN sales s ON s.id = si.sale_id
+		JOIN products p ON p.id = si.product_id
+		WHERE s.created_at >= ? AND s.created_at < ? AND s.status <> ?
+		GROUP BY si.product_id, si.currency
+		ORDER BY SUM(si.net_cents) - SUM(si.cost_cents) DESC, p.name`,
+		from,
+		to,
+		string(SaleVoided),
+	)
+}
+
+// MarginByCategory reports margin per product category for sales created
+// in [from, to). Like CategoryReport, each category includes its
+// subcategories and rows come in tree order, named by category path;
+// products without a category are reported last as Uncategorised with ID
+// zero. Each currency sold in gets its own row.
+func MarginByCategory(db *sql.DB, from, to time.Time) ([]MarginLine, error) {
+	categories, err := ListCategories(db)
+	if err != nil {
+		return nil, err
+	}
+	direct, err := marginLines(db,
+		`SELECT COALESCE(p.category_id, 0), '', SUM(si.quantity), SUM(si.net_cents), SUM(si.cost_cents), si.currency
+		FROM sale_items si
+		JOIN sales s ON s.id = si.sale_id
+		JOIN products p ON p.id = si.product_id
+		WHERE s.created_at >= ? AND s.created_at < ? AND s.status <> ?
+		GROUP BY COALESCE(p.category_id, 0), si.currency
+		ORDER BY si.currency`,
+		from,
+		to,
+		string(SaleVoided),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var currencies []string
+	for _, line := range direct {
+		if !slices.Contains(currencies, line.Revenue.Currency) {
+			currencies = append(currencies, line.Revenue.Currency)
+		}
+	}
+	total := func(id int64, name string, subtree []int64, currency string) (MarginLine, bool) {
+		line := MarginLine{ID: id, Name: name, Revenue: money.Zero(currency), Cost: money.Zero(currency)}
+		found := false
+		for _, sold := range direct {
+			if sold.Revenue.Currency == currency && slices.Contains(subtree, sold.ID) {
+				line.Quantity += sold.Quantity
+				line.Revenue = line.Revenue.Add(sold.Revenue)
+				line.Cost = line.Cost.Add(sold.Cost)
+				found = true
+			}
+		}
+		return line, found
+	}
+
+	var lines []MarginLine
+	for _, node := range categoryTree(categories) {
+		for _, currency := range currencies {
+			if line, ok := total(node.ID, CategoryPath(categories, node.ID), node.Subtree, currency); ok {
+				lines = append(lines, line)
+			}
+		}
+	}
+	for _, currency := range currencies {
+		if line, ok := total(0, Uncategorised, []int64{0}, currency); ok {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+func marginLines(db *sql.DB, query string, args ...any) ([]MarginLine, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []MarginLine
+	for rows.Next() {
+		var (
+			line          MarginLine
+			revenue, cost int64
+			currency      string
+		)
+		if err := rows.Scan(&line.ID, &line.Name, &line.Quantity, &revenue, &cost, &currency); err != nil {
+			return nil, err
+		}
+		line.Revenue = money.New(revenue, currency)
+		line.Cost = money.New(cost, currency)
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// MarginByDay reports margin per local calendar day for sales created in
+// [from, to), oldest day first. Name holds the day as YYYY-MM-DD. Each
+// currency sold in on a day gets its own row.
+func MarginByDay(db *sql.DB, from, to time.Time) ([]MarginLine, error) {
+	rows, err := db.Query(
+		`SELECT s.created_at, si.quantity, si.net_cents, si.cost_cents, si.currency
+		FROM sale_items si JOIN sales s ON s.id = si.sale_id
+		WHERE s.created_at >= ? AND s.created_at < ? AND s.status <> ?`,
+		from,
+		to,
+		string(SaleVoided),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type dayCurrency struct{ day, currency string }
+	days := make(map[dayCurrency]*MarginLine)
+	for rows.Next() {
+		var (
+			createdAt     time.Time
+			qty           quantity.Quantity
+			revenue, cost int64
+			currency      string
+		)
+		if err := rows.Scan(&createdAt, &qty, &revenue, &cost, &currency); err != nil {
+			return nil, err
+		}
+		key := dayCurrency{createdAt.Local().Format(time.DateOnly), currency}
+		line, ok := days[key]
+		if !ok {
+			line = &MarginLine{Name: key.day, Revenue: money.Zero(currency), Cost: money.Zero(currency)}
+			days[key] = line
+		}
+		line.Quantity += qty
+		line.Revenue = line.Revenue.Add(money.New(revenue, currency))
+		line.Cost = line.Cost.Add(money.New(cost, currency))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	lines := make([]MarginLine, 0, len(days))
+	for _, line := range days {
+		lines = append(lines, *line)
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].Name != lines[j].Name {
+			return lines[i].Name < lines[j].Name
+		}
+		return lines[i].Revenue.Currency < lines[j].Revenue.Currency
+	})
+	return lines, nil
+}
@@ -0,0 +1,121 @@
+package store
+
+import (
+	"database/sql"
+	"slices"
+	"testing"
+	"time"
+
+	"pos-system/internal/money"
+	"pos-system/internal/quantity"
+)
+
+// marginFixture sells, today, 5 apples (Food > Fruit) and 2 loaves of
+// bread (Food) in dollars, and a bottle of uncategorised wine in euros.
+type marginFixture struct {
+	db                 *sql.DB
+	food, fruit        int64
+	apple, bread, wine int64
+	from, to           time.Time
+}
+
+func newMarginFixture(t *testing.T) marginFixture {
+	t.Helper()
+	db := openTestDB(t)
+	f := marginFixture{db: db}
+	var err error
+	f.food, err = CreateCategory(db, Category{Name: "Food"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.fruit, err = CreateCategory(db, Category{Name: "Fruit", ParentID: &f.food})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.apple = createTestProduct(t, db, "Apple", money.New(100, "USD"), &f.fruit)
+	f.bread = createTestProduct(t, db, "Bread", money.New(300, "USD"), &f.food)
+	f.wine = createTestProduct(t, db, "Wine", money.New(1200, "EUR"), nil)
+	receiveTestStock(t, db, f.apple, 10, money.New(40, "USD"))
+	receiveTestStock(t, db, f.bread, 10, money.New(100, "USD"))
+	receiveTestStock(t, db, f.wine, 10, money.New(600, "EUR"))
+
+	f.from = time.Now().Add(-time.Hour)
+	sellTestStock(t, db, f.apple, 5)
+	sellTestStock(t, db, f.bread, 2)
+	sellTestStock(t, db, f.wine, 1)
+	f.to = time.Now().Add(time.Hour)
+	return f
+}
+
+func margin(id int64, name string, units int64, revenue, cost int64, currency string) MarginLine {
+	return MarginLine{
+		ID:       id,
+		Name:     name,
+		Quantity: quantity.Of(units),
+		Revenue:  money.New(revenue, currency),
+		Cost:     money.New(cost, currency),
+	}
+}
+
+func TestMarginReports(t *testing.T) {
+	f := newMarginFixture(t)
+	today := time.Now().Local().Format(time.DateOnly)
+	tests := []struct {
+		name   string
+		report func(*sql.DB, time.Time, time.Time) ([]MarginLine, error)
+		want   []MarginLine
+	}{
+		{"by product", MarginByProduct, []MarginLine{
+			margin(f.wine, "Wine", 1, 1200, 600, "EUR"),
+			margin(f.bread, "Bread", 2, 600, 200, "USD"),
+			margin(f.apple, "Apple", 5, 500, 200, "USD"),
+		}},
+		{"by category", MarginByCategory, []MarginLine{
+			margin(f.food, "Food", 7, 1100, 400, "USD"),
+			margin(f.fruit, "Food > Fruit", 5, 500, 200, "USD"),
+			margin(0, Uncategorised, 1, 1200, 600, "EUR"),
+		}},
+		{"by day", MarginByDay, []MarginLine{
+			margin(0, today, 1, 1200, 600, "EUR"),
+			margin(0, today, 7, 1100, 400, "USD"),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.report(f.db, f.from, f.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarginReportsSkipVoidedSales(t *testing.T) {
+	db := openTestDB(t)
+	productID := createTestProduct(t, db, "Widget", money.New(500, "USD"), nil)
+	receiveTestStock(t, db, productID, 10, money.New(200, "USD"))
+	from := time.Now().Add(-time.Hour)
+	sale := sellTestStock(t, db, productID, 3)
+	if err := VoidSale(db, sale.ID, "manager", "test"); err != nil {
+		t.Fatal(err)
+	}
+	to := time.Now().Add(time.Hour)
+
+	for name, report := range map[string]func(*sql.DB, time.Time, time.Time) ([]MarginLine, error){
+		"by product":  MarginByProduct,
+		"by category": MarginByCategory,
+		"by day":      MarginByDay,
+	} {
+		lines, err := report(db, from, to)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(lines) != 0 {
+			t.Errorf("%s reports voided sale: %+v", name, lines)
+		}
+	}
+}
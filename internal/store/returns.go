@@ -0,0 +1,352 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"pos-system/internal/money"
+	"pos-system/internal/quantity"
+)
+
+var ErrReturnExceedsSold = errors.New("return quantity exceeds quantity sold")
+
+// ReturnableLines lists a sale's lines with how much of each was already returned.
+func ReturnableLines(db *sql.DB, saleID int64) ([]ReturnableLine, error) {
+	lines, err := saleLines(db, saleID)
+	if err != nil {
+		return nil, err
+	}
+
+	returnable := make([]ReturnableLine, 0, len(lines))
+	for _, line := range lines {
+		returned, _, _, err := returnedSoFar(db, line.ID)
+		if err != nil {
+			return nil, err
+		}
+		returnable = append(returnable, ReturnableLine{SaleLine: line, Returned: returned})
+	}
+	return returnable, nil
+}
+
+func returnedSoFar(q queryer, saleItemID int64) (returned quantity.Quantity, refund, taxCents int64, err error) {
+	err = q.QueryRow(
+		`SELECT COALESCE(SUM(quantity), 0), COALESCE(SUM(refund_cents), 0), COALESCE(SUM(tax_cents), 0)
+		FROM return_items WHERE sale_item_id = ?`,
+		saleItemID,
+	).Scan(&returned, &refund, &taxCents)
+	return returned, refund, taxCents, err
+}
+
+// CreateReturn takes goods back against the original sale lines. Each line
+// is refunded pro rata to what the customer actually paid for it, so
+// discounts and tax are returned in proportion. Restocked goods go back to
+// the location and lots they were sold from. Units of products tracked by serial number
+// must be named, and must have been sold on the line they come back against.
+func CreateReturn(db *sql.DB, saleID int64, lines []ReturnLine, restock bool, refund Tender, reason string) (int64, error) {
+	if len(lines) == 0 {
+		return 0, errors.New("return requires at least one item")
+	}
+	if !validTenderType(refund.Type) {
+		return 0, fmt.Errorf("%w: unknown type %q", ErrInvalidTender, refund.Type)
+	}
+	lines = mergeReturnLines(lines)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var (
+		customerID int64
+		locationID int64
+		currency   string
+		status     string
+	)
+	err = tx.QueryRow(
+		`SELECT customer_id, location_id, currency, status FROM sales WHERE id = ?`,
+		saleID,
+	).Scan(&customerID, &locationID, &currency, &status)
+	if err != nil {
+		return 0, err
+	}
+	if SaleStatus(status) == SaleVoided {
+		return 0, fmt.Errorf("%w: sale %d", ErrSaleVoided, saleID)
+	}
+
+	type returnItem struct {
+		saleItemID int64
+		productID  int64
+		quantity   quantity.Quantity
+		refund     int64
+		tax        int64
+		serials    []SoldSerial
+		unitCost   money.Money
+	}
+	items := make([]returnItem, 0, len(lines))
+	totalRefund := money.Zero(currency)
+	totalTax := money.Zero(currency)
+	for _, line := range lines {
+		if line.Quantity <= 0 {
+			return 0, errors.New("quantity must be greater than zero")
+		}
+
+		var (
+			productID, total, taxCents, unitCost int64
+			sold                                 quantity.Quantity
+		)
+		err := tx.QueryRow(
+			`SELECT product_id, quantity, total_cents, tax_cents, unit_cost_cents FROM sale_items WHERE id = ? AND sale_id = ?`,
+			line.SaleItemID,
+			saleID,
+		).Scan(&productID, &sold, &total, &taxCents, &unitCost)
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("sale item %d does not belong to sale %d", line.SaleItemID, saleID)
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		returned, refunded, taxRefunded, err := returnedSoFar(tx, line.SaleItemID)
+		if err != nil {
+			return 0, err
+		}
+		remaining := sold - returned
+		if line.Quantity > remaining {
+			return 0, fmt.Errorf("%w: sale item %d has %s left to return", ErrReturnExceedsSold, line.SaleItemID, remaining)
+		}
+
+		item := returnItem{
+			saleItemID: line.SaleItemID,
+			productID:  productID,
+			quantity:   line.Quantity,
+			unitCost:   money.New(unitCost, currency),
+		}
+		item.serials, err = returningSerials(tx, productID, line)
+		if err != nil {
+			return 0, err
+		}
+		if line.Quantity == remaining {
+			item.refund = total - refunded
+			item.tax = taxCents - taxRefunded
+		} else {
+			item.refund = money.New(total, currency).MulDiv(int64(line.Quantity), int64(sold)).Amount
+			item.tax = money.New(taxCents, currency).MulDiv(int64(line.Quantity), int64(sold)).Amount
+		}
+		items = append(items, item)
+		totalRefund = totalRefund.Add(money.New(item.refund, currency))
+		totalTax = totalTax.Add(money.New(item.tax, currency))
+	}
+
+	result, err := tx.Exec(
+		`INSERT INTO returns (sale_id, created_at, reason, restock, tender, refund_cents, tax_cents, currency, reference)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		saleID,
+		time.Now(),
+		reason,
+		restock,
+		string(refund.Type),
+		totalRefund.Amount,
+		totalTax.Amount,
+		currency,
+		refund.Reference,
+	)
+	if err != nil {
+		return 0, err
+	}
+	returnID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, item := range items {
+		if _, err := tx.Exec(
+			`INSERT INTO return_items (return_id, sale_item_id, quantity, refund_cents, tax_cents) VALUES (?, ?, ?, ?, ?)`,
+			returnID,
+			item.saleItemID,
+			item.quantity,
+			item.refund,
+			item.tax,
+		); err != nil {
+			return 0, err
+		}
+		if err := returnSerials(tx, item.productID, locationID, item.saleItemID, returnID, item.serials, restock); err != nil {
+			return 0, err
+		}
+		if !restock {
+			continue
+		}
+		// Restocked goods come back at what they cost when sold, into the
+		// lots they were sold from. Earlier returns against the line are
+		// taken to have used up its lots in the order they were sold.
+		allocations, err := saleItemLots(tx, item.saleItemID)
+		if err != nil {
+			return 0, err
+		}
+		var returned quantity.Quantity
+		err = tx.QueryRow(
+			`SELECT COALESCE(SUM(quantity), 0) FROM return_items WHERE sale_item_id = ? AND return_id <> ?`,
+			item.saleItemID,
+			returnID,
+		).Scan(&returned)
+		if err != nil {
+			return 0, err
+		}
+		rest := item.quantity
+		for _, allocation := range allocations {
+			left := allocation.Quantity - min(allocation.Quantity, returned)
+			returned -= allocation.Quantity - left
+			take := min(left, rest)
+			err := moveStock(tx, StockMovement{
+				ProductID:  item.productID,
+				LocationID: locationID,
+				Kind:       MovementReturn,
+				Quantity:   take,
+				Reason:     reason,
+				Reference:  fmt.Sprintf("return #%d", returnID),
+				Lot:        allocation.Lot,
+				Expiry:     allocation.Expiry,
+				UnitCost:   &item.unitCost,
+			})
+			if err != nil {
+				return 0, err
+			}
+			rest -= take
+		}
+		err = moveStock(tx, StockMovement{
+			ProductID:  item.productID,
+			LocationID: locationID,
+			Kind:       MovementReturn,
+			Quantity:   rest,
+			Reason:     reason,
+			Reference:  fmt.Sprintf("return #%d", returnID),
+			UnitCost:   &item.unitCost,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if refund.Type == TenderStoreCredit {
+		if err := adjustStoreCredit(tx, customerID, totalRefund); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return returnID, nil
+}
+
+func mergeReturnLines(lines []ReturnLine) []ReturnLine {
+	merged := make([]ReturnLine, 0, len(lines))
+	index := make(map[int64]int, len(lines))
+	for _, line := range lines {
+		if i, ok := index[line.SaleItemID]; ok {
+			merged[i].Quantity += line.Quantity
+			merged[i].Serials = append(merged[i].Serials, line.Serials...)
+			continue
+		}
+		index[line.SaleItemID] = len(merged)
+		merged = append(merged, line)
+	}
+	return merged
+}
+
+func ListReturns(db *sql.DB, saleID int64) ([]Return, error) {
+	rows, err := db.Query(
+		`SELECT id, sale_id, created_at, reason, restock, tender, refund_cents, tax_cents, currency, reference
+		FROM returns WHERE sale_id = ? ORDER BY id`,
+		saleID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var returns []Return
+	for rows.Next() {
+		var (
+			ret              Return
+			tender, currency string
+			refund, taxCents int64
+		)
+		if err := rows.Scan(
+			&ret.ID, &ret.SaleID, &ret.CreatedAt, &ret.Reason, &ret.Restock,
+			&tender, &refund, &taxCents, &currency, &ret.Refund.Reference,
+		); err != nil {
+			return nil, err
+		}
+		ret.Refund.Type = TenderType(tender)
+		ret.Refund.Amount = money.New(refund, currency)
+		ret.Tax = money.New(taxCents, currency)
+		returns = append(returns, ret)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return returns, nil
+}
+
+// returningSerials checks the serial numbers on a return line against the
+// units its sale line sold and has not taken back yet.
+func returningSerials(q queryer, productID int64, line ReturnLine) ([]SoldSerial, error) {
+	serials, err := requireSerials(q, productID, line.Quantity, line.Serials)
+	if err != nil {
+		return nil, err
+	}
+	sold, err := saleItemSerials(q, line.SaleItemID)
+	if err != nil {
+		return nil, err
+	}
+	returning := make([]SoldSerial, 0, len(serials))
+	taken := make(map[int64]bool, len(serials))
+	for _, serial := range serials {
+		found := false
+		for _, unit := range sold {
+			if unit.Serial == serial && unit.ReturnID == nil && !taken[unit.SerialID] {
+				returning = append(returning, unit)
+				taken[unit.SerialID] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: %s on sale item %d", ErrSerialNotSold, serial, line.SaleItemID)
+		}
+	}
+	return returning, nil
+}
+
+// returnSerials records which return brought units back. Restocked units
+// go back into stock at the sale's location; the others are kept apart as
+// returned.
+func returnSerials(q queryer, productID, locationID, saleItemID, returnID int64, serials []SoldSerial, restock bool) error {
+	names := make([]string, 0, len(serials))
+	for _, serial := range serials {
+		if _, err := q.Exec(
+			`UPDATE sale_item_serials SET return_id = ? WHERE sale_item_id = ? AND serial_id = ?`,
+			returnID,
+			saleItemID,
+			serial.SerialID,
+		); err != nil {
+			return err
+		}
+		if !restock {
+			if _, err := q.Exec(`UPDATE serials SET status = ? WHERE id = ?`, string(SerialReturned), serial.SerialID); err != nil {
+				return err
+			}
+		}
+		names = append(names, serial.Serial)
+	}
+	if restock {
+		return receiveSerials(q, productID, locationID, names)
+	}
+	return nil
+}
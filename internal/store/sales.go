@@ -5,18 +5,28 @@ import (
 	"errors"
 	"fmt"
 	"time"
+
+	"pos-system/internal/money"
+	"pos-system/internal/quantity"
+	"pos-system/internal/tax"
 )
 
-var ErrInsufficientStock = errors.New("insufficient stock")
+var (
+	ErrInsufficientStock = errors.New("insufficient stock")
+	ErrSaleVoided        = errors.New("sale is voided")
+	ErrSaleHasReturns    = errors.New("sale has returns")
+)
 
-func CreateSale(db *sql.DB, customerID *int64, items []SaleItem) (int64, error) {
+// CreateSale records a sale and takes its goods out of stock at locationID,
+// the selling location of the terminal ringing it up. Scheduled price
+// changes that have fallen due take effect first.
+func CreateSale(db *sql.DB, locationID int64, customerID *int64, items []SaleItem, tenders []Tender) (int64, error) {
 	if customerID == nil {
 		return 0, errors.New("customer id is required")
 	}
 	if len(items) == 0 {
 		return 0, errors.New("sale requires at least one item")
 	}
-
 	tx, err := db.Begin()
 	if err != nil {
 		return 0, err
@@ -25,66 +35,178 @@ func CreateSale(db *sql.DB, customerID *int64, items []SaleItem) (int64, error)
 		_ = tx.Rollback()
 	}()
 
-	itemPrices := make(map[int64]float64, len(items))
-	for _, item := range items {
-		if item.Quantity <= 0 {
-			return 0, errors.New("quantity must be greater than zero")
-		}
+	if err := checkLocation(tx, locationID); err != nil {
+		return 0, err
+	}
+	if locationID == 0 {
+		locationID = DefaultLocationID
+	}
+	if _, err := applyDuePriceChanges(tx, time.Now()); err != nil {
+		return 0, err
+	}
+	cart, err := priceCart(tx, customerID, items)
+	if err != nil {
+		return 0, err
+	}
+	change, err := SettleTenders(cart.Total, tenders)
+	if err != nil {
+		return 0, err
+	}
 
-		var price float64
-		var stock int64
-		err := tx.QueryRow(
-			`SELECT price, stock FROM products WHERE id = ?`,
-			item.ProductID,
-		).Scan(&price, &stock)
+	result, err := tx.Exec(
+		`INSERT INTO sales (customer_id, location_id, created_at, status, tax_mode, currency, subtotal_cents, discount_cents, tax_cents, total_cents)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		*customerID,
+		locationID,
+		time.Now(),
+		string(SaleCompleted),
+		string(cart.Mode),
+		cart.Total.Currency,
+		cart.Subtotal.Amount,
+		cart.Discount.Amount,
+		cart.Tax.Amount,
+		cart.Total.Amount,
+	)
+	if err != nil {
+		return 0, err
+	}
+	saleID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	// Priced lines hold base units, so packs deduct everything they contain.
+	// Lines of products tracked by lot come out of the lots allocated to
+	// them, and any rest out of stock held outside lots. Units of products
+	// tracked by serial number must each be in stock at the location.
+	// Each line's cost is taken before its goods go out, so FIFO costs it
+	// from the layers it uses up.
+	lineLots := make([][]LotAllocation, len(cart.Lines))
+	lineSerials := make([][]int64, len(cart.Lines))
+	lineCosts := make([]money.Money, len(cart.Lines))
+	for i, line := range cart.Lines {
+		lineCosts[i], err = issueCost(tx, line.ProductID, line.Quantity)
+		if err != nil {
+			return 0, err
+		}
+		serials, err := requireSerials(tx, line.ProductID, line.Quantity, items[i].Serials)
+		if err != nil {
+			return 0, err
+		}
+		lineSerials[i], err = releaseSerials(tx, line.ProductID, locationID, serials, SerialSold)
+		if err != nil {
+			return 0, err
+		}
+		allocations, err := allocateLots(tx, line.ProductID, locationID, line.Quantity, items[i].Lot)
 		if err != nil {
 			return 0, err
 		}
-		if stock < item.Quantity {
-			return 0, fmt.Errorf("%w for product %d", ErrInsufficientStock, item.ProductID)
+		lineLots[i] = allocations
+		rest := line.Quantity
+		for _, allocation := range allocations {
+			err := moveStock(tx, StockMovement{
+				ProductID:  line.ProductID,
+				LocationID: locationID,
+				Kind:       MovementSale,
+				Quantity:   -allocation.Quantity,
+				Reference:  fmt.Sprintf("sale #%d", saleID),
+				Lot:        allocation.Lot,
+			})
+			if err != nil {
+				return 0, err
+			}
+			rest -= allocation.Quantity
 		}
+		err = moveStock(tx, StockMovement{
+			ProductID:  line.ProductID,
+			LocationID: locationID,
+			Kind:       MovementSale,
+			Quantity:   -rest,
+			Reference:  fmt.Sprintf("sale #%d", saleID),
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
 
+	for i, line := range cart.Lines {
 		result, err := tx.Exec(
-			`UPDATE products SET stock = stock - ? WHERE id = ? AND stock >= ?`,
-			item.Quantity,
-			item.ProductID,
-			item.Quantity,
+			`INSERT INTO sale_items (
+				sale_id, product_id, quantity, price_cents, currency, discount_cents,
+				tax_class_id, tax_rate_bp, net_cents, tax_cents, total_cents,
+				pack_id, packs, pack_price_cents, price_list, unit_cost_cents, cost_cents
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			saleID,
+			line.ProductID,
+			line.Quantity,
+			line.UnitPrice.Amount,
+			line.UnitPrice.Currency,
+			line.Discount.Amount,
+			line.TaxClassID,
+			int64(line.TaxRate),
+			line.Net.Amount,
+			line.Tax.Amount,
+			line.Total.Amount,
+			line.PackID,
+			line.Packs,
+			line.PackPrice.Amount,
+			line.PriceList,
+			lineCosts[i].MulDiv(int64(quantity.Of(1)), int64(line.Quantity)).Amount,
+			lineCosts[i].Amount,
 		)
 		if err != nil {
 			return 0, err
 		}
-		updated, err := result.RowsAffected()
+		saleItemID, err := result.LastInsertId()
 		if err != nil {
 			return 0, err
 		}
-		if updated == 0 {
-			return 0, fmt.Errorf("%w for product %d", ErrInsufficientStock, item.ProductID)
+
+		for _, discount := range line.Discounts {
+			_, err := tx.Exec(
+				`INSERT INTO sale_item_discounts (sale_item_id, promotion_id, name, amount_cents) VALUES (?, ?, ?, ?)`,
+				saleItemID,
+				discount.PromotionID,
+				discount.Name,
+				discount.Amount.Amount,
+			)
+			if err != nil {
+				return 0, err
+			}
 		}
 
-		itemPrices[item.ProductID] = price
-	}
+		for _, allocation := range lineLots[i] {
+			_, err := tx.Exec(
+				`INSERT INTO sale_item_lots (sale_item_id, lot_id, quantity) VALUES (?, ?, ?)`,
+				saleItemID,
+				allocation.LotID,
+				allocation.Quantity,
+			)
+			if err != nil {
+				return 0, err
+			}
+		}
 
-	result, err := tx.Exec(
-		`INSERT INTO sales (customer_id, created_at) VALUES (?, ?)`,
-		*customerID,
-		time.Now(),
-	)
-	if err != nil {
-		return 0, err
+		for _, serialID := range lineSerials[i] {
+			_, err := tx.Exec(`INSERT INTO sale_item_serials (sale_item_id, serial_id) VALUES (?, ?)`, saleItemID, serialID)
+			if err != nil {
+				return 0, err
+			}
+		}
 	}
-	saleID, err := result.LastInsertId()
-	if err != nil {
+
+	if err := recordPayments(tx, saleID, *customerID, tenders, change); err != nil {
 		return 0, err
 	}
 
-	for _, item := range items {
-		price := itemPrices[item.ProductID]
+	for _, taxLine := range cart.Taxes {
 		_, err := tx.Exec(
-			`INSERT INTO sale_items (sale_id, product_id, quantity, price) VALUES (?, ?, ?, ?)`,
+			`INSERT INTO sale_taxes (sale_id, tax_class_id, rate_bp, net_cents, tax_cents) VALUES (?, ?, ?, ?, ?)`,
 			saleID,
-			item.ProductID,
-			item.Quantity,
-			price,
+			taxLine.TaxClassID,
+			int64(taxLine.Rate),
+			taxLine.Net.Amount,
+			taxLine.Tax.Amount,
 		)
 		if err != nil {
 			return 0, err
@@ -97,3 +219,356 @@ func CreateSale(db *sql.DB, customerID *int64, items []SaleItem) (int64, error)
 
 	return saleID, nil
 }
+
+// VoidSale cancels a completed sale. Stock and any store credit spent on it
+// are restored in the same transaction. Sales with returns against them
+// cannot be voided, since part of the goods are already back in stock.
+func VoidSale(db *sql.DB, saleID int64, user, reason string) error {
+	if user == "" {
+		return errors.New("voiding a sale requires a user")
+	}
+	if reason == "" {
+		return errors.New("voiding a sale requires a reason")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var (
+		status     string
+		customerID int64
+		locationID int64
+		currency   string
+	)
+	err = tx.QueryRow(
+		`SELECT status, customer_id, location_id, currency FROM sales WHERE id = ?`,
+		saleID,
+	).Scan(&status, &customerID, &locationID, &currency)
+	if err != nil {
+		return err
+	}
+	if SaleStatus(status) == SaleVoided {
+		return fmt.Errorf("%w: sale %d", ErrSaleVoided, saleID)
+	}
+
+	var returns int64
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM returns WHERE sale_id = ?`, saleID).Scan(&returns); err != nil {
+		return err
+	}
+	if returns > 0 {
+		return fmt.Errorf("%w: sale %d", ErrSaleHasReturns, saleID)
+	}
+
+	rows, err := tx.Query(`SELECT id, product_id, quantity, unit_cost_cents, currency FROM sale_items WHERE sale_id = ? ORDER BY id`, saleID)
+	if err != nil {
+		return err
+	}
+	var (
+		itemIDs   []int64
+		items     []SaleItem
+		itemCosts []money.Money
+	)
+	for rows.Next() {
+		var (
+			id, unitCost int64
+			currency     string
+			item         SaleItem
+		)
+		if err := rows.Scan(&id, &item.ProductID, &item.Quantity, &unitCost, &currency); err != nil {
+			rows.Close()
+			return err
+		}
+		itemIDs = append(itemIDs, id)
+		items = append(items, item)
+		itemCosts = append(itemCosts, money.New(unitCost, currency))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	// Goods go back into the lots they were sold from, and serial numbers
+	// back into stock, at what they cost when sold.
+	for i, item := range items {
+		sold, err := saleItemSerials(tx, itemIDs[i])
+		if err != nil {
+			return err
+		}
+		serials := make([]string, 0, len(sold))
+		for _, serial := range sold {
+			serials = append(serials, serial.Serial)
+		}
+		if err := receiveSerials(tx, item.ProductID, locationID, serials); err != nil {
+			return err
+		}
+		allocations, err := saleItemLots(tx, itemIDs[i])
+		if err != nil {
+			return err
+		}
+		rest := item.Quantity
+		for _, allocation := range allocations {
+			err := moveStock(tx, StockMovement{
+				ProductID:  item.ProductID,
+				LocationID: locationID,
+				Kind:       MovementVoid,
+				Quantity:   allocation.Quantity,
+				Reason:     reason,
+				Reference:  fmt.Sprintf("sale #%d", saleID),
+				User:       user,
+				Lot:        allocation.Lot,
+				UnitCost:   &itemCosts[i],
+			})
+			if err != nil {
+				return err
+			}
+			rest -= allocation.Quantity
+		}
+		err = moveStock(tx, StockMovement{
+			ProductID:  item.ProductID,
+			LocationID: locationID,
+			Kind:       MovementVoid,
+			Quantity:   rest,
+			Reason:     reason,
+			Reference:  fmt.Sprintf("sale #%d", saleID),
+			User:       user,
+			UnitCost:   &itemCosts[i],
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var credit int64
+	err = tx.QueryRow(
+		`SELECT COALESCE(SUM(amount_cents - change_cents), 0) FROM payments WHERE sale_id = ? AND tender = ?`,
+		saleID,
+		string(TenderStoreCredit),
+	).Scan(&credit)
+	if err != nil {
+		return err
+	}
+	if credit > 0 {
+		if err := adjustStoreCredit(tx, customerID, money.New(credit, currency)); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(
+		`UPDATE sales SET status = ?, voided_at = ?, voided_by = ?, void_reason = ? WHERE id = ?`,
+		string(SaleVoided),
+		time.Now(),
+		user,
+		reason,
+		saleID,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func GetSale(db *sql.DB, saleID int64) (Sale, error) {
+	var (
+		sale     = Sale{ID: saleID}
+		status   string
+		voidedAt sql.NullTime
+		mode     string
+		currency string
+		subtotal int64
+		discount int64
+		taxTotal int64
+		total    int64
+	)
+	err := db.QueryRow(
+		`SELECT customer_id, location_id, created_at, status, voided_at, voided_by, void_reason,
+			tax_mode, currency, subtotal_cents, discount_cents, tax_cents, total_cents
+		FROM sales WHERE id = ?`,
+		saleID,
+	).Scan(
+		&sale.CustomerID, &sale.LocationID, &sale.CreatedAt, &status, &voidedAt, &sale.VoidedBy, &sale.VoidReason,
+		&mode, &currency, &subtotal, &discount, &taxTotal, &total,
+	)
+	if err != nil {
+		return Sale{}, err
+	}
+	sale.Status = SaleStatus(status)
+	if voidedAt.Valid {
+		sale.VoidedAt = &voidedAt.Time
+	}
+	sale.Mode = tax.Mode(mode)
+	sale.Subtotal = money.New(subtotal, currency)
+	sale.Discount = money.New(discount, currency)
+	sale.Tax = money.New(taxTotal, currency)
+	sale.Total = money.New(total, currency)
+
+	lines, err := saleLines(db, saleID)
+	if err != nil {
+		return Sale{}, err
+	}
+	sale.Lines = lines
+
+	taxes, err := saleTaxes(db, saleID)
+	if err != nil {
+		return Sale{}, err
+	}
+	sale.Taxes = taxes
+
+	payments, err := salePayments(db, saleID)
+	if err != nil {
+		return Sale{}, err
+	}
+	sale.Payments = payments
+	sale.Change = money.Zero(currency)
+	for _, payment := range payments {
+		sale.Change = sale.Change.Add(payment.Change)
+	}
+
+	return sale, nil
+}
+
+func saleLines(db *sql.DB, saleID int64) ([]SaleLine, error) {
+	rows, err := db.Query(
+		`SELECT si.id, si.product_id, p.name, si.quantity, p.unit, si.price_cents, si.currency, si.discount_cents,
+			si.tax_class_id, si.tax_rate_bp, si.net_cents, si.tax_cents, si.total_cents,
+			si.pack_id, COALESCE(pp.name, ''), si.packs, si.pack_price_cents, si.price_list,
+			si.unit_cost_cents, si.cost_cents
+		FROM sale_items si
+		JOIN products p ON p.id = si.product_id
+		LEFT JOIN product_packs pp ON pp.id = si.pack_id
+		WHERE si.sale_id = ? ORDER BY si.id`,
+		saleID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []SaleLine
+	for rows.Next() {
+		var (
+			line                                  SaleLine
+			price, discount, net, taxCents, total int64
+			currency, unit                        string
+			rate, packPrice, unitCost, cost       int64
+			packID                                sql.NullInt64
+		)
+		if err := rows.Scan(
+			&line.ID, &line.ProductID, &line.Name, &line.Quantity, &unit, &price, &currency, &discount,
+			&line.TaxClassID, &rate, &net, &taxCents, &total,
+			&packID, &line.PackName, &line.Packs, &packPrice, &line.PriceList,
+			&unitCost, &cost,
+		); err != nil {
+			return nil, err
+		}
+		if packID.Valid {
+			line.PackID = &packID.Int64
+			line.PackPrice = money.New(packPrice, currency)
+		}
+		line.Unit = quantity.Unit(unit)
+		line.UnitPrice = money.New(price, currency)
+		line.Discount = money.New(discount, currency)
+		line.TaxRate = tax.Rate(rate)
+		line.Net = money.New(net, currency)
+		line.Tax = money.New(taxCents, currency)
+		line.Total = money.New(total, currency)
+		line.UnitCost = money.New(unitCost, currency)
+		line.Cost = money.New(cost, currency)
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range lines {
+		discounts, err := saleLineDiscounts(db, lines[i].ID, lines[i].UnitPrice.Currency)
+		if err != nil {
+			return nil, err
+		}
+		lines[i].Discounts = discounts
+		lots, err := saleItemLots(db, lines[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		lines[i].Lots = lots
+		serials, err := saleItemSerials(db, lines[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		lines[i].Serials = serials
+	}
+
+	return lines, nil
+}
+
+func saleLineDiscounts(db *sql.DB, saleItemID int64, currency string) ([]LineDiscount, error) {
+	rows, err := db.Query(
+		`SELECT promotion_id, name, amount_cents FROM sale_item_discounts WHERE sale_item_id = ? ORDER BY id`,
+		saleItemID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var discounts []LineDiscount
+	for rows.Next() {
+		var (
+			discount LineDiscount
+			amount   int64
+		)
+		if err := rows.Scan(&discount.PromotionID, &discount.Name, &amount); err != nil {
+			return nil, err
+		}
+		discount.Amount = money.New(amount, currency)
+		discounts = append(discounts, discount)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return discounts, nil
+}
+
+func saleTaxes(db *sql.DB, saleID int64) ([]TaxLine, error) {
+	rows, err := db.Query(
+		`SELECT st.tax_class_id, st.rate_bp, st.net_cents, st.tax_cents, s.currency
+		FROM sale_taxes st JOIN sales s ON s.id = st.sale_id
+		WHERE st.sale_id = ? ORDER BY st.rate_bp DESC, st.tax_class_id`,
+		saleID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTaxLines(rows)
+}
+
+func scanTaxLines(rows *sql.Rows) ([]TaxLine, error) {
+	var taxes []TaxLine
+	for rows.Next() {
+		var (
+			taxLine       TaxLine
+			rate          int64
+			net, taxCents int64
+			currency      string
+		)
+		if err := rows.Scan(&taxLine.TaxClassID, &rate, &net, &taxCents, &currency); err != nil {
+			return nil, err
+		}
+		taxLine.Rate = tax.Rate(rate)
+		taxLine.Net = money.New(net, currency)
+		taxLine.Tax = money.New(taxCents, currency)
+		taxes = append(taxes, taxLine)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return taxes, nil
+}
@@ -0,0 +1,138 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"pos-system/internal/barcode"
+	"pos-system/internal/quantity"
+)
+
+// ScannedItem is what a scan resolves to. Pack is set for a pack barcode,
+// in which case one scan is one pack. Quantity is set when the code
+// carries one, as scale labels and GS1 net weights do, and is zero
+// otherwise.
+type ScannedItem struct {
+	Product  Product
+	Pack     *Pack
+	Quantity quantity.Quantity
+	Scan     barcode.Scan
+}
+
+// LookupScan resolves a decoded scan. Product barcodes and aliases are
+// matched first, then pack barcodes; failing that, a GS1 variable-measure
+// code is decoded and its item code looked up, with and without leading
+// zeros so that PLU aliases match. It returns sql.ErrNoRows when nothing
+// matches.
+func LookupScan(db *sql.DB, scan barcode.Scan) (ScannedItem, error) {
+	item := ScannedItem{Scan: scan}
+	product, err := productForScan(db, scan)
+	if err == nil {
+		item.Product = product
+		if weight, ok := quantity.Convert(scan.Weight, quantity.Kilogram, productUnit(product)); ok && weight > 0 {
+			item.Quantity = weight
+		}
+		return item, nil
+	}
+	if err != sql.ErrNoRows {
+		return ScannedItem{}, err
+	}
+
+	pack, err := packForScan(db, scan)
+	if err == nil {
+		item.Pack = &pack
+		item.Product, err = GetProduct(db, pack.ProductID)
+		return item, err
+	}
+	if err != sql.ErrNoRows {
+		return ScannedItem{}, err
+	}
+
+	measure, ok := barcode.ParseVariableMeasure(scan.Code)
+	if !ok {
+		return ScannedItem{}, sql.ErrNoRows
+	}
+	product, err = GetProductByBarcode(db, measure.ItemCode)
+	if trimmed := strings.TrimLeft(measure.ItemCode, "0"); err == sql.ErrNoRows && trimmed != measure.ItemCode && trimmed != "" {
+		product, err = GetProductByBarcode(db, trimmed)
+	}
+	if err != nil {
+		return ScannedItem{}, err
+	}
+
+	item.Product = product
+	if !measure.Priced {
+		// Labels weigh in kilograms; the product may be sold by another mass.
+		weight, ok := quantity.Convert(measure.Weight, quantity.Kilogram, productUnit(product))
+		if !ok {
+			return ScannedItem{}, fmt.Errorf("%w: %s is sold by %s, not by weight", ErrInvalidBarcode, product.Name, productUnit(product))
+		}
+		item.Quantity = weight
+	}
+	if measure.Priced {
+		if product.Price.Amount <= 0 {
+			return ScannedItem{}, fmt.Errorf("%w: %s has no unit price", ErrInvalidBarcode, product.Name)
+		}
+		// Work the quantity back from the label price; repricing it at the
+		// unit price can differ from the label by at most a rounding cent.
+		item.Quantity = quantity.Quantity(measure.Price.MulDiv(quantity.Scale, product.Price.Amount).Amount)
+	}
+	if item.Quantity <= 0 {
+		return ScannedItem{}, fmt.Errorf("%w: %s carries no quantity", ErrInvalidBarcode, scan.Code)
+	}
+	if err := checkQuantity(productUnit(product), item.Quantity); err != nil {
+		return ScannedItem{}, err
+	}
+	return item, nil
+}
+
+func productForScan(db *sql.DB, scan barcode.Scan) (Product, error) {
+	for _, code := range scanCandidates(scan) {
+		product, err := GetProductByBarcode(db, code)
+		if err != sql.ErrNoRows {
+			return product, err
+		}
+	}
+	return Product{}, sql.ErrNoRows
+}
+
+func packForScan(db *sql.DB, scan barcode.Scan) (Pack, error) {
+	for _, code := range scanCandidates(scan) {
+		pack, err := GetPackByBarcode(db, code)
+		if err != sql.ErrNoRows {
+			return pack, err
+		}
+	}
+	return Pack{}, sql.ErrNoRows
+}
+
+// scanCandidates lists the normalised code first, then the forms an item
+// may have been registered under: the code as scanned, the UPC-A inside an
+// EAN-13 and the full GTIN-14. An 8-digit code that reads as both EAN-8
+// and UPC-E also tries the UPC-A it expands to.
+func scanCandidates(scan barcode.Scan) []string {
+	candidates := []string{scan.Code}
+	if scan.Symbology != barcode.GS1 {
+		candidates = append(candidates, scan.Raw)
+	}
+	if scan.Symbology == barcode.EAN8 {
+		if expanded, ok := barcode.ExpandUPCE(scan.Code); ok {
+			candidates = append(candidates, "0"+expanded, expanded)
+		}
+	}
+	if len(scan.Code) == 13 && scan.Code[0] == '0' {
+		candidates = append(candidates, scan.Code[1:])
+	}
+	candidates = append(candidates, scan.GTIN)
+
+	unique := make([]string, 0, len(candidates))
+	seen := make(map[string]bool, len(candidates))
+	for _, code := range candidates {
+		if code != "" && !seen[code] {
+			seen[code] = true
+			unique = append(unique, code)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,306 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"pos-system/internal/quantity"
+)
+
+type SerialStatus string
+
+const (
+	SerialInStock SerialStatus = "in_stock"
+	SerialSold    SerialStatus = "sold"
+	// SerialInTransit units are on a transfer between locations.
+	SerialInTransit SerialStatus = "in_transit"
+	// SerialReturned units came back without going into stock, such as
+	// faulty goods sent on for a warranty claim.
+	SerialReturned SerialStatus = "returned"
+	SerialRemoved  SerialStatus = "removed"
+)
+
+var (
+	ErrSerialRequired    = errors.New("product is tracked by serial number")
+	ErrSerialUnavailable = errors.New("serial number is not in stock here")
+	ErrDuplicateSerial   = errors.New("serial number is already in stock")
+	ErrSerialNotSold     = errors.New("serial number was not sold on this sale")
+)
+
+// Serial is one unit of a product tracked by serial number.
+type Serial struct {
+	ID        int64
+	ProductID int64
+	Name      string
+	Serial    string
+	Status    SerialStatus
+	// LocationID is where the unit is, or was last, in stock.
+	LocationID int64
+	Location   string
+	ReceivedAt time.Time
+	// Sales lists every sale of the unit, oldest first.
+	Sales []SerialSale
+}
+
+type SerialSale struct {
+	SaleID     int64
+	SaleItemID int64
+	SoldAt     time.Time
+	SaleStatus SaleStatus
+	// ReturnID is set once the unit has come back on a return.
+	ReturnID *int64
+}
+
+// SoldSerial is a unit sold on a sale line.
+type SoldSerial struct {
+	SerialID int64
+	Serial   string
+	ReturnID *int64
+}
+
+// requireSerials checks that a movement of qty names one serial number per
+// unit of a product tracked by serial number, and returns them. Serials of
+// other products are dropped.
+func requireSerials(q queryer, productID int64, qty quantity.Quantity, serials []string) ([]string, error) {
+	var track bool
+	if err := q.QueryRow(`SELECT track_serials FROM products WHERE id = ?`, productID).Scan(&track); err != nil {
+		return nil, err
+	}
+	if !track {
+		return nil, nil
+	}
+	units := max(qty, -qty)
+	if quantity.Of(int64(len(serials))) != units {
+		return nil, fmt.Errorf("%w: %s units need %s serial numbers, got %d", ErrSerialRequired, units, units, len(serials))
+	}
+	for _, serial := range serials {
+		if serial == "" {
+			return nil, fmt.Errorf("%w: serial numbers cannot be blank", ErrSerialRequired)
+		}
+	}
+	return serials, nil
+}
+
+// moveSerials moves a movement's serial numbers into or out of stock with
+// its goods.
+func moveSerials(q queryer, movement StockMovement) error {
+	units := max(movement.Quantity, -movement.Quantity)
+	if quantity.Of(int64(len(movement.Serials))) != units {
+		return fmt.Errorf("%w: %s units moved with %d serial numbers", ErrSerialRequired, units, len(movement.Serials))
+	}
+	if movement.Quantity > 0 {
+		return receiveSerials(q, movement.ProductID, movement.LocationID, movement.Serials)
+	}
+	status := SerialRemoved
+	if movement.Kind == MovementSale {
+		status = SerialSold
+	}
+	_, err := releaseSerials(q, movement.ProductID, movement.LocationID, movement.Serials, status)
+	return err
+}
+
+// receiveSerials puts units into stock at a location, registering serial
+// numbers seen for the first time.
+func receiveSerials(q queryer, productID, locationID int64, serials []string) error {
+	for _, serial := range serials {
+		var status string
+		err := q.QueryRow(`SELECT status FROM serials WHERE product_id = ? AND serial = ?`, productID, serial).Scan(&status)
+		if err == sql.ErrNoRows {
+			if _, err := q.Exec(
+				`INSERT INTO serials (product_id, serial, status, location_id, received_at) VALUES (?, ?, ?, ?, ?)`,
+				productID,
+				serial,
+				string(SerialInStock),
+				locationID,
+				time.Now(),
+			); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if SerialStatus(status) == SerialInStock {
+			return fmt.Errorf("%w: %s", ErrDuplicateSerial, serial)
+		}
+		if _, err := q.Exec(
+			`UPDATE serials SET status = ?, location_id = ? WHERE product_id = ? AND serial = ?`,
+			string(SerialInStock),
+			locationID,
+			productID,
+			serial,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stockedSerial returns the id of a unit in stock at a location.
+func stockedSerial(q queryer, productID, locationID int64, serial string) (int64, error) {
+	var id int64
+	err := q.QueryRow(
+		`SELECT id FROM serials WHERE product_id = ? AND serial = ? AND status = ? AND location_id = ?`,
+		productID,
+		serial,
+		string(SerialInStock),
+		locationID,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("%w: %s", ErrSerialUnavailable, serial)
+	}
+	return id, err
+}
+
+// releaseSerials takes units out of stock at a location, returning their
+// ids. Every unit must be in stock there.
+func releaseSerials(q queryer, productID, locationID int64, serials []string, status SerialStatus) ([]int64, error) {
+	ids := make([]int64, 0, len(serials))
+	for _, serial := range serials {
+		id, err := stockedSerial(q, productID, locationID, serial)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := q.Exec(`UPDATE serials SET status = ? WHERE id = ?`, string(status), id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// serialsInStock keeps the serial numbers of a product still in stock at a
+// location.
+func serialsInStock(q queryer, productID, locationID int64, serials []string) ([]string, error) {
+	var inStock []string
+	for _, serial := range serials {
+		var count int64
+		err := q.QueryRow(
+			`SELECT COUNT(*) FROM serials WHERE product_id = ? AND serial = ? AND status = ? AND location_id = ?`,
+			productID,
+			serial,
+			string(SerialInStock),
+			locationID,
+		).Scan(&count)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			inStock = append(inStock, serial)
+		}
+	}
+	return inStock, nil
+}
+
+func saleItemSerials(q queryer, saleItemID int64) ([]SoldSerial, error) {
+	rows, err := q.Query(
+		`SELECT ss.serial_id, s.serial, ss.return_id
+		FROM sale_item_serials ss JOIN serials s ON s.id = ss.serial_id
+		WHERE ss.sale_item_id = ? ORDER BY ss.id`,
+		saleItemID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var serials []SoldSerial
+	for rows.Next() {
+		var (
+			serial   SoldSerial
+			returnID sql.NullInt64
+		)
+		if err := rows.Scan(&serial.SerialID, &serial.Serial, &returnID); err != nil {
+			return nil, err
+		}
+		if returnID.Valid {
+			serial.ReturnID = &returnID.Int64
+		}
+		serials = append(serials, serial)
+	}
+	return serials, rows.Err()
+}
+
+// ListSerials returns every unit of a product ever received.
+func ListSerials(db *sql.DB, productID int64) ([]Serial, error) {
+	return querySerials(db, `WHERE s.product_id = ? ORDER BY s.serial`, productID)
+}
+
+// FindSerial looks a serial number up across all products, with the sales
+// of each unit carrying it, for warranty claims and returns.
+func FindSerial(db *sql.DB, serial string) ([]Serial, error) {
+	serials, err := querySerials(db, `WHERE s.serial = ? ORDER BY s.product_id`, serial)
+	if err != nil {
+		return nil, err
+	}
+	for i := range serials {
+		sales, err := serialSales(db, serials[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		serials[i].Sales = sales
+	}
+	return serials, nil
+}
+
+func querySerials(db *sql.DB, where string, args ...any) ([]Serial, error) {
+	rows, err := db.Query(
+		`SELECT s.id, s.product_id, p.name, s.serial, s.status, s.location_id, COALESCE(l.name, ''), s.received_at
+		FROM serials s JOIN products p ON p.id = s.product_id LEFT JOIN locations l ON l.id = s.location_id `+where,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var serials []Serial
+	for rows.Next() {
+		var (
+			serial Serial
+			status string
+		)
+		if err := rows.Scan(&serial.ID, &serial.ProductID, &serial.Name, &serial.Serial, &status, &serial.LocationID, &serial.Location, &serial.ReceivedAt); err != nil {
+			return nil, err
+		}
+		serial.Status = SerialStatus(status)
+		serials = append(serials, serial)
+	}
+	return serials, rows.Err()
+}
+
+func serialSales(db *sql.DB, serialID int64) ([]SerialSale, error) {
+	rows, err := db.Query(
+		`SELECT sa.id, si.id, sa.created_at, sa.status, ss.return_id
+		FROM sale_item_serials ss
+		JOIN sale_items si ON si.id = ss.sale_item_id
+		JOIN sales sa ON sa.id = si.sale_id
+		WHERE ss.serial_id = ? ORDER BY ss.id`,
+		serialID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sales []SerialSale
+	for rows.Next() {
+		var (
+			sale     SerialSale
+			status   string
+			returnID sql.NullInt64
+		)
+		if err := rows.Scan(&sale.SaleID, &sale.SaleItemID, &sale.SoldAt, &status, &returnID); err != nil {
+			return nil, err
+		}
+		sale.SaleStatus = SaleStatus(status)
+		if returnID.Valid {
+			sale.ReturnID = &returnID.Int64
+		}
+		sales = append(sales, sale)
+	}
+	return sales, rows.Err()
+}
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"database/sql"
+
+	"pos-system/internal/tax"
+)
+
+func GetSetting(db *sql.DB, key string) (string, error) {
+	return getSetting(db, key)
+}
+
+func getSetting(q queryer, key string) (string, error) {
+	var value string
+	err := q.QueryRow(`SELECT value FROM settings WHERE key = ?`, key).Scan(&value)
+	return value, err
+}
+
+func SetSetting(db *sql.DB, key, value string) error {
+	_, err := db.Exec(
+		`INSERT INTO settings (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value`,
+		key,
+		value,
+	)
+	return err
+}
+
+func GetTaxMode(db *sql.DB) (tax.Mode, error) {
+	return taxMode(db)
+}
+
+func taxMode(q queryer) (tax.Mode, error) {
+	value, err := getSetting(q, "tax_mode")
+	if err == sql.ErrNoRows {
+		return tax.Inclusive, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return tax.ParseMode(value)
+}
+
+func SetTaxMode(db *sql.DB, mode tax.Mode) error {
+	if _, err := tax.ParseMode(string(mode)); err != nil {
+		return err
+	}
+	return SetSetting(db, "tax_mode", string(mode))
+}
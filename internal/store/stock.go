@@ -0,0 +1,349 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"pos-system/internal/money"
+	"pos-system/internal/quantity"
+)
+
+type MovementKind string
+
+const (
+	MovementSale       MovementKind = "sale"
+	MovementVoid       MovementKind = "void"
+	MovementReturn     MovementKind = "return"
+	MovementReceipt    MovementKind = "receipt"
+	MovementAdjustment MovementKind = "adjustment"
+	MovementCount      MovementKind = "count"
+	MovementTransfer   MovementKind = "transfer"
+)
+
+// StockMovement is one change to a product's on-hand stock. Quantity is
+// signed: goods in are positive, goods out negative. A zero LocationID
+// means the default location.
+type StockMovement struct {
+	ID         int64
+	ProductID  int64
+	LocationID int64
+	// Location is the location's name on movements read back.
+	Location  string
+	Kind      MovementKind
+	Quantity  quantity.Quantity
+	Reason    string
+	Reference string
+	User      string
+	CreatedAt time.Time
+	// Lot names the lot goods came from or went into. Expiry is only read
+	// when goods go into a new lot.
+	Lot    string
+	Expiry *time.Time
+	// Serials name the units moved of a product tracked by serial number.
+	Serials []string
+	// UnitCost is what goods coming in cost per base unit. Without one they
+	// come in at the product's cost price.
+	UnitCost *money.Money
+}
+
+// StockDiscrepancy is a product whose cached stock no longer matches the
+// sum of its movements.
+type StockDiscrepancy struct {
+	ProductID int64
+	Name      string
+	OnHand    quantity.Quantity
+	Ledger    quantity.Quantity
+}
+
+// moveStock books a movement and applies it to the product's stock at its
+// location and to products.stock, which are kept as running totals of the
+// ledger. Outgoing movements fail with ErrInsufficientStock rather than
+// taking the location's stock negative. Movements with a lot also move
+// that lot; other outgoing movements trim lots so they never hold more
+// than the location does. Serial numbers given move in or out of stock
+// with the goods.
+func moveStock(q queryer, movement StockMovement) error {
+	if movement.Quantity == 0 {
+		return nil
+	}
+	if movement.LocationID == 0 {
+		movement.LocationID = DefaultLocationID
+	}
+	if _, err := q.Exec(
+		`INSERT INTO product_stock (product_id, location_id, quantity) VALUES (?, ?, 0)
+		ON CONFLICT(product_id, location_id) DO NOTHING`,
+		movement.ProductID,
+		movement.LocationID,
+	); err != nil {
+		return err
+	}
+	result, err := q.Exec(
+		`UPDATE product_stock SET quantity = quantity + ?
+		WHERE product_id = ? AND location_id = ? AND quantity + ? >= 0`,
+		movement.Quantity,
+		movement.ProductID,
+		movement.LocationID,
+		movement.Quantity,
+	)
+	if err != nil {
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return fmt.Errorf("%w for product %d at location %d", ErrInsufficientStock, movement.ProductID, movement.LocationID)
+	}
+	if err := costMovement(q, movement); err != nil {
+		return err
+	}
+	if _, err := q.Exec(`UPDATE products SET stock = stock + ? WHERE id = ?`, movement.Quantity, movement.ProductID); err != nil {
+		return err
+	}
+	if movement.Lot != "" {
+		err = moveLot(q, movement)
+	} else if movement.Quantity < 0 {
+		err = trimLots(q, movement.ProductID, movement.LocationID)
+	}
+	if err != nil {
+		return err
+	}
+	if len(movement.Serials) > 0 {
+		if err := moveSerials(q, movement); err != nil {
+			return err
+		}
+	}
+
+	_, err = q.Exec(
+		`INSERT INTO stock_movements (product_id, location_id, kind, quantity, reason, reference, user, created_at, lot)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		movement.ProductID,
+		movement.LocationID,
+		string(movement.Kind),
+		movement.Quantity,
+		movement.Reason,
+		movement.Reference,
+		movement.User,
+		time.Now(),
+		movement.Lot,
+	)
+	return err
+}
+
+// AdjustStock books a manual receipt or adjustment. Receipts of products
+// tracked by lot must name the lot, and movements of products tracked by
+// serial number every unit.
+func AdjustStock(db *sql.DB, movement StockMovement) error {
+	switch movement.Kind {
+	case MovementReceipt, MovementAdjustment:
+	default:
+		return fmt.Errorf("stock cannot be adjusted manually as %q", movement.Kind)
+	}
+	if movement.Quantity == 0 {
+		return errors.New("quantity must not be zero")
+	}
+	if movement.Reason == "" {
+		return errors.New("stock adjustments require a reason")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var (
+		unit      string
+		trackLots bool
+	)
+	err = tx.QueryRow(`SELECT unit, track_lots FROM products WHERE id = ?`, movement.ProductID).Scan(&unit, &trackLots)
+	if err != nil {
+		return err
+	}
+	if err := checkQuantity(quantity.Unit(unit), movement.Quantity); err != nil {
+		return err
+	}
+	if trackLots && movement.Kind == MovementReceipt && movement.Lot == "" {
+		return fmt.Errorf("%w: receipts need a lot number", ErrLotRequired)
+	}
+	movement.Serials, err = requireSerials(tx, movement.ProductID, movement.Quantity, movement.Serials)
+	if err != nil {
+		return err
+	}
+	if err := checkLocation(tx, movement.LocationID); err != nil {
+		return err
+	}
+	if err := moveStock(tx, movement); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CorrectStockCount sets a product's stock at a location to what was
+// physically counted, booking the difference as a count correction. It
+// returns that difference. Products tracked by lot are counted one lot at
+// a time, and those tracked by serial number must name every unit found
+// or missing, as with AdjustStock. Open counts at the location take the
+// correction into their expected quantity.
+func CorrectStockCount(db *sql.DB, productID, locationID int64, counted quantity.Quantity, lot string, serials []string, reason, user string) (quantity.Quantity, error) {
+	if counted < 0 {
+		return 0, errors.New("counted quantity cannot be negative")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var (
+		unit      string
+		trackLots bool
+	)
+	if err := tx.QueryRow(`SELECT unit, track_lots FROM products WHERE id = ?`, productID).Scan(&unit, &trackLots); err != nil {
+		return 0, err
+	}
+	if err := checkQuantity(quantity.Unit(unit), counted); err != nil {
+		return 0, err
+	}
+	if err := checkLocation(tx, locationID); err != nil {
+		return 0, err
+	}
+	if locationID == 0 {
+		locationID = DefaultLocationID
+	}
+	if !trackLots {
+		lot = ""
+	} else if lot == "" {
+		return 0, fmt.Errorf("%w: count corrections need a lot number", ErrLotRequired)
+	}
+	stock, err := countedStock(tx, productID, locationID, lot)
+	if err != nil {
+		return 0, err
+	}
+	delta := counted - stock
+	serials, err = requireSerials(tx, productID, delta, serials)
+	if err != nil {
+		return 0, err
+	}
+	err = moveStock(tx, StockMovement{
+		ProductID:  productID,
+		LocationID: locationID,
+		Kind:       MovementCount,
+		Quantity:   delta,
+		Reason:     reason,
+		Reference:  fmt.Sprintf("counted %s", counted),
+		User:       user,
+		Lot:        lot,
+		Serials:    serials,
+	})
+	if err != nil {
+		return 0, err
+	}
+	// Open counts at the location expect the correction, so approving one
+	// does not book the same variance again.
+	if _, err := tx.Exec(
+		`UPDATE stock_count_lines SET expected = expected + ?
+		WHERE product_id = ? AND count_id IN (SELECT id FROM stock_counts WHERE location_id = ? AND status = ?)`,
+		delta,
+		productID,
+		locationID,
+		string(CountOpen),
+	); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return delta, nil
+}
+
+// countedStock is what a count is compared with: a product's stock at a
+// location, or only what one lot of it holds there.
+func countedStock(q queryer, productID, locationID int64, lot string) (quantity.Quantity, error) {
+	if lot == "" {
+		return stockAt(q, productID, locationID)
+	}
+	var stock quantity.Quantity
+	err := q.QueryRow(
+		`SELECT quantity FROM lots WHERE product_id = ? AND location_id = ? AND number = ?`,
+		productID,
+		locationID,
+		lot,
+	).Scan(&stock)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return stock, err
+}
+
+func ListStockMovements(db *sql.DB, productID int64) ([]StockMovement, error) {
+	rows, err := db.Query(
+		`SELECT m.id, m.product_id, m.location_id, COALESCE(l.name, ''), m.kind, m.quantity, m.reason, m.reference, m.user, m.created_at, m.lot
+		FROM stock_movements m LEFT JOIN locations l ON l.id = m.location_id
+		WHERE m.product_id = ? ORDER BY m.id DESC`,
+		productID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var movements []StockMovement
+	for rows.Next() {
+		var (
+			movement StockMovement
+			kind     string
+		)
+		err := rows.Scan(
+			&movement.ID, &movement.ProductID, &movement.LocationID, &movement.Location, &kind, &movement.Quantity,
+			&movement.Reason, &movement.Reference, &movement.User, &movement.CreatedAt, &movement.Lot,
+		)
+		if err != nil {
+			return nil, err
+		}
+		movement.Kind = MovementKind(kind)
+		movements = append(movements, movement)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return movements, nil
+}
+
+// ReconcileStock lists every product whose stock differs from its ledger.
+func ReconcileStock(db *sql.DB) ([]StockDiscrepancy, error) {
+	rows, err := db.Query(
+		`SELECT p.id, p.name, p.stock, COALESCE(SUM(m.quantity), 0) AS ledger
+		FROM products p LEFT JOIN stock_movements m ON m.product_id = p.id
+		GROUP BY p.id
+		HAVING p.stock <> ledger
+		ORDER BY p.id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var discrepancies []StockDiscrepancy
+	for rows.Next() {
+		var discrepancy StockDiscrepancy
+		if err := rows.Scan(&discrepancy.ProductID, &discrepancy.Name, &discrepancy.OnHand, &discrepancy.Ledger); err != nil {
+			return nil, err
+		}
+		discrepancies = append(discrepancies, discrepancy)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return discrepancies, nil
+}
@@ -0,0 +1,396 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"pos-system/internal/money"
+	"pos-system/internal/quantity"
+)
+
+type CountStatus string
+
+const (
+	CountOpen      CountStatus = "open"
+	CountApproved  CountStatus = "approved"
+	CountCancelled CountStatus = "cancelled"
+)
+
+var ErrStockCountClosed = errors.New("stock count is closed")
+
+// expectedStock selects product p's stock at the location bound to it.
+const expectedStock = `COALESCE((SELECT quantity FROM product_stock WHERE product_id = p.id AND location_id = ?), 0)`
+
+const stockCountColumns = `c.id, c.location_id, COALESCE(l.name, ''), c.name, c.category_id, c.status, c.created_at, c.closed_at, c.closed_by
+	FROM stock_counts c LEFT JOIN locations l ON l.id = c.location_id`
+
+// StockCount is a physical count session. Expected quantities are frozen
+// when a product joins the count, so sales made while counting do not
+// show up as variances.
+type StockCount struct {
+	ID         int64
+	LocationID int64
+	Location   string
+	// CategoryID limits the count to a category and its subcategories; nil
+	// counts every product.
+	CategoryID *int64
+	Name       string
+	Status     CountStatus
+	CreatedAt  time.Time
+	ClosedAt   *time.Time
+	ClosedBy   string
+	Lines      []StockCountLine
+}
+
+type StockCountLine struct {
+	ProductID int64
+	Name      string
+	Unit      quantity.Unit
+	Cost      money.Money
+	Expected  quantity.Quantity
+	// Counted is nil until the product has been counted.
+	Counted *quantity.Quantity
+}
+
+// Variance is counted less expected, zero while uncounted.
+func (l StockCountLine) Variance() quantity.Quantity {
+	if l.Counted == nil {
+		return 0
+	}
+	return *l.Counted - l.Expected
+}
+
+// VarianceValue is the variance at the product's cost price.
+func (l StockCountLine) VarianceValue() money.Money {
+	return l.Variance().Times(l.Cost)
+}
+
+// VarianceValue totals the value impact of every counted line.
+func (c StockCount) VarianceValue() money.Money {
+	total := money.Zero(money.DefaultCurrency)
+	for _, line := range c.Lines {
+		total = total.Add(line.VarianceValue())
+	}
+	return total
+}
+
+// StartStockCount opens a count at a location of every product, or of one
+// category tree, freezing their current stock there as the expected
+// quantities.
+func StartStockCount(db *sql.DB, name string, locationID int64, categoryID *int64) (int64, error) {
+	if name == "" {
+		return 0, errors.New("stock count requires a name")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := checkLocation(tx, locationID); err != nil {
+		return 0, err
+	}
+	if locationID == 0 {
+		locationID = DefaultLocationID
+	}
+	result, err := tx.Exec(
+		`INSERT INTO stock_counts (name, location_id, category_id, status, created_at) VALUES (?, ?, ?, ?, ?)`,
+		name,
+		locationID,
+		categoryID,
+		string(CountOpen),
+		time.Now(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if categoryID == nil {
+		_, err = tx.Exec(
+			`INSERT INTO stock_count_lines (count_id, product_id, expected)
+			SELECT ?, p.id, `+expectedStock+` FROM products p`,
+			id,
+			locationID,
+		)
+	} else {
+		_, err = tx.Exec(
+			subtreeCTE+` INSERT INTO stock_count_lines (count_id, product_id, expected)
+			SELECT ?, p.id, `+expectedStock+` FROM products p WHERE p.category_id IN (SELECT id FROM tree)`,
+			*categoryID,
+			id,
+			locationID,
+		)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// ListStockCounts returns every count, newest first, without its lines.
+func ListStockCounts(db *sql.DB) ([]StockCount, error) {
+	rows, err := db.Query(
+		`SELECT ` + stockCountColumns + ` ORDER BY c.id DESC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []StockCount
+	for rows.Next() {
+		count, err := scanStockCount(rows)
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+func GetStockCount(db *sql.DB, id int64) (StockCount, error) {
+	count, err := scanStockCount(db.QueryRow(
+		`SELECT `+stockCountColumns+` WHERE c.id = ?`,
+		id,
+	))
+	if err != nil {
+		return StockCount{}, err
+	}
+	count.Lines, err = stockCountLines(db, id)
+	if err != nil {
+		return StockCount{}, err
+	}
+	return count, nil
+}
+
+func scanStockCount(row rowScanner) (StockCount, error) {
+	var (
+		count      StockCount
+		categoryID sql.NullInt64
+		status     string
+		closedAt   sql.NullTime
+	)
+	err := row.Scan(&count.ID, &count.LocationID, &count.Location, &count.Name, &categoryID, &status, &count.CreatedAt, &closedAt, &count.ClosedBy)
+	count.Status = CountStatus(status)
+	if categoryID.Valid {
+		count.CategoryID = &categoryID.Int64
+	}
+	if closedAt.Valid {
+		count.ClosedAt = &closedAt.Time
+	}
+	return count, err
+}
+
+func stockCountLines(q queryer, countID int64) ([]StockCountLine, error) {
+	rows, err := q.Query(
+		`SELECT l.product_id, p.name, p.unit, p.cost_cents, p.currency, l.expected, l.counted
+		FROM stock_count_lines l JOIN products p ON p.id = l.product_id
+		WHERE l.count_id = ? ORDER BY p.name, p.id`,
+		countID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []StockCountLine
+	for rows.Next() {
+		var (
+			line     StockCountLine
+			unit     string
+			cost     int64
+			currency string
+			counted  sql.NullInt64
+		)
+		if err := rows.Scan(&line.ProductID, &line.Name, &unit, &cost, &currency, &line.Expected, &counted); err != nil {
+			return nil, err
+		}
+		line.Unit = quantity.Unit(unit)
+		line.Cost = money.New(cost, currency)
+		if counted.Valid {
+			qty := quantity.Quantity(counted.Int64)
+			line.Counted = &qty
+		}
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
+
+// CountItem adds qty to what has been counted of a product, as each scan
+// does. A product outside the count's scope joins it, with its stock at
+// that moment as the expected quantity.
+func CountItem(db *sql.DB, countID, productID int64, qty quantity.Quantity) error {
+	if qty <= 0 {
+		return errors.New("quantity must be greater than zero")
+	}
+	return recordCount(db, countID, productID, qty, true)
+}
+
+// SetCountedQuantity records the total counted of a product, replacing
+// anything counted so far.
+func SetCountedQuantity(db *sql.DB, countID, productID int64, counted quantity.Quantity) error {
+	if counted < 0 {
+		return errors.New("counted quantity cannot be negative")
+	}
+	return recordCount(db, countID, productID, counted, false)
+}
+
+func recordCount(db *sql.DB, countID, productID int64, qty quantity.Quantity, add bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	locationID, err := checkCountOpen(tx, countID)
+	if err != nil {
+		return err
+	}
+	var unit string
+	if err := tx.QueryRow(`SELECT unit FROM products WHERE id = ?`, productID).Scan(&unit); err != nil {
+		return err
+	}
+	if err := checkQuantity(quantity.Unit(unit), qty); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO stock_count_lines (count_id, product_id, expected)
+		SELECT ?, p.id, `+expectedStock+` FROM products p WHERE p.id = ?
+		ON CONFLICT(count_id, product_id) DO NOTHING`,
+		countID,
+		locationID,
+		productID,
+	); err != nil {
+		return err
+	}
+	statement := `UPDATE stock_count_lines SET counted = ? WHERE count_id = ? AND product_id = ?`
+	if add {
+		statement = `UPDATE stock_count_lines SET counted = COALESCE(counted, 0) + ? WHERE count_id = ? AND product_id = ?`
+	}
+	if _, err := tx.Exec(statement, qty, countID, productID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ApproveStockCount closes a count and books each counted variance as a
+// count correction. Products that were never counted are left alone. A
+// count does not say which lots or serial numbers differ, so variances on
+// products tracked that way are refused until they have been corrected
+// one by one with CorrectStockCount, which clears them from the count.
+func ApproveStockCount(db *sql.DB, countID int64, user string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	locationID, err := checkCountOpen(tx, countID)
+	if err != nil {
+		return err
+	}
+	lines, err := stockCountLines(tx, countID)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if line.Variance() == 0 {
+			continue
+		}
+		var trackLots, trackSerials bool
+		err := tx.QueryRow(`SELECT track_lots, track_serials FROM products WHERE id = ?`, line.ProductID).Scan(&trackLots, &trackSerials)
+		if err != nil {
+			return err
+		}
+		switch {
+		case trackSerials:
+			return fmt.Errorf("%s: %w: correct its count with the serial numbers that differ", line.Name, ErrSerialRequired)
+		case trackLots:
+			return fmt.Errorf("%s: %w: correct its count lot by lot", line.Name, ErrLotRequired)
+		}
+		err = moveStock(tx, StockMovement{
+			ProductID:  line.ProductID,
+			LocationID: locationID,
+			Kind:       MovementCount,
+			Quantity:   line.Variance(),
+			Reason:     "stock count",
+			Reference:  fmt.Sprintf("count #%d", countID),
+			User:       user,
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", line.Name, err)
+		}
+	}
+	if err := closeStockCount(tx, countID, CountApproved, user); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CancelStockCount closes a count without touching stock.
+func CancelStockCount(db *sql.DB, countID int64, user string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := checkCountOpen(tx, countID); err != nil {
+		return err
+	}
+	if err := closeStockCount(tx, countID, CountCancelled, user); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkCountOpen returns the location of an open count.
+func checkCountOpen(q queryer, countID int64) (int64, error) {
+	var (
+		status     string
+		locationID int64
+	)
+	err := q.QueryRow(`SELECT status, location_id FROM stock_counts WHERE id = ?`, countID).Scan(&status, &locationID)
+	if err != nil {
+		return 0, err
+	}
+	if CountStatus(status) != CountOpen {
+		return 0, fmt.Errorf("%w: count #%d is %s", ErrStockCountClosed, countID, status)
+	}
+	return locationID, nil
+}
+
+func closeStockCount(q queryer, countID int64, status CountStatus, user string) error {
+	_, err := q.Exec(
+		`UPDATE stock_counts SET status = ?, closed_at = ?, closed_by = ? WHERE id = ?`,
+		string(status),
+		time.Now(),
+		user,
+		countID,
+	)
+	return err
+}
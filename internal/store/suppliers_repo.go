@@ -0,0 +1,48 @@
+package store
+
+import "database/sql"
+
+func ListSuppliers(db *sql.DB) ([]Supplier, error) {
+	rows, err := db.Query(`SELECT id, name, email, phone FROM suppliers ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var suppliers []Supplier
+	for rows.Next() {
+		var supplier Supplier
+		if err := rows.Scan(&supplier.ID, &supplier.Name, &supplier.Email, &supplier.Phone); err != nil {
+			return nil, err
+		}
+		suppliers = append(suppliers, supplier)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return suppliers, nil
+}
+
+func CreateSupplier(db *sql.DB, supplier Supplier) (int64, error) {
+	result, err := db.Exec(`INSERT INTO suppliers (name, email, phone) VALUES (?, ?, ?)`, supplier.Name, supplier.Email, supplier.Phone)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func UpdateSupplier(db *sql.DB, supplier Supplier) error {
+	_, err := db.Exec(`UPDATE suppliers SET name = ?, email = ?, phone = ? WHERE id = ?`, supplier.Name, supplier.Email, supplier.Phone, supplier.ID)
+	return err
+}
+
+// DeleteSupplier removes a supplier. Products it was preferred for are left
+// without one.
+func DeleteSupplier(db *sql.DB, id int64) error {
+	if _, err := db.Exec(`UPDATE products SET supplier_id = NULL WHERE supplier_id = ?`, id); err != nil {
+		return err
+	}
+	_, err := db.Exec(`DELETE FROM suppliers WHERE id = ?`, id)
+	return err
+}
@@ -0,0 +1,253 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"pos-system/internal/quantity"
+)
+
+// SuspendSale parks a cart so it can be resumed later, on this terminal or
+// another one. Stock is not reserved while the cart is parked.
+func SuspendSale(db *sql.DB, label string, customerID *int64, items []SaleItem) (int64, error) {
+	if label == "" {
+		return 0, errors.New("parked cart requires a label")
+	}
+	if len(items) == 0 {
+		return 0, errors.New("parked cart requires at least one item")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	result, err := tx.Exec(
+		`INSERT INTO suspended_sales (label, customer_id, created_at) VALUES (?, ?, ?)`,
+		label,
+		customerID,
+		time.Now(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	suspendedID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return 0, errors.New("quantity must be greater than zero")
+		}
+		if _, err := tx.Exec(
+			`INSERT INTO suspended_sale_items (suspended_sale_id, product_id, quantity, pack_id, lot, serials)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			suspendedID,
+			item.ProductID,
+			item.Quantity,
+			item.PackID,
+			item.Lot,
+			strings.Join(item.Serials, "\n"),
+		); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return suspendedID, nil
+}
+
+func ListSuspendedSales(db *sql.DB) ([]SuspendedSale, error) {
+	rows, err := db.Query(`SELECT id, label, customer_id, created_at FROM suspended_sales ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sales []SuspendedSale
+	for rows.Next() {
+		var (
+			sale       SuspendedSale
+			customerID sql.NullInt64
+		)
+		if err := rows.Scan(&sale.ID, &sale.Label, &customerID, &sale.CreatedAt); err != nil {
+			return nil, err
+		}
+		if customerID.Valid {
+			sale.CustomerID = &customerID.Int64
+		}
+		sales = append(sales, sale)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range sales {
+		items, err := suspendedSaleItems(db, sales[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		sales[i].Items = items
+	}
+	return sales, nil
+}
+
+func suspendedSaleItems(q queryer, suspendedID int64) ([]SaleItem, error) {
+	rows, err := q.Query(
+		`SELECT product_id, quantity, pack_id, lot, serials FROM suspended_sale_items WHERE suspended_sale_id = ? ORDER BY id`,
+		suspendedID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []SaleItem
+	for rows.Next() {
+		var (
+			item    SaleItem
+			packID  sql.NullInt64
+			serials string
+		)
+		if err := rows.Scan(&item.ProductID, &item.Quantity, &packID, &item.Lot, &serials); err != nil {
+			return nil, err
+		}
+		if serials != "" {
+			item.Serials = strings.Split(serials, "\n")
+		}
+		if packID.Valid {
+			item.PackID = &packID.Int64
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// ResumeSale takes a parked cart off the shelf. Quantities are checked
+// against what the location can sell now, with lines for the same product
+// drawing on the same stock: lines that can no longer be filled are cut
+// down to what is available (or dropped) and reported as shortages. The
+// parked cart is removed, so two terminals cannot resume the same one.
+func ResumeSale(db *sql.DB, suspendedID, locationID int64) (SuspendedSale, []StockShortage, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return SuspendedSale{}, nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var (
+		sale       = SuspendedSale{ID: suspendedID}
+		customerID sql.NullInt64
+	)
+	err = tx.QueryRow(
+		`SELECT label, customer_id, created_at FROM suspended_sales WHERE id = ?`,
+		suspendedID,
+	).Scan(&sale.Label, &customerID, &sale.CreatedAt)
+	if err != nil {
+		return SuspendedSale{}, nil, err
+	}
+	if customerID.Valid {
+		sale.CustomerID = &customerID.Int64
+	}
+
+	items, err := suspendedSaleItems(tx, suspendedID)
+	if err != nil {
+		return SuspendedSale{}, nil, err
+	}
+
+	type lotKey struct {
+		productID int64
+		lot       string
+	}
+	var (
+		shortages []StockShortage
+		// Stock already taken by earlier lines, per product and per lot.
+		taken    = make(map[int64]quantity.Quantity)
+		takenLot = make(map[lotKey]quantity.Quantity)
+	)
+	for _, item := range items {
+		var name string
+		err := tx.QueryRow(`SELECT name FROM products WHERE id = ?`, item.ProductID).Scan(&name)
+		if err == sql.ErrNoRows {
+			shortages = append(shortages, StockShortage{ProductID: item.ProductID, Requested: item.Quantity})
+			continue
+		}
+		if err != nil {
+			return SuspendedSale{}, nil, err
+		}
+		stock, err := sellableStock(tx, item.ProductID, locationID, "")
+		if err != nil {
+			return SuspendedSale{}, nil, err
+		}
+		stock -= taken[item.ProductID]
+		if item.Lot != "" {
+			lotStock, err := sellableStock(tx, item.ProductID, locationID, item.Lot)
+			if err != nil {
+				return SuspendedSale{}, nil, err
+			}
+			stock = min(stock, lotStock-takenLot[lotKey{item.ProductID, item.Lot}])
+		}
+		stock = max(stock, 0)
+
+		// Pack lines are checked in whole packs.
+		available, factor := stock, quantity.Of(1)
+		if item.PackID != nil {
+			var packName string
+			factor = 0
+			err := tx.QueryRow(`SELECT name, factor FROM product_packs WHERE id = ?`, *item.PackID).Scan(&packName, &factor)
+			if err != nil && err != sql.ErrNoRows {
+				return SuspendedSale{}, nil, err
+			}
+			name += " (" + packName + ")"
+			available = Pack{Factor: factor}.WholePacks(stock)
+		}
+		// Units with serial numbers are checked one by one.
+		if len(item.Serials) > 0 {
+			item.Serials, err = serialsInStock(tx, item.ProductID, locationID, item.Serials)
+			if err != nil {
+				return SuspendedSale{}, nil, err
+			}
+			available = min(available, quantity.Of(int64(len(item.Serials))))
+			item.Serials = item.Serials[:available.Units()]
+		}
+		if available < item.Quantity {
+			shortages = append(shortages, StockShortage{
+				ProductID: item.ProductID,
+				Name:      name,
+				Requested: item.Quantity,
+				Available: available,
+			})
+			item.Quantity = available
+		}
+		if item.Quantity > 0 {
+			used := item.Quantity.Mul(factor)
+			taken[item.ProductID] += used
+			if item.Lot != "" {
+				takenLot[lotKey{item.ProductID, item.Lot}] += used
+			}
+			sale.Items = append(sale.Items, item)
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM suspended_sale_items WHERE suspended_sale_id = ?`, suspendedID); err != nil {
+		return SuspendedSale{}, nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM suspended_sales WHERE id = ?`, suspendedID); err != nil {
+		return SuspendedSale{}, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return SuspendedSale{}, nil, err
+	}
+	return sale, shortages, nil
+}
@@ -0,0 +1,43 @@
+package store
+
+import (
+	"database/sql"
+
+	"pos-system/internal/tax"
+)
+
+const DefaultTaxClassID int64 = 1
+
+func ListTaxClasses(db *sql.DB) ([]TaxClass, error) {
+	rows, err := db.Query(`SELECT id, code, name, rate_bp FROM tax_classes ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var classes []TaxClass
+	for rows.Next() {
+		var class TaxClass
+		var rate int64
+		if err := rows.Scan(&class.ID, &class.Code, &class.Name, &rate); err != nil {
+			return nil, err
+		}
+		class.Rate = tax.Rate(rate)
+		classes = append(classes, class)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return classes, nil
+}
+
+func UpdateTaxClass(db *sql.DB, class TaxClass) error {
+	_, err := db.Exec(
+		`UPDATE tax_classes SET name = ?, rate_bp = ? WHERE id = ?`,
+		class.Name,
+		int64(class.Rate),
+		class.ID,
+	)
+	return err
+}
@@ -0,0 +1,611 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"pos-system/internal/quantity"
+)
+
+type TransferStatus string
+
+const (
+	TransferDraft      TransferStatus = "draft"
+	TransferDispatched TransferStatus = "dispatched"
+	TransferReceived   TransferStatus = "received"
+	TransferCancelled  TransferStatus = "cancelled"
+)
+
+var ErrTransferState = errors.New("transfer is not in the right state")
+
+// Transfer moves stock from one location to another. Dispatching takes the
+// goods out of the source; until they are received they are in transit
+// and held at neither location.
+type Transfer struct {
+	ID             int64
+	FromLocationID int64
+	FromLocation   string
+	ToLocationID   int64
+	ToLocation     string
+	Status         TransferStatus
+	Reference      string
+	CreatedAt      time.Time
+	DispatchedAt   *time.Time
+	DispatchedBy   string
+	ReceivedAt     *time.Time
+	ReceivedBy     string
+	Lines          []TransferLine
+}
+
+type TransferLine struct {
+	ID        int64
+	ProductID int64
+	Name      string
+	Unit      quantity.Unit
+	Quantity  quantity.Quantity
+	// Serials names every unit of a product tracked by serial number.
+	Serials []string
+	// Received is nil until the transfer is received. Note explains any
+	// difference from Quantity.
+	Received *quantity.Quantity
+	Note     string
+}
+
+// Discrepancy is received less dispatched, negative for goods lost in
+// transit.
+func (l TransferLine) Discrepancy() quantity.Quantity {
+	if l.Received == nil {
+		return 0
+	}
+	return *l.Received - l.Quantity
+}
+
+// TransferReceipt records what arrived of one line when it differs from
+// what was dispatched. Lines of products tracked by serial number name
+// the units that arrived.
+type TransferReceipt struct {
+	LineID   int64
+	Quantity quantity.Quantity
+	Note     string
+	Serials  []string
+}
+
+const transferColumns = `t.id, t.from_location_id, f.name, t.to_location_id, d.name, t.status, t.reference,
+	t.created_at, t.dispatched_at, t.dispatched_by, t.received_at, t.received_by
+	FROM transfers t
+	JOIN locations f ON f.id = t.from_location_id
+	JOIN locations d ON d.id = t.to_location_id`
+
+func CreateTransfer(db *sql.DB, fromLocationID, toLocationID int64, reference string) (int64, error) {
+	if fromLocationID == 0 {
+		fromLocationID = DefaultLocationID
+	}
+	if toLocationID == 0 {
+		toLocationID = DefaultLocationID
+	}
+	if fromLocationID == toLocationID {
+		return 0, errors.New("transfer needs two different locations")
+	}
+	for _, id := range []int64{fromLocationID, toLocationID} {
+		if err := checkLocation(db, id); err != nil {
+			return 0, err
+		}
+	}
+	result, err := db.Exec(
+		`INSERT INTO transfers (from_location_id, to_location_id, status, reference, created_at) VALUES (?, ?, ?, ?, ?)`,
+		fromLocationID,
+		toLocationID,
+		string(TransferDraft),
+		reference,
+		time.Now(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func ListTransfers(db *sql.DB) ([]Transfer, error) {
+	rows, err := db.Query(`SELECT ` + transferColumns + ` ORDER BY t.id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transfers []Transfer
+	for rows.Next() {
+		transfer, err := scanTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, transfer)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range transfers {
+		lines, err := transferLines(db, transfers[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		transfers[i].Lines = lines
+	}
+	return transfers, nil
+}
+
+func GetTransfer(db *sql.DB, id int64) (Transfer, error) {
+	transfer, err := scanTransfer(db.QueryRow(`SELECT `+transferColumns+` WHERE t.id = ?`, id))
+	if err != nil {
+		return Transfer{}, err
+	}
+	transfer.Lines, err = transferLines(db, id)
+	if err != nil {
+		return Transfer{}, err
+	}
+	return transfer, nil
+}
+
+func scanTransfer(row rowScanner) (Transfer, error) {
+	var (
+		transfer             Transfer
+		status               string
+		dispatched, received sql.NullTime
+	)
+	err := row.Scan(
+		&transfer.ID, &transfer.FromLocationID, &transfer.FromLocation, &transfer.ToLocationID, &transfer.ToLocation,
+		&status, &transfer.Reference, &transfer.CreatedAt, &dispatched, &transfer.DispatchedBy, &received, &transfer.ReceivedBy,
+	)
+	transfer.Status = TransferStatus(status)
+	if dispatched.Valid {
+		transfer.DispatchedAt = &dispatched.Time
+	}
+	if received.Valid {
+		transfer.ReceivedAt = &received.Time
+	}
+	return transfer, err
+}
+
+func transferLines(q queryer, transferID int64) ([]TransferLine, error) {
+	rows, err := q.Query(
+		`SELECT l.id, l.product_id, p.name, p.unit, l.quantity, l.received, l.note
+		FROM transfer_lines l JOIN products p ON p.id = l.product_id
+		WHERE l.transfer_id = ? ORDER BY l.id`,
+		transferID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []TransferLine
+	for rows.Next() {
+		var (
+			line     TransferLine
+			unit     string
+			received sql.NullInt64
+		)
+		if err := rows.Scan(&line.ID, &line.ProductID, &line.Name, &unit, &line.Quantity, &received, &line.Note); err != nil {
+			return nil, err
+		}
+		line.Unit = quantity.Unit(unit)
+		if received.Valid {
+			qty := quantity.Quantity(received.Int64)
+			line.Received = &qty
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range lines {
+		lines[i].Serials, err = transferLineSerials(q, lines[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return lines, nil
+}
+
+func transferLineSerials(q queryer, lineID int64) ([]string, error) {
+	rows, err := q.Query(
+		`SELECT s.serial FROM transfer_line_serials ts JOIN serials s ON s.id = ts.serial_id
+		WHERE ts.transfer_line_id = ? ORDER BY ts.id`,
+		lineID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var serials []string
+	for rows.Next() {
+		var serial string
+		if err := rows.Scan(&serial); err != nil {
+			return nil, err
+		}
+		serials = append(serials, serial)
+	}
+	return serials, rows.Err()
+}
+
+// transferState returns a transfer's status and locations.
+func transferState(q queryer, transferID int64) (status TransferStatus, from, to int64, err error) {
+	var value string
+	err = q.QueryRow(
+		`SELECT status, from_location_id, to_location_id FROM transfers WHERE id = ?`,
+		transferID,
+	).Scan(&value, &from, &to)
+	return TransferStatus(value), from, to, err
+}
+
+func checkTransferDraft(q queryer, transferID int64) error {
+	status, _, _, err := transferState(q, transferID)
+	if err != nil {
+		return err
+	}
+	if status != TransferDraft {
+		return fmt.Errorf("%w: only draft transfers can be edited", ErrTransferState)
+	}
+	return nil
+}
+
+// AddTransferLine adds qty of a product to a draft. Products tracked by
+// serial number name each unit, which must be in stock at the source.
+func AddTransferLine(db *sql.DB, transferID, productID int64, qty quantity.Quantity, serials []string) (int64, error) {
+	if qty <= 0 {
+		return 0, errors.New("quantity must be greater than zero")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	status, from, _, err := transferState(tx, transferID)
+	if err != nil {
+		return 0, err
+	}
+	if status != TransferDraft {
+		return 0, fmt.Errorf("%w: only draft transfers can be edited", ErrTransferState)
+	}
+	var unit string
+	if err := tx.QueryRow(`SELECT unit FROM products WHERE id = ?`, productID).Scan(&unit); err != nil {
+		return 0, err
+	}
+	if err := checkQuantity(quantity.Unit(unit), qty); err != nil {
+		return 0, err
+	}
+	serials, err = requireSerials(tx, productID, qty, serials)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(
+		`INSERT INTO transfer_lines (transfer_id, product_id, quantity) VALUES (?, ?, ?)`,
+		transferID,
+		productID,
+		qty,
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	for _, serial := range serials {
+		serialID, err := stockedSerial(tx, productID, from, serial)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(
+			`INSERT INTO transfer_line_serials (transfer_line_id, serial_id) VALUES (?, ?)`,
+			id,
+			serialID,
+		); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func RemoveTransferLine(db *sql.DB, transferID, lineID int64) error {
+	if err := checkTransferDraft(db, transferID); err != nil {
+		return err
+	}
+	result, err := db.Exec(`DELETE FROM transfer_lines WHERE id = ? AND transfer_id = ?`, lineID, transferID)
+	if err != nil {
+		return err
+	}
+	if removed, err := result.RowsAffected(); err != nil || removed == 0 {
+		return err
+	}
+	_, err = db.Exec(`DELETE FROM transfer_line_serials WHERE transfer_line_id = ?`, lineID)
+	return err
+}
+
+// DispatchTransfer takes a draft's goods out of the source location. It
+// fails as a whole if any line is short there. Products tracked by lot
+// leave from their lots first to expire first, and each line records the
+// lots it took. Units named by serial number leave with the line and are
+// in transit until received.
+func DispatchTransfer(db *sql.DB, transferID int64, user string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	status, from, _, err := transferState(tx, transferID)
+	if err != nil {
+		return err
+	}
+	if status != TransferDraft {
+		return fmt.Errorf("%w: transfer %d is %s", ErrTransferState, transferID, status)
+	}
+	lines, err := transferLines(tx, transferID)
+	if err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		return errors.New("transfer has no lines")
+	}
+	for _, line := range lines {
+		allocations, err := transferLots(tx, line.ProductID, from, line.Quantity)
+		if err != nil {
+			return err
+		}
+		rest := line.Quantity
+		for _, allocation := range allocations {
+			err := moveStock(tx, StockMovement{
+				ProductID:  line.ProductID,
+				LocationID: from,
+				Kind:       MovementTransfer,
+				Quantity:   -allocation.Quantity,
+				Reference:  fmt.Sprintf("transfer #%d", transferID),
+				User:       user,
+				Lot:        allocation.Lot,
+			})
+			if err != nil {
+				return fmt.Errorf("%s: %w", line.Name, err)
+			}
+			if _, err := tx.Exec(
+				`INSERT INTO transfer_line_lots (transfer_line_id, lot_id, quantity) VALUES (?, ?, ?)`,
+				line.ID,
+				allocation.LotID,
+				allocation.Quantity,
+			); err != nil {
+				return err
+			}
+			rest -= allocation.Quantity
+		}
+		err = moveStock(tx, StockMovement{
+			ProductID:  line.ProductID,
+			LocationID: from,
+			Kind:       MovementTransfer,
+			Quantity:   -rest,
+			Reference:  fmt.Sprintf("transfer #%d", transferID),
+			User:       user,
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", line.Name, err)
+		}
+		if _, err := releaseSerials(tx, line.ProductID, from, line.Serials, SerialInTransit); err != nil {
+			return fmt.Errorf("%s: %w", line.Name, err)
+		}
+	}
+
+	if _, err := tx.Exec(
+		`UPDATE transfers SET status = ?, dispatched_at = ?, dispatched_by = ? WHERE id = ?`,
+		string(TransferDispatched),
+		time.Now(),
+		user,
+		transferID,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReceiveTransfer books a dispatched transfer into the destination. Lines
+// without a receipt arrived in full; receipts record what did arrive of
+// the others, and why. Shortfalls are written off at the destination as
+// adjustments, so they use up their cost. Goods go into the same lots,
+// with the same expiry, as they left; what arrived fills the line's lots
+// in the order they were dispatched.
+func ReceiveTransfer(db *sql.DB, transferID int64, receipts []TransferReceipt, user string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	status, _, to, err := transferState(tx, transferID)
+	if err != nil {
+		return err
+	}
+	if status != TransferDispatched {
+		return fmt.Errorf("%w: transfer %d is %s", ErrTransferState, transferID, status)
+	}
+	lines, err := transferLines(tx, transferID)
+	if err != nil {
+		return err
+	}
+
+	byLine := make(map[int64]TransferReceipt, len(receipts))
+	for _, receipt := range receipts {
+		byLine[receipt.LineID] = receipt
+	}
+	for _, line := range lines {
+		receipt, ok := byLine[line.ID]
+		delete(byLine, line.ID)
+		if !ok {
+			receipt = TransferReceipt{LineID: line.ID, Quantity: line.Quantity}
+		}
+		if receipt.Quantity < 0 {
+			return errors.New("received quantity cannot be negative")
+		}
+		if receipt.Quantity > line.Quantity {
+			return fmt.Errorf("%w: %s had %s dispatched", ErrOverReceipt, line.Name, line.Quantity)
+		}
+		if err := checkQuantity(line.Unit, receipt.Quantity); err != nil {
+			return err
+		}
+		if receipt.Quantity != line.Quantity && receipt.Note == "" {
+			return fmt.Errorf("%s: explain the difference between dispatched and received", line.Name)
+		}
+		arrived, err := arrivedSerials(tx, line, receipt)
+		if err != nil {
+			return fmt.Errorf("%s: %w", line.Name, err)
+		}
+
+		if _, err := tx.Exec(
+			`UPDATE transfer_lines SET received = ?, note = ? WHERE id = ?`,
+			receipt.Quantity,
+			receipt.Note,
+			line.ID,
+		); err != nil {
+			return err
+		}
+		allocations, err := transferLineLots(tx, line.ID)
+		if err != nil {
+			return err
+		}
+		rest, unlotted := receipt.Quantity, line.Quantity
+		for _, allocation := range allocations {
+			in := StockMovement{
+				ProductID:  line.ProductID,
+				LocationID: to,
+				Kind:       MovementTransfer,
+				Quantity:   allocation.Quantity,
+				Reason:     receipt.Note,
+				Reference:  fmt.Sprintf("transfer #%d", transferID),
+				User:       user,
+				Lot:        allocation.Lot,
+				Expiry:     allocation.Expiry,
+			}
+			take := min(allocation.Quantity, rest)
+			if err := receiveTransferred(tx, in, allocation.Quantity-take); err != nil {
+				return err
+			}
+			rest -= take
+			unlotted -= allocation.Quantity
+		}
+		in := StockMovement{
+			ProductID:  line.ProductID,
+			LocationID: to,
+			Kind:       MovementTransfer,
+			Quantity:   unlotted,
+			Reason:     receipt.Note,
+			Reference:  fmt.Sprintf("transfer #%d", transferID),
+			User:       user,
+		}
+		if err := receiveTransferred(tx, in, unlotted-rest); err != nil {
+			return err
+		}
+		// Units that arrived are in stock at the destination; the rest
+		// were lost with the shortfall.
+		if err := receiveSerials(tx, line.ProductID, to, arrived); err != nil {
+			return err
+		}
+		for _, serial := range line.Serials {
+			if slices.Contains(arrived, serial) {
+				continue
+			}
+			if _, err := tx.Exec(
+				`UPDATE serials SET status = ? WHERE product_id = ? AND serial = ?`,
+				string(SerialRemoved),
+				line.ProductID,
+				serial,
+			); err != nil {
+				return err
+			}
+		}
+	}
+	if len(byLine) > 0 {
+		return fmt.Errorf("receipt has lines that do not belong to transfer %d", transferID)
+	}
+
+	if _, err := tx.Exec(
+		`UPDATE transfers SET status = ?, received_at = ?, received_by = ? WHERE id = ?`,
+		string(TransferReceived),
+		time.Now(),
+		user,
+		transferID,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// arrivedSerials returns the units of a line that arrived: all of them
+// when the line arrived in full, and otherwise those the receipt names,
+// which must have left on the line.
+func arrivedSerials(q queryer, line TransferLine, receipt TransferReceipt) ([]string, error) {
+	if len(line.Serials) == 0 || receipt.Quantity == line.Quantity {
+		return line.Serials, nil
+	}
+	arrived, err := requireSerials(q, line.ProductID, receipt.Quantity, receipt.Serials)
+	if err != nil {
+		return nil, err
+	}
+	for i, serial := range arrived {
+		if !slices.Contains(line.Serials, serial) || slices.Contains(arrived[:i], serial) {
+			return nil, fmt.Errorf("serial number %s was not dispatched on this line", serial)
+		}
+	}
+	return arrived, nil
+}
+
+// receiveTransferred books a transfer movement into its destination and
+// writes off the part of it that was lost on the way as an adjustment,
+// which, unlike the transfer, uses up cost layers.
+func receiveTransferred(q queryer, in StockMovement, lost quantity.Quantity) error {
+	if err := moveStock(q, in); err != nil {
+		return err
+	}
+	loss := in
+	loss.Kind = MovementAdjustment
+	loss.Quantity = -lost
+	return moveStock(q, loss)
+}
+
+// CancelTransfer drops a transfer that has not been dispatched.
+func CancelTransfer(db *sql.DB, transferID int64) error {
+	if err := checkTransferDraft(db, transferID); err != nil {
+		return err
+	}
+	_, err := db.Exec(`UPDATE transfers SET status = ? WHERE id = ?`, string(TransferCancelled), transferID)
+	return err
+}
+
+// QuantityInTransit is what of a product has been dispatched on transfers
+// that have not arrived yet.
+func QuantityInTransit(db *sql.DB, productID int64) (quantity.Quantity, error) {
+	var inTransit quantity.Quantity
+	err := db.QueryRow(
+		`SELECT COALESCE(SUM(l.quantity), 0)
+		FROM transfer_lines l JOIN transfers t ON t.id = l.transfer_id
+		WHERE l.product_id = ? AND t.status = ?`,
+		productID,
+		string(TransferDispatched),
+	).Scan(&inTransit)
+	return inTransit, err
+}
@@ -0,0 +1,75 @@
+package tax
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"pos-system/internal/money"
+)
+
+type Mode string
+
+const (
+	Inclusive Mode = "inclusive"
+	Exclusive Mode = "exclusive"
+)
+
+var (
+	ErrInvalidMode = errors.New("invalid tax mode")
+	ErrInvalidRate = errors.New("invalid tax rate")
+)
+
+func ParseMode(value string) (Mode, error) {
+	switch Mode(value) {
+	case Inclusive, Exclusive:
+		return Mode(value), nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidMode, value)
+}
+
+// Rate is a percentage expressed in basis points, so 2000 is 20%.
+type Rate int64
+
+const basisPoints = 10000
+
+// ParseRate reads a percentage such as "20" or "5.5".
+func ParseRate(value string) (Rate, error) {
+	whole, fraction, _ := strings.Cut(strings.TrimSuffix(strings.TrimSpace(value), "%"), ".")
+	if whole == "" || len(fraction) > 2 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidRate, value)
+	}
+	fraction += strings.Repeat("0", 2-len(fraction))
+
+	percent, err := strconv.ParseUint(whole, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidRate, value)
+	}
+	hundredths, err := strconv.ParseUint(fraction, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidRate, value)
+	}
+	return Rate(percent*100 + hundredths), nil
+}
+
+func (r Rate) String() string {
+	return fmt.Sprintf("%d.%02d%%", r/100, r%100)
+}
+
+type Amounts struct {
+	Net   money.Money
+	Tax   money.Money
+	Gross money.Money
+}
+
+// Compute splits a line amount into net, tax and gross. In inclusive mode
+// the amount already contains tax; in exclusive mode tax is added on top.
+func Compute(mode Mode, amount money.Money, rate Rate) Amounts {
+	if mode == Exclusive {
+		tax := amount.MulDiv(int64(rate), basisPoints)
+		return Amounts{Net: amount, Tax: tax, Gross: amount.Add(tax)}
+	}
+	tax := amount.MulDiv(int64(rate), basisPoints+int64(rate))
+	return Amounts{Net: amount.Sub(tax), Tax: tax, Gross: amount}
+}
@@ -0,0 +1,84 @@
+package tax
+
+import (
+	"errors"
+	"testing"
+
+	"pos-system/internal/money"
+)
+
+func TestParseMode(t *testing.T) {
+	for _, mode := range []Mode{Inclusive, Exclusive} {
+		got, err := ParseMode(string(mode))
+		if err != nil || got != mode {
+			t.Errorf("ParseMode(%q) = %q, %v", mode, got, err)
+		}
+	}
+	for _, in := range []string{"", "INCLUSIVE", "gross"} {
+		if _, err := ParseMode(in); !errors.Is(err, ErrInvalidMode) {
+			t.Errorf("ParseMode(%q) error = %v, want ErrInvalidMode", in, err)
+		}
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Rate
+	}{
+		{"20", 2000},
+		{"5.5", 550},
+		{"5.55", 555},
+		{"0", 0},
+		{"17.5%", 1750},
+		{" 7 ", 700},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseRate(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", ".5", "5.555", "-5", "abc", "5.x", "1.2.3"} {
+		if _, err := ParseRate(in); !errors.Is(err, ErrInvalidRate) {
+			t.Errorf("ParseRate(%q) error = %v, want ErrInvalidRate", in, err)
+		}
+	}
+}
+
+func TestRateString(t *testing.T) {
+	if got := Rate(550).String(); got != "5.50%" {
+		t.Errorf("String = %q", got)
+	}
+}
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name            string
+		mode            Mode
+		amount          int64
+		rate            Rate
+		net, tax, gross int64
+	}{
+		{"exclusive", Exclusive, 1000, 2000, 1000, 200, 1200},
+		{"exclusive rounds half up", Exclusive, 25, 2000, 25, 5, 30},
+		{"exclusive rounds down", Exclusive, 22, 2000, 22, 4, 26},
+		{"exclusive fraction rate", Exclusive, 999, 550, 999, 55, 1054},
+		{"inclusive", Inclusive, 1200, 2000, 1000, 200, 1200},
+		{"inclusive rounds", Inclusive, 1000, 2000, 833, 167, 1000},
+		{"inclusive small", Inclusive, 1, 2000, 1, 0, 1},
+		{"zero rate", Inclusive, 1000, 0, 1000, 0, 1000},
+		{"exclusive negative", Exclusive, -25, 2000, -25, -5, -30},
+		{"inclusive negative", Inclusive, -1000, 2000, -833, -167, -1000},
+	}
+	for _, tt := range tests {
+		got := Compute(tt.mode, money.New(tt.amount, "USD"), tt.rate)
+		if got.Net.Amount != tt.net || got.Tax.Amount != tt.tax || got.Gross.Amount != tt.gross {
+			t.Errorf("%s: got net %d tax %d gross %d, want %d %d %d",
+				tt.name, got.Net.Amount, got.Tax.Amount, got.Gross.Amount, tt.net, tt.tax, tt.gross)
+		}
+		if got.Net.Add(got.Tax) != got.Gross {
+			t.Errorf("%s: net + tax != gross", tt.name)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package ui
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"pos-system/internal/store"
+)
+
+var barcodeTypeLabels = map[store.BarcodeType]string{
+	store.BarcodeEAN13:    "EAN-13",
+	store.BarcodeUPCA:     "UPC-A",
+	store.BarcodeInternal: "Internal",
+	store.BarcodePLU:      "PLU",
+}
+
+func showBarcodesDialog(db *sql.DB, window fyne.Window, product store.Product) {
+	var (
+		aliases  []store.BarcodeAlias
+		selected = -1
+	)
+
+	list := widget.NewList(
+		func() int { return len(aliases) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			alias := aliases[id]
+			item.(*widget.Label).SetText(fmt.Sprintf("%s (%s)", alias.Code, barcodeTypeLabels[alias.Type]))
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		selected = id
+	}
+	reload := func() {
+		items, err := store.ListBarcodes(db, product.ID)
+		if err != nil {
+			fmt.Println("Failed to load barcodes:", err)
+			return
+		}
+		aliases = items
+		selected = -1
+		list.UnselectAll()
+		list.Refresh()
+	}
+
+	typeOptions := make([]string, 0, len(store.BarcodeTypes))
+	for _, barcodeType := range store.BarcodeTypes {
+		typeOptions = append(typeOptions, barcodeTypeLabels[barcodeType])
+	}
+	typeSelect := widget.NewSelect(typeOptions, nil)
+	typeSelect.SetSelectedIndex(0)
+	codeEntry := widget.NewEntry()
+	codeEntry.SetPlaceHolder("Code")
+
+	addButton := widget.NewButton("Add", func() {
+		err := store.AddBarcode(db, store.BarcodeAlias{
+			Code:      strings.TrimSpace(codeEntry.Text),
+			ProductID: product.ID,
+			Type:      store.BarcodeTypes[typeSelect.SelectedIndex()],
+		})
+		if err != nil {
+			dialog.NewInformation("Barcode Not Added", err.Error(), window).Show()
+			return
+		}
+		codeEntry.SetText("")
+		reload()
+	})
+	removeButton := widget.NewButton("Remove", func() {
+		if selected < 0 || selected >= len(aliases) {
+			return
+		}
+		if err := store.RemoveBarcode(db, aliases[selected].Code); err != nil {
+			dialog.NewError(err, window).Show()
+			return
+		}
+		reload()
+	})
+
+	primary := widget.NewLabel("Primary barcode: " + product.Barcode)
+	controls := container.NewVBox(
+		container.NewBorder(nil, nil, typeSelect, addButton, codeEntry),
+		removeButton,
+	)
+	content := container.NewBorder(primary, controls, nil, nil, list)
+
+	reload()
+	barcodesDialog := dialog.NewCustom("Barcodes for "+product.Name, "Close", content, window)
+	barcodesDialog.Resize(fyne.NewSize(460, 380))
+	barcodesDialog.Show()
+}
@@ -0,0 +1,157 @@
+package ui
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"pos-system/internal/store"
+)
+
+// categoryOptions lists categories by full path, sorted, for a select. The
+// returned ids line up with the labels.
+func categoryOptions(categories []store.Category) ([]string, []int64) {
+	type option struct {
+		label string
+		id    int64
+	}
+	options := make([]option, 0, len(categories))
+	for _, category := range categories {
+		options = append(options, option{label: store.CategoryPath(categories, category.ID), id: category.ID})
+	}
+	sort.Slice(options, func(i, j int) bool { return options[i].label < options[j].label })
+
+	labels := make([]string, 0, len(options))
+	ids := make([]int64, 0, len(options))
+	for _, option := range options {
+		labels = append(labels, option.label)
+		ids = append(ids, option.id)
+	}
+	return labels, ids
+}
+
+func showCategoriesDialog(db *sql.DB, window fyne.Window, onChanged func()) {
+	var (
+		categories []store.Category
+		labels     []string
+		ids        []int64
+		selected   = -1
+	)
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("Name")
+	parentSelect := widget.NewSelect(nil, nil)
+	parentSelect.PlaceHolder = "(top level)"
+
+	list := widget.NewList(
+		func() int { return len(labels) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(labels[id])
+		},
+	)
+
+	byID := func(id int64) store.Category {
+		for _, category := range categories {
+			if category.ID == id {
+				return category
+			}
+		}
+		return store.Category{}
+	}
+	selectedParent := func() *int64 {
+		index := parentSelect.SelectedIndex()
+		if index <= 0 {
+			return nil
+		}
+		id := ids[index-1]
+		return &id
+	}
+
+	reload := func() {
+		items, err := store.ListCategories(db)
+		if err != nil {
+			fmt.Println("Failed to load categories:", err)
+			return
+		}
+		categories = items
+		labels, ids = categoryOptions(categories)
+		parentSelect.SetOptions(append([]string{"(top level)"}, labels...))
+		parentSelect.ClearSelected()
+		nameEntry.SetText("")
+		selected = -1
+		list.UnselectAll()
+		list.Refresh()
+	}
+
+	list.OnSelected = func(id widget.ListItemID) {
+		selected = id
+		category := byID(ids[id])
+		nameEntry.SetText(category.Name)
+		parentSelect.SetSelectedIndex(0)
+		if category.ParentID != nil {
+			for i, candidate := range ids {
+				if candidate == *category.ParentID {
+					parentSelect.SetSelectedIndex(i + 1)
+				}
+			}
+		}
+	}
+
+	changed := func() {
+		reload()
+		onChanged()
+	}
+
+	addButton := widget.NewButton("Add", func() {
+		_, err := store.CreateCategory(db, store.Category{Name: strings.TrimSpace(nameEntry.Text), ParentID: selectedParent()})
+		if err != nil {
+			dialog.NewInformation("Category Not Added", err.Error(), window).Show()
+			return
+		}
+		changed()
+	})
+	updateButton := widget.NewButton("Update", func() {
+		if selected < 0 || selected >= len(ids) {
+			return
+		}
+		err := store.UpdateCategory(db, store.Category{ID: ids[selected], Name: strings.TrimSpace(nameEntry.Text), ParentID: selectedParent()})
+		if err != nil {
+			dialog.NewInformation("Category Not Updated", err.Error(), window).Show()
+			return
+		}
+		changed()
+	})
+	deleteButton := widget.NewButton("Delete", func() {
+		if selected < 0 || selected >= len(ids) {
+			return
+		}
+		if err := store.DeleteCategory(db, ids[selected]); err != nil {
+			if errors.Is(err, store.ErrCategoryInUse) {
+				dialog.NewInformation("Category In Use", err.Error(), window).Show()
+				return
+			}
+			dialog.NewError(err, window).Show()
+			return
+		}
+		changed()
+	})
+
+	form := widget.NewForm(
+		widget.NewFormItem("Name", nameEntry),
+		widget.NewFormItem("Parent", parentSelect),
+	)
+	content := container.NewBorder(nil, container.NewVBox(form, container.NewHBox(addButton, updateButton, deleteButton)), nil, nil, list)
+
+	reload()
+	categoriesDialog := dialog.NewCustom("Categories", "Close", content, window)
+	categoriesDialog.Resize(fyne.NewSize(480, 440))
+	categoriesDialog.Show()
+}
@@ -3,38 +3,72 @@ package ui
 import (
 	"database/sql"
 	"fmt"
+	"slices"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
+
+	"pos-system/internal/barcode"
+	"pos-system/internal/money"
+	"pos-system/internal/quantity"
+	"pos-system/internal/store"
 )
 
 type CheckoutView struct {
 	Tab        *container.TabItem
+	db         *sql.DB
+	terminal   string
 	active     bool
-	HandleScan func(string)
+	HandleScan func(barcode.Scan)
 	cartByCode map[string]*CartLine
 	cartLines  []*CartLine
+	priced     store.PricedCart
 	totalLabel *widget.Label
 	receipt    *widget.List
+	// customerID is the customer the cart is for, whose price list it is
+	// priced on; nil for none.
+	customerID     *int64
+	customers      []store.Customer
+	customerSelect *widget.Select
 }
 
 type CartLine struct {
 	ProductID int
 	Name      string
 	Barcode   string
-	UnitPrice float64
-	Qty       int
-	Stock     int
+	Unit      quantity.Unit
+	UnitPrice money.Money
+	Qty       quantity.Quantity
+	// Stock is what the terminal's location can sell.
+	Stock quantity.Quantity
+	// PackID marks a pack line; Qty and Stock then count packs.
+	PackID *int64
+	Level  store.StockLevel
+	// Lot is the lot read from a GS1 label, sold instead of the first to expire.
+	Lot string
+	// Serials holds one serial number per unit of products tracked by serial
+	// number.
+	Serials []string
+}
+
+// key identifies a cart line, keeping each lot of a product on its own line.
+func (l *CartLine) key() string {
+	if l.Lot == "" {
+		return l.Barcode
+	}
+	return l.Barcode + "|" + l.Lot
 }
 
-func NewCheckoutTab(db *sql.DB, window fyne.Window, scanner *ScannerService) *CheckoutView {
-	_ = db
-	_ = window
+func NewCheckoutTab(db *sql.DB, window fyne.Window, scanner *ScannerService, terminal string) *CheckoutView {
 	_ = scanner
 
 	view := &CheckoutView{
+		db:         db,
+		terminal:   terminal,
 		cartByCode: make(map[string]*CartLine),
 	}
 
@@ -68,32 +102,82 @@ func NewCheckoutTab(db *sql.DB, window fyne.Window, scanner *ScannerService) *Ch
 			subtract := buttons.Objects[1].(*widget.Button)
 			remove := buttons.Objects[2].(*widget.Button)
 
-			name.SetText(line.Name)
-			unit.SetText(fmt.Sprintf("Unit: %.2f", line.UnitPrice))
-			qty.SetText(fmt.Sprintf("Qty: %d", line.Qty))
-			total.SetText(fmt.Sprintf("Line: %.2f", line.UnitPrice*float64(line.Qty)))
+			text := line.Name + stockWarning(line.Level)
+			if len(line.Serials) > 0 {
+				text += "\nS/N " + strings.Join(line.Serials, ", ")
+			}
+			name.SetText(text)
+			unitPrice := line.UnitPrice
+			if id < len(view.priced.Lines) {
+				unitPrice = pricedUnitPrice(view.priced.Lines[id])
+			}
+			unit.SetText("Unit: " + unitPrice.String())
+			qty.SetText("Qty: " + line.Qty.Format(line.Unit))
+			lineTotal := line.Qty.Times(line.UnitPrice).String()
+			if id < len(view.priced.Lines) {
+				lineTotal = pricedLineText(view.priced.Lines[id])
+			}
+			total.SetText("Line: " + lineTotal)
 
+			// The buttons step by a whole unit; weighed lines keep their fraction.
+			// Each unit of a serial-tracked line needs its own serial number.
 			add.OnTapped = func() {
-				if line.Qty < line.Stock {
-					line.Qty++
-					view.refreshReceiptUI()
+				if line.Qty+quantity.Of(1) > line.Stock {
+					return
+				}
+				if len(line.Serials) > 0 {
+					promptSerial(window, line.Name, barcode.Scan{}, func(serial string) {
+						view.addOrIncrement(&CartLine{Barcode: line.Barcode, Lot: line.Lot, Qty: quantity.Of(1), Serials: []string{serial}})
+					})
+					return
 				}
+				line.Qty += quantity.Of(1)
+				view.refreshReceiptUI()
 			}
 			subtract.OnTapped = func() {
-				if line.Qty > 1 {
-					line.Qty--
+				if line.Qty > quantity.Of(1) {
+					line.Qty -= quantity.Of(1)
+					if len(line.Serials) > 0 {
+						line.Serials = line.Serials[:len(line.Serials)-1]
+					}
 					view.refreshReceiptUI()
 				}
 			}
 			remove.OnTapped = func() {
-				view.removeLine(line.Barcode)
+				view.removeLine(line.key())
 			}
 		},
 	)
 
-	view.totalLabel = widget.NewLabel("Total: 0.00")
+	view.totalLabel = widget.NewLabel(cartTotalsText(store.PricedCart{}))
+
+	view.customerSelect = widget.NewSelect(nil, func(string) {
+		view.customerID = nil
+		if index := view.customerSelect.SelectedIndex() - 1; index >= 0 && index < len(view.customers) {
+			view.customerID = &view.customers[index].ID
+		}
+		view.refreshReceiptUI()
+	})
+	view.loadCustomers()
 
-	leftPane := container.NewBorder(nil, view.totalLabel, nil, nil, view.receipt)
+	parkButton := widget.NewButton("Park Cart", func() {
+		showParkDialog(db, window, view.customerID, view.saleItems(), view.clearCart)
+	})
+	resumeButton := widget.NewButton("Resume Cart", func() {
+		showResumeDialog(db, window, terminal, view.resume)
+	})
+
+	leftPane := container.NewBorder(
+		nil,
+		container.NewVBox(
+			container.NewBorder(nil, nil, widget.NewLabel("Customer"), nil, view.customerSelect),
+			view.totalLabel,
+			container.NewHBox(parkButton, resumeButton),
+		),
+		nil,
+		nil,
+		view.receipt,
+	)
 
 	searchPlaceholder := widget.NewLabel("Scan or search products here.")
 	resultsPlaceholder := widget.NewLabel("Search results will appear here.")
@@ -108,8 +192,35 @@ func NewCheckoutTab(db *sql.DB, window fyne.Window, scanner *ScannerService) *Ch
 	content.SetOffset(0.55)
 
 	view.Tab = container.NewTabItem("Checkout", content)
-	view.HandleScan = func(barcode string) {
-		_ = barcode
+	view.HandleScan = func(scan barcode.Scan) {
+		item, err := store.LookupScan(db, scan)
+		if err == sql.ErrNoRows {
+			dialog.NewInformation("Unknown Barcode", "No product found for barcode: "+scan.Code, window).Show()
+			return
+		}
+		if err != nil {
+			dialog.NewError(err, window).Show()
+			return
+		}
+		if item.Pack != nil {
+			view.addPack(item.Product, *item.Pack, quantity.Of(1), scannedLot(item.Product, scan))
+			return
+		}
+		if item.Quantity > 0 {
+			view.addProduct(item.Product, item.Quantity, scannedLot(item.Product, scan))
+			return
+		}
+		resolveVariant(db, window, item.Product, func(product store.Product) {
+			if product.TrackSerials {
+				promptSerial(window, product.Name, scan, func(serial string) {
+					view.addSerial(product, serial, scannedLot(product, scan))
+				})
+				return
+			}
+			promptQuantity(window, product, func(qty quantity.Quantity) {
+				view.addProduct(product, qty, scannedLot(product, scan))
+			})
+		})
 	}
 
 	return view
@@ -117,27 +228,130 @@ func NewCheckoutTab(db *sql.DB, window fyne.Window, scanner *ScannerService) *Ch
 
 func (c *CheckoutView) SetActive(active bool) {
 	c.active = active
+	if active {
+		c.loadCustomers()
+	}
+}
+
+// loadCustomers refreshes the customers the cart can be for, keeping the
+// one chosen.
+func (c *CheckoutView) loadCustomers() {
+	customers, err := store.ListCustomers(c.db)
+	if err != nil {
+		fmt.Println("Failed to load customers:", err)
+		return
+	}
+	c.customers = customers
+	options := []string{"No customer"}
+	for _, customer := range customers {
+		options = append(options, fmt.Sprintf("%d - %s", customer.ID, customer.Name))
+	}
+	c.customerSelect.SetOptions(options)
+	c.selectCustomer(c.customerID)
+}
+
+// selectCustomer attaches a customer to the cart, nil for none.
+func (c *CheckoutView) selectCustomer(id *int64) {
+	index := 0
+	for i, customer := range c.customers {
+		if id != nil && customer.ID == *id {
+			index = i + 1
+		}
+	}
+	c.customerSelect.SetSelectedIndex(index)
 }
 
+// stockHere is what the terminal's location can sell of a product, from
+// the named lot when there is one.
+func (c *CheckoutView) stockHere(product store.Product, lot string) quantity.Quantity {
+	location, err := store.TerminalLocation(c.db, c.terminal)
+	if err != nil {
+		fmt.Println("Failed to load terminal location:", err)
+		return 0
+	}
+	stock, err := store.SellableStock(c.db, product.ID, location.ID, lot)
+	if err != nil {
+		fmt.Println("Failed to load stock:", err)
+		return 0
+	}
+	return stock
+}
+
+func (c *CheckoutView) addProduct(product store.Product, qty quantity.Quantity, lot string) {
+	c.addOrIncrement(&CartLine{
+		ProductID: int(product.ID),
+		Name:      lotLabel(product.Name, lot),
+		Barcode:   product.Barcode,
+		Unit:      product.Unit,
+		UnitPrice: product.Price,
+		Qty:       qty,
+		Stock:     c.stockHere(product, lot),
+		Level:     product.StockLevel(),
+		Lot:       lot,
+	})
+}
+
+// addSerial adds one unit of a product tracked by serial number.
+func (c *CheckoutView) addSerial(product store.Product, serial, lot string) {
+	c.addOrIncrement(&CartLine{
+		ProductID: int(product.ID),
+		Name:      lotLabel(product.Name, lot),
+		Barcode:   product.Barcode,
+		Unit:      product.Unit,
+		UnitPrice: product.Price,
+		Qty:       quantity.Of(1),
+		Stock:     c.stockHere(product, lot),
+		Level:     product.StockLevel(),
+		Lot:       lot,
+		Serials:   []string{serial},
+	})
+}
+
+func (c *CheckoutView) addPack(product store.Product, pack store.Pack, packs quantity.Quantity, lot string) {
+	c.addOrIncrement(&CartLine{
+		ProductID: int(product.ID),
+		Name:      lotLabel(packLabel(product, pack), lot),
+		Barcode:   pack.Barcode,
+		Unit:      quantity.Each,
+		UnitPrice: pack.PriceFor(product.Price),
+		Qty:       packs,
+		Stock:     pack.WholePacks(c.stockHere(product, lot)),
+		PackID:    &pack.ID,
+		Level:     product.StockLevel(),
+		Lot:       lot,
+	})
+}
+
+// addOrIncrement adds line to the cart, or its quantity to the line already
+// there for the same product, as far as stock allows. Serial numbers already
+// in the cart are not added again.
 func (c *CheckoutView) addOrIncrement(line *CartLine) {
-	if existing, ok := c.cartByCode[line.Barcode]; ok {
-		if existing.Qty < existing.Stock {
-			existing.Qty++
+	if existing, ok := c.cartByCode[line.key()]; ok {
+		if len(line.Serials) == 0 {
+			existing.Qty = min(existing.Qty+line.Qty, existing.Stock)
+		}
+		for _, serial := range line.Serials {
+			if slices.Contains(existing.Serials, serial) || existing.Qty+quantity.Of(1) > existing.Stock {
+				continue
+			}
+			existing.Serials = append(existing.Serials, serial)
+			existing.Qty += quantity.Of(1)
 		}
 		c.refreshReceiptUI()
 		return
 	}
-	c.cartByCode[line.Barcode] = line
+	line.Qty = min(line.Qty, line.Stock)
+	c.cartByCode[line.key()] = line
 	c.cartLines = append(c.cartLines, line)
 	c.refreshReceiptUI()
 }
 
-func (c *CheckoutView) removeLine(barcode string) {
-	line, ok := c.cartByCode[barcode]
+func (c *CheckoutView) removeLine(key string) {
+	line, ok := c.cartByCode[key]
 	if !ok {
 		return
 	}
-	delete(c.cartByCode, barcode)
+	delete(c.cartByCode, key)
 	for i, existing := range c.cartLines {
 		if existing == line {
 			c.cartLines = append(c.cartLines[:i], c.cartLines[i+1:]...)
@@ -147,17 +361,85 @@ func (c *CheckoutView) removeLine(barcode string) {
 	c.refreshReceiptUI()
 }
 
-func (c *CheckoutView) recomputeTotal() float64 {
-	var total float64
+func (c *CheckoutView) clearCart() {
+	c.cartByCode = make(map[string]*CartLine)
+	c.cartLines = nil
+	c.priced = store.PricedCart{}
+	c.selectCustomer(nil)
+	c.refreshReceiptUI()
+}
+
+// resume replaces the cart with a parked one. Stock was re-checked when it
+// was taken off the shelf, so quantities are already within limits.
+func (c *CheckoutView) resume(sale store.SuspendedSale) {
+	c.cartByCode = make(map[string]*CartLine)
+	c.cartLines = nil
+	for _, item := range sale.Items {
+		product, err := store.GetProduct(c.db, item.ProductID)
+		if err != nil {
+			fmt.Println("Failed to load product:", err)
+			continue
+		}
+		if item.PackID != nil {
+			pack, err := store.GetPack(c.db, *item.PackID)
+			if err != nil {
+				fmt.Println("Failed to load pack:", err)
+				continue
+			}
+			c.addPack(product, pack, item.Quantity, item.Lot)
+			continue
+		}
+		line := &CartLine{
+			ProductID: int(product.ID),
+			Name:      lotLabel(product.Name, item.Lot),
+			Barcode:   product.Barcode,
+			Unit:      product.Unit,
+			UnitPrice: product.Price,
+			Qty:       item.Quantity,
+			Stock:     c.stockHere(product, item.Lot),
+			Level:     product.StockLevel(),
+			Lot:       item.Lot,
+			Serials:   item.Serials,
+		}
+		if existing, ok := c.cartByCode[line.key()]; ok {
+			existing.Qty += item.Quantity
+			existing.Serials = append(existing.Serials, item.Serials...)
+			continue
+		}
+		c.cartByCode[line.key()] = line
+		c.cartLines = append(c.cartLines, line)
+	}
+	c.selectCustomer(sale.CustomerID)
+	c.refreshReceiptUI()
+}
+
+func (c *CheckoutView) saleItems() []store.SaleItem {
+	items := make([]store.SaleItem, 0, len(c.cartLines))
 	for _, line := range c.cartLines {
-		total += float64(line.Qty) * line.UnitPrice
+		items = append(items, store.SaleItem{
+			ProductID: int64(line.ProductID),
+			Quantity:  line.Qty,
+			PackID:    line.PackID,
+			Lot:       line.Lot,
+			Serials:   line.Serials,
+		})
 	}
-	return total
+	return items
+}
+
+func (c *CheckoutView) recomputeTotal() (store.PricedCart, error) {
+	return store.PriceCart(c.db, c.customerID, c.saleItems())
 }
 
 func (c *CheckoutView) refreshReceiptUI() {
 	if c.totalLabel != nil {
-		c.totalLabel.SetText(fmt.Sprintf("Total: %.2f", c.recomputeTotal()))
+		cart, err := c.recomputeTotal()
+		if err != nil {
+			fmt.Println("Failed to price cart:", err)
+		} else {
+			c.priced = cart
+			c.totalLabel.SetText(cartTotalsText(cart))
+		}
 	}
 	if c.receipt != nil {
 		c.receipt.Refresh()
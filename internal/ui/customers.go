@@ -14,19 +14,63 @@ import (
 )
 
 type customerForm struct {
-	name  *widget.Entry
-	email *widget.Entry
-	phone *widget.Entry
+	name      *widget.Entry
+	email     *widget.Entry
+	phone     *widget.Entry
+	priceList *widget.Select
 }
 
-func CustomersTab(db *sql.DB) fyne.CanvasObject {
-	var customers []store.Customer
+type CustomersView struct {
+	Tab     *container.TabItem
+	Refresh func()
+}
+
+func NewCustomersTab(db *sql.DB) *CustomersView {
+	var (
+		customers  []store.Customer
+		priceLists []store.PriceList
+	)
 	selectedIndex := -1
 
 	form := customerForm{
-		name:  widget.NewEntry(),
-		email: widget.NewEntry(),
-		phone: widget.NewEntry(),
+		name:      widget.NewEntry(),
+		email:     widget.NewEntry(),
+		phone:     widget.NewEntry(),
+		priceList: widget.NewSelect(nil, nil),
+	}
+
+	// The first price list option is product prices, for customers on none.
+	selectPriceList := func(id *int64) {
+		form.priceList.SetSelectedIndex(0)
+		for i, priceList := range priceLists {
+			if id != nil && priceList.ID == *id {
+				form.priceList.SetSelectedIndex(i + 1)
+			}
+		}
+	}
+	selectedPriceList := func() *int64 {
+		index := form.priceList.SelectedIndex() - 1
+		if index < 0 || index >= len(priceLists) {
+			return nil
+		}
+		return &priceLists[index].ID
+	}
+
+	// loadPriceLists refreshes the price lists offered, keeping the one chosen.
+	loadPriceLists := func() {
+		lists, err := store.ListPriceLists(db)
+		if err != nil {
+			fmt.Println("Failed to load price lists:", err)
+			return
+		}
+		chosen := selectedPriceList()
+		priceLists = lists
+		options := []string{"Product prices"}
+		for _, priceList := range priceLists {
+			options = append(options, priceList.Name)
+		}
+		form.priceList.SetOptions(options)
+		selectPriceList(chosen)
 	}
 
 	refresh := func(list *widget.List) {
@@ -36,10 +80,12 @@ func CustomersTab(db *sql.DB) fyne.CanvasObject {
 			return
 		}
 		customers = items
+		loadPriceLists()
 		selectedIndex = -1
 		form.name.SetText("")
 		form.email.SetText("")
 		form.phone.SetText("")
+		selectPriceList(nil)
 		list.Refresh()
 	}
 
@@ -48,7 +94,11 @@ func CustomersTab(db *sql.DB) fyne.CanvasObject {
 		func() fyne.CanvasObject { return widget.NewLabel("") },
 		func(id widget.ListItemID, item fyne.CanvasObject) {
 			customer := customers[id]
-			item.(*widget.Label).SetText(fmt.Sprintf("%d - %s", customer.ID, customer.Name))
+			text := fmt.Sprintf("%d - %s", customer.ID, customer.Name)
+			if !customer.StoreCredit.IsZero() {
+				text += fmt.Sprintf(" (credit %s)", customer.StoreCredit)
+			}
+			item.(*widget.Label).SetText(text)
 		},
 	)
 
@@ -57,9 +107,10 @@ func CustomersTab(db *sql.DB) fyne.CanvasObject {
 			return
 		}
 		_, err := store.CreateCustomer(db, store.Customer{
-			Name:  form.name.Text,
-			Email: form.email.Text,
-			Phone: form.phone.Text,
+			Name:        form.name.Text,
+			Email:       form.email.Text,
+			Phone:       form.phone.Text,
+			PriceListID: selectedPriceList(),
 		})
 		if err != nil {
 			fmt.Println("Failed to create customer:", err)
@@ -76,6 +127,7 @@ func CustomersTab(db *sql.DB) fyne.CanvasObject {
 		customer.Name = form.name.Text
 		customer.Email = form.email.Text
 		customer.Phone = form.phone.Text
+		customer.PriceListID = selectedPriceList()
 		if err := store.UpdateCustomer(db, customer); err != nil {
 			fmt.Println("Failed to update customer:", err)
 			return
@@ -98,6 +150,7 @@ func CustomersTab(db *sql.DB) fyne.CanvasObject {
 		{Text: "Name", Widget: form.name},
 		{Text: "Email", Widget: form.email},
 		{Text: "Phone", Widget: form.phone},
+		{Text: "Price list", Widget: form.priceList},
 	}
 	formWidget := widget.NewForm(formItems...)
 
@@ -111,15 +164,20 @@ func CustomersTab(db *sql.DB) fyne.CanvasObject {
 		form.name.SetText(customer.Name)
 		form.email.SetText(customer.Email)
 		form.phone.SetText(customer.Phone)
+		selectPriceList(customer.PriceListID)
 		footer.SetText("Selected ID: " + strconv.FormatInt(customer.ID, 10))
 	}
 
 	refresh(list)
 
-	return container.NewBorder(nil, footer, nil, nil,
+	content := container.NewBorder(nil, footer, nil, nil,
 		container.NewHSplit(
 			container.NewBorder(nil, nil, nil, nil, list),
 			container.NewVBox(controls, layout.NewSpacer()),
 		),
 	)
+	return &CustomersView{
+		Tab:     container.NewTabItem("Customers", content),
+		Refresh: loadPriceLists,
+	}
 }
@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"database/sql"
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"pos-system/internal/store"
+)
+
+func locationOptions(locations []store.Location) []string {
+	options := make([]string, 0, len(locations))
+	for _, location := range locations {
+		options = append(options, location.Name)
+	}
+	return options
+}
+
+// selectLocation picks id in a select built from locationOptions, falling
+// back to the first location.
+func selectLocation(choice *widget.Select, locations []store.Location, id int64) {
+	choice.SetSelectedIndex(0)
+	for i, location := range locations {
+		if location.ID == id {
+			choice.SetSelectedIndex(i)
+		}
+	}
+}
+
+func selectedLocation(choice *widget.Select, locations []store.Location) int64 {
+	index := choice.SelectedIndex()
+	if index < 0 || index >= len(locations) {
+		return store.DefaultLocationID
+	}
+	return locations[index].ID
+}
+
+// locationSettings manages stock locations and the one this terminal
+// sells from.
+func locationSettings(db *sql.DB, terminal string) fyne.CanvasObject {
+	var locations []store.Location
+
+	locationSelect := widget.NewSelect(nil, nil)
+	name := widget.NewEntry()
+	name.SetPlaceHolder("Location name")
+	sellingLabel := widget.NewLabel("")
+
+	refresh := func() {
+		items, err := store.ListLocations(db)
+		if err != nil {
+			fmt.Println("Failed to load locations:", err)
+			return
+		}
+		selling, err := store.TerminalLocation(db, terminal)
+		if err != nil {
+			fmt.Println("Failed to load terminal location:", err)
+			return
+		}
+		locations = items
+		locationSelect.SetOptions(locationOptions(locations))
+		selectLocation(locationSelect, locations, selling.ID)
+		sellingLabel.SetText(fmt.Sprintf("Terminal %s sells from %s", terminal, selling.Name))
+	}
+	locationSelect.OnChanged = func(string) {
+		index := locationSelect.SelectedIndex()
+		if index >= 0 && index < len(locations) {
+			name.SetText(locations[index].Name)
+		}
+	}
+
+	addButton := widget.NewButton("Add", func() {
+		if _, err := store.CreateLocation(db, name.Text); err != nil {
+			fmt.Println("Failed to create location:", err)
+			return
+		}
+		refresh()
+	})
+	renameButton := widget.NewButton("Rename", func() {
+		if err := store.RenameLocation(db, selectedLocation(locationSelect, locations), name.Text); err != nil {
+			fmt.Println("Failed to rename location:", err)
+			return
+		}
+		refresh()
+	})
+	deleteButton := widget.NewButton("Delete", func() {
+		if err := store.DeleteLocation(db, selectedLocation(locationSelect, locations)); err != nil {
+			fmt.Println("Failed to delete location:", err)
+			return
+		}
+		refresh()
+	})
+	sellButton := widget.NewButton("Sell From Here", func() {
+		if err := store.SetTerminalLocation(db, terminal, selectedLocation(locationSelect, locations)); err != nil {
+			fmt.Println("Failed to set terminal location:", err)
+			return
+		}
+		refresh()
+	})
+
+	refresh()
+
+	return container.NewVBox(
+		widget.NewLabel("Locations"),
+		locationSelect,
+		name,
+		container.NewHBox(addButton, renameButton, deleteButton, sellButton),
+		sellingLabel,
+	)
+}
@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"pos-system/internal/barcode"
+	"pos-system/internal/store"
+)
+
+// scannedLot is the lot read from a GS1 label, kept only for products
+// tracked by lot.
+func scannedLot(product store.Product, scan barcode.Scan) string {
+	if product.TrackLots {
+		return scan.Batch
+	}
+	return ""
+}
+
+func lotLabel(name, lot string) string {
+	if lot == "" {
+		return name
+	}
+	return name + " lot " + lot
+}
+
+func expiryText(expiry *time.Time) string {
+	if expiry == nil {
+		return ""
+	}
+	return " exp " + expiry.Format(time.DateOnly)
+}
+
+// parseOptionalDate reads a YYYY-MM-DD date, nil when left empty.
+func parseOptionalDate(text string) (*time.Time, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+	date, err := time.ParseInLocation(time.DateOnly, text, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
+
+func lotText(lot store.Lot, now time.Time) string {
+	text := fmt.Sprintf("%s at %s: lot %s%s, %s", lot.Name, lot.Location, lot.Number, expiryText(lot.Expiry), lot.Quantity.Format(lot.Unit))
+	if lot.Expired(now) {
+		text += "  [expired]"
+	}
+	return text
+}
+
+func showLotsDialog(db *sql.DB, window fyne.Window, product store.Product) {
+	lots, err := store.ListLots(db, product.ID)
+	if err != nil {
+		dialog.NewError(err, window).Show()
+		return
+	}
+	if len(lots) == 0 {
+		dialog.NewInformation("Lots", product.Name+" has no lots in stock.", window).Show()
+		return
+	}
+	now := time.Now()
+	list := container.NewVBox()
+	for _, lot := range lots {
+		list.Add(widget.NewLabel(lotText(lot, now)))
+	}
+	lotsDialog := dialog.NewCustom("Lots of "+product.Name, "Close", container.NewVScroll(list), window)
+	lotsDialog.Resize(fyne.NewSize(560, 360))
+	lotsDialog.Show()
+}
+
+// showNearExpiryDialog lists lots expiring within a number of days, and
+// those already expired.
+func showNearExpiryDialog(db *sql.DB, window fyne.Window) {
+	daysEntry := widget.NewEntry()
+	daysEntry.SetText("30")
+	list := container.NewVBox()
+
+	load := func() {
+		days, err := strconv.Atoi(strings.TrimSpace(daysEntry.Text))
+		if err != nil {
+			dialog.NewInformation("Invalid Days", "Days must be a whole number.", window).Show()
+			return
+		}
+		lots, err := store.NearExpiryReport(db, days)
+		if err != nil {
+			dialog.NewError(err, window).Show()
+			return
+		}
+		list.RemoveAll()
+		if len(lots) == 0 {
+			list.Add(widget.NewLabel("No lots expire in that time."))
+		}
+		now := time.Now()
+		for _, lot := range lots {
+			list.Add(widget.NewLabel(lotText(lot, now)))
+		}
+	}
+	load()
+
+	content := container.NewBorder(
+		container.NewBorder(nil, nil, widget.NewLabel("Expiring within days:"), widget.NewButton("Show", load), daysEntry),
+		nil, nil, nil,
+		container.NewVScroll(list),
+	)
+	expiryDialog := dialog.NewCustom("Near Expiry", "Close", content, window)
+	expiryDialog.Resize(fyne.NewSize(640, 420))
+	expiryDialog.Show()
+}
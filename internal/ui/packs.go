@@ -0,0 +1,119 @@
+package ui
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"pos-system/internal/money"
+	"pos-system/internal/quantity"
+	"pos-system/internal/store"
+)
+
+func showPacksDialog(db *sql.DB, window fyne.Window, product store.Product) {
+	var (
+		packs    []store.Pack
+		selected = -1
+	)
+
+	list := widget.NewList(
+		func() int { return len(packs) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			pack := packs[id]
+			price := pack.PriceFor(product.Price).String()
+			if pack.Price == nil {
+				price += " (from unit price)"
+			}
+			item.(*widget.Label).SetText(fmt.Sprintf(
+				"%s | %s | %s | %s", pack.Name, pack.Barcode, pack.Factor.Format(product.Unit), price,
+			))
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		selected = id
+	}
+	reload := func() {
+		items, err := store.ListPacks(db, product.ID)
+		if err != nil {
+			fmt.Println("Failed to load packs:", err)
+			return
+		}
+		packs = items
+		selected = -1
+		list.UnselectAll()
+		list.Refresh()
+	}
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("Case of 24")
+	barcodeEntry := widget.NewEntry()
+	factorEntry := widget.NewEntry()
+	factorEntry.SetPlaceHolder("Units per pack")
+	priceEntry := widget.NewEntry()
+	priceEntry.SetPlaceHolder("Blank to charge the unit price")
+
+	addButton := widget.NewButton("Add Pack", func() {
+		factor, err := quantity.Parse(factorEntry.Text)
+		if err != nil {
+			dialog.NewInformation("Invalid Pack Size", "Enter how many units one pack holds.", window).Show()
+			return
+		}
+		pack := store.Pack{
+			ProductID: product.ID,
+			Name:      strings.TrimSpace(nameEntry.Text),
+			Barcode:   strings.TrimSpace(barcodeEntry.Text),
+			Factor:    factor,
+		}
+		if text := strings.TrimSpace(priceEntry.Text); text != "" {
+			price, err := money.Parse(text, product.Price.Currency)
+			if err != nil {
+				dialog.NewInformation("Invalid Price", err.Error(), window).Show()
+				return
+			}
+			pack.Price = &price
+		}
+		if _, err := store.CreatePack(db, pack); err != nil {
+			dialog.NewInformation("Pack Not Added", err.Error(), window).Show()
+			return
+		}
+		nameEntry.SetText("")
+		barcodeEntry.SetText("")
+		factorEntry.SetText("")
+		priceEntry.SetText("")
+		reload()
+	})
+	removeButton := widget.NewButton("Remove", func() {
+		if selected < 0 || selected >= len(packs) {
+			return
+		}
+		if err := store.DeletePack(db, packs[selected].ID); err != nil {
+			dialog.NewError(err, window).Show()
+			return
+		}
+		reload()
+	})
+
+	form := widget.NewForm(
+		widget.NewFormItem("Name", nameEntry),
+		widget.NewFormItem("Barcode", barcodeEntry),
+		widget.NewFormItem("Pack Size", factorEntry),
+		widget.NewFormItem("Price", priceEntry),
+	)
+	content := container.NewBorder(nil, container.NewVBox(form, container.NewHBox(addButton, removeButton)), nil, nil, list)
+
+	reload()
+	packsDialog := dialog.NewCustom("Packs of "+product.Name, "Close", content, window)
+	packsDialog.Resize(fyne.NewSize(560, 440))
+	packsDialog.Show()
+}
+
+// packLabel names a pack line: "Cola - Case of 24".
+func packLabel(product store.Product, pack store.Pack) string {
+	return product.Name + " - " + pack.Name
+}
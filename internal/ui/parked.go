@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"pos-system/internal/store"
+)
+
+// showParkDialog asks for a label and parks items under it.
+func showParkDialog(db *sql.DB, window fyne.Window, customerID *int64, items []store.SaleItem, onParked func()) {
+	if len(items) == 0 {
+		dialog.NewInformation("Empty Cart", "There is nothing to park.", window).Show()
+		return
+	}
+	labelEntry := widget.NewEntry()
+	labelEntry.SetPlaceHolder("e.g. customer name or basket colour")
+	formItems := []*widget.FormItem{widget.NewFormItem("Label", labelEntry)}
+	dialog.ShowForm("Park Cart", "Park", "Cancel", formItems, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		label := strings.TrimSpace(labelEntry.Text)
+		if label == "" {
+			label = "Parked " + time.Now().Format("15:04")
+		}
+		if _, err := store.SuspendSale(db, label, customerID, items); err != nil {
+			dialog.NewError(err, window).Show()
+			return
+		}
+		onParked()
+	}, window)
+}
+
+// showResumeDialog lists parked carts and resumes the one picked. Stock is
+// re-checked at the terminal's location, and any shortages are shown before
+// onResume runs.
+func showResumeDialog(db *sql.DB, window fyne.Window, terminal string, onResume func(store.SuspendedSale)) {
+	parked, err := store.ListSuspendedSales(db)
+	if err != nil {
+		dialog.NewError(err, window).Show()
+		return
+	}
+	if len(parked) == 0 {
+		dialog.NewInformation("No Parked Carts", "There are no parked carts.", window).Show()
+		return
+	}
+
+	var resumeDialog dialog.Dialog
+	list := widget.NewList(
+		func() int { return len(parked) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			sale := parked[id]
+			item.(*widget.Label).SetText(fmt.Sprintf(
+				"%s - %d lines, parked %s", sale.Label, len(sale.Items), sale.CreatedAt.Format("Jan 2 15:04"),
+			))
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		resumeDialog.Hide()
+		location, err := store.TerminalLocation(db, terminal)
+		if err != nil {
+			dialog.NewError(err, window).Show()
+			return
+		}
+		sale, shortages, err := store.ResumeSale(db, parked[id].ID, location.ID)
+		if err == sql.ErrNoRows {
+			dialog.NewInformation("Already Resumed", "That cart was resumed on another terminal.", window).Show()
+			return
+		}
+		if err != nil {
+			dialog.NewError(err, window).Show()
+			return
+		}
+		if len(shortages) > 0 {
+			dialog.NewInformation("Stock Changed", shortageText(shortages), window).Show()
+		}
+		onResume(sale)
+	}
+
+	resumeDialog = dialog.NewCustom("Resume Cart", "Cancel", list, window)
+	resumeDialog.Resize(fyne.NewSize(420, 360))
+	resumeDialog.Show()
+}
+
+func shortageText(shortages []store.StockShortage) string {
+	var b strings.Builder
+	b.WriteString("Some items no longer have enough stock:\n")
+	for _, shortage := range shortages {
+		name := shortage.Name
+		if name == "" {
+			name = fmt.Sprintf("Product %d (deleted)", shortage.ProductID)
+		}
+		fmt.Fprintf(&b, "\n%s: wanted %s, %s available", name, shortage.Requested, shortage.Available)
+	}
+	return b.String()
+}
@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"pos-system/internal/money"
+	"pos-system/internal/store"
+)
+
+var tenderLabels = map[store.TenderType]string{
+	store.TenderCash:        "Cash",
+	store.TenderCard:        "Card",
+	store.TenderVoucher:     "Voucher",
+	store.TenderStoreCredit: "Store credit",
+}
+
+// showPaymentDialog collects one or more tenders for total and hands them to
+// onComplete, which commits the sale. The dialog stays open if it fails.
+func showPaymentDialog(window fyne.Window, total money.Money, onComplete func([]store.Tender) error) {
+	var tenders []store.Tender
+
+	tenderOptions := make([]string, 0, len(store.TenderTypes))
+	for _, tenderType := range store.TenderTypes {
+		tenderOptions = append(tenderOptions, tenderLabels[tenderType])
+	}
+	tenderSelect := widget.NewSelect(tenderOptions, nil)
+	tenderSelect.SetSelectedIndex(0)
+	amountEntry := widget.NewEntry()
+	referenceEntry := widget.NewEntry()
+	referenceEntry.SetPlaceHolder("Card slip / voucher code")
+	balanceLabel := widget.NewLabel("")
+
+	paid := func() money.Money {
+		sum := money.Zero(total.Currency)
+		for _, tender := range tenders {
+			sum = sum.Add(tender.Amount)
+		}
+		return sum
+	}
+
+	list := widget.NewList(
+		func() int { return len(tenders) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			tender := tenders[id]
+			text := fmt.Sprintf("%s %s", tenderLabels[tender.Type], tender.Amount)
+			if tender.Reference != "" {
+				text += " (" + tender.Reference + ")"
+			}
+			item.(*widget.Label).SetText(text)
+		},
+	)
+
+	updateBalance := func() {
+		remaining := total.Sub(paid())
+		if remaining.Amount > 0 {
+			balanceLabel.SetText(fmt.Sprintf("Total: %s  Remaining: %s", total, remaining))
+			amountEntry.SetText(remaining.String())
+		} else {
+			balanceLabel.SetText(fmt.Sprintf("Total: %s  Change due: %s", total, remaining.Neg()))
+			amountEntry.SetText("")
+		}
+		list.Refresh()
+	}
+
+	addButton := widget.NewButton("Add Tender", func() {
+		amount, err := money.Parse(amountEntry.Text, total.Currency)
+		if err != nil || amount.Amount <= 0 {
+			dialog.NewInformation("Invalid Amount", "Enter a positive amount.", window).Show()
+			return
+		}
+		tenders = append(tenders, store.Tender{
+			Type:      store.TenderTypes[tenderSelect.SelectedIndex()],
+			Amount:    amount,
+			Reference: referenceEntry.Text,
+		})
+		referenceEntry.SetText("")
+		updateBalance()
+	})
+
+	clearButton := widget.NewButton("Clear Tenders", func() {
+		tenders = nil
+		updateBalance()
+	})
+
+	var paymentDialog dialog.Dialog
+	completeButton := widget.NewButton("Complete Sale", func() {
+		if _, err := store.SettleTenders(total, tenders); err != nil {
+			dialog.NewInformation("Payment Incomplete", err.Error(), window).Show()
+			return
+		}
+		if err := onComplete(tenders); err != nil {
+			return
+		}
+		paymentDialog.Hide()
+	})
+
+	form := widget.NewForm(
+		&widget.FormItem{Text: "Tender", Widget: tenderSelect},
+		&widget.FormItem{Text: "Amount", Widget: amountEntry},
+		&widget.FormItem{Text: "Reference", Widget: referenceEntry},
+	)
+	content := container.NewBorder(
+		container.NewVBox(balanceLabel, form, container.NewHBox(addButton, clearButton)),
+		completeButton,
+		nil,
+		nil,
+		list,
+	)
+
+	updateBalance()
+	paymentDialog = dialog.NewCustom("Payment", "Cancel", content, window)
+	paymentDialog.Resize(fyne.NewSize(420, 420))
+	paymentDialog.Show()
+}
@@ -0,0 +1,266 @@
+package ui
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+
+	"pos-system/internal/money"
+	"pos-system/internal/quantity"
+	"pos-system/internal/store"
+)
+
+type PriceListsView struct {
+	Tab     *container.TabItem
+	Refresh func()
+}
+
+func NewPriceListsTab(db *sql.DB, window fyne.Window) *PriceListsView {
+	var (
+		priceLists    []store.PriceList
+		prices        []store.ListPrice
+		products      []store.Product
+		selectedList  = -1
+		selectedPrice = -1
+	)
+	view := &PriceListsView{}
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("Price list name, such as Wholesale")
+	productSelect := widget.NewSelect(nil, nil)
+	minEntry := widget.NewEntry()
+	minEntry.SetPlaceHolder("0 for any quantity")
+	priceEntry := widget.NewEntry()
+	priceEntry.SetPlaceHolder("Unit price")
+
+	currentList := func() (store.PriceList, bool) {
+		if selectedList < 0 || selectedList >= len(priceLists) {
+			return store.PriceList{}, false
+		}
+		return priceLists[selectedList], true
+	}
+
+	priceList := widget.NewList(
+		func() int { return len(prices) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			price := prices[id]
+			text := fmt.Sprintf("%s: %s", price.Name, price.Price)
+			if price.MinQuantity > 0 {
+				text += fmt.Sprintf(" from %s", price.MinQuantity.Format(price.Unit))
+			}
+			item.(*widget.Label).SetText(text)
+		},
+	)
+	priceList.OnSelected = func(id widget.ListItemID) {
+		selectedPrice = id
+		price := prices[id]
+		for i, product := range products {
+			if product.ID == price.ProductID {
+				productSelect.SetSelectedIndex(i)
+			}
+		}
+		minEntry.SetText(price.MinQuantity.String())
+		priceEntry.SetText(price.Price.String())
+	}
+
+	loadPrices := func() {
+		selectedPrice = -1
+		prices = nil
+		priceList.UnselectAll()
+		if list, ok := currentList(); ok {
+			items, err := store.ListPrices(db, list.ID)
+			if err != nil {
+				fmt.Println("Failed to load prices:", err)
+				return
+			}
+			prices = items
+		}
+		priceList.Refresh()
+	}
+
+	listList := widget.NewList(
+		func() int { return len(priceLists) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(priceLists[id].Name)
+		},
+	)
+	listList.OnSelected = func(id widget.ListItemID) {
+		selectedList = id
+		nameEntry.SetText(priceLists[id].Name)
+		loadPrices()
+	}
+
+	// reload keeps the same price list selected after the lists change.
+	reload := func() {
+		selectedID := int64(0)
+		if list, ok := currentList(); ok {
+			selectedID = list.ID
+		}
+		items, err := store.ListPriceLists(db)
+		if err != nil {
+			fmt.Println("Failed to load price lists:", err)
+			return
+		}
+		priceLists = items
+		selectedList = -1
+		for i, list := range priceLists {
+			if list.ID == selectedID {
+				selectedList = i
+			}
+		}
+		listList.Refresh()
+		if selectedList >= 0 {
+			listList.Select(selectedList)
+		} else {
+			listList.UnselectAll()
+		}
+		loadPrices()
+	}
+
+	view.Refresh = func() {
+		items, err := store.ListProducts(db)
+		if err != nil {
+			fmt.Println("Failed to load products:", err)
+			return
+		}
+		products = items
+		options := make([]string, 0, len(products))
+		for _, product := range products {
+			options = append(options, fmt.Sprintf("%d - %s", product.ID, product.Name))
+		}
+		productSelect.SetOptions(options)
+		reload()
+	}
+
+	showError := func(err error) {
+		dialog.NewInformation("Price List", err.Error(), window).Show()
+	}
+
+	addListButton := widget.NewButton("Add", func() {
+		id, err := store.CreatePriceList(db, nameEntry.Text)
+		if err != nil {
+			showError(err)
+			return
+		}
+		priceLists = append(priceLists, store.PriceList{ID: id})
+		selectedList = len(priceLists) - 1
+		reload()
+	})
+
+	renameListButton := widget.NewButton("Rename", func() {
+		list, ok := currentList()
+		if !ok {
+			return
+		}
+		if err := store.RenamePriceList(db, list.ID, nameEntry.Text); err != nil {
+			showError(err)
+			return
+		}
+		reload()
+	})
+
+	deleteListButton := widget.NewButton("Delete", func() {
+		list, ok := currentList()
+		if !ok {
+			return
+		}
+		dialog.ShowConfirm("Delete Price List", "Delete "+list.Name+" and all its prices?", func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			if err := store.DeletePriceList(db, list.ID); err != nil {
+				if errors.Is(err, store.ErrPriceListInUse) {
+					showError(fmt.Errorf("%w; move them to another list first", err))
+					return
+				}
+				dialog.NewError(err, window).Show()
+				return
+			}
+			nameEntry.SetText("")
+			reload()
+		}, window)
+	})
+
+	setPriceButton := widget.NewButton("Set Price", func() {
+		list, ok := currentList()
+		index := productSelect.SelectedIndex()
+		if !ok || index < 0 || index >= len(products) {
+			return
+		}
+		minQuantity := quantity.Quantity(0)
+		if text := strings.TrimSpace(minEntry.Text); text != "" {
+			parsed, err := quantity.Parse(text)
+			if err != nil {
+				dialog.NewInformation("Invalid Quantity", "Minimum quantity must be a number.", window).Show()
+				return
+			}
+			minQuantity = parsed
+		}
+		price, err := money.Parse(priceEntry.Text, products[index].Price.Currency)
+		if err != nil {
+			dialog.NewInformation("Invalid Price", "Enter the unit price on this list.", window).Show()
+			return
+		}
+		err = store.SetListPrice(db, store.ListPrice{
+			PriceListID: list.ID,
+			ProductID:   products[index].ID,
+			MinQuantity: minQuantity,
+			Price:       price,
+		})
+		if err != nil {
+			showError(err)
+			return
+		}
+		minEntry.SetText("")
+		priceEntry.SetText("")
+		loadPrices()
+	})
+
+	removePriceButton := widget.NewButton("Remove Price", func() {
+		if selectedPrice < 0 || selectedPrice >= len(prices) {
+			return
+		}
+		if err := store.RemoveListPrice(db, prices[selectedPrice].ID); err != nil {
+			showError(err)
+			return
+		}
+		loadPrices()
+	})
+
+	listForm := widget.NewForm(
+		&widget.FormItem{Text: "Name", Widget: nameEntry},
+	)
+	priceForm := widget.NewForm(
+		&widget.FormItem{Text: "Product", Widget: productSelect},
+		&widget.FormItem{Text: "From quantity", Widget: minEntry},
+		&widget.FormItem{Text: "Price", Widget: priceEntry},
+	)
+
+	controls := container.NewVBox(
+		listForm,
+		container.NewHBox(addListButton, renameListButton, deleteListButton),
+		widget.NewSeparator(),
+		widget.NewLabel("Several prices of one product give quantity breaks."),
+		priceForm,
+		container.NewHBox(setPriceButton, removePriceButton),
+		layout.NewSpacer(),
+	)
+
+	view.Refresh()
+
+	content := container.NewHSplit(
+		listList,
+		container.NewHSplit(priceList, controls),
+	)
+	view.Tab = container.NewTabItem("Price Lists", content)
+	return view
+}
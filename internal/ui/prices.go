@@ -0,0 +1,182 @@
+package ui
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"pos-system/internal/money"
+	"pos-system/internal/store"
+)
+
+const dateTimeLayout = "2006-01-02 15:04"
+
+// parseDateTime reads a local YYYY-MM-DD HH:MM time, or a date on its own
+// for the start of that day.
+func parseDateTime(text string) (time.Time, error) {
+	text = strings.TrimSpace(text)
+	if at, err := time.ParseInLocation(dateTimeLayout, text, time.Local); err == nil {
+		return at, nil
+	}
+	return time.ParseInLocation(time.DateOnly, text, time.Local)
+}
+
+func priceChangeText(change store.PriceChange) string {
+	text := fmt.Sprintf("%s  %s -> %s", change.ChangedAt.Format(dateTimeLayout), change.OldPrice, change.NewPrice)
+	for _, detail := range []string{change.User, change.Note} {
+		if detail != "" {
+			text += "  " + detail
+		}
+	}
+	return text
+}
+
+// showPricesDialog shows a product's price history and scheduled changes,
+// and schedules new ones.
+func showPricesDialog(db *sql.DB, window fyne.Window, product store.Product) {
+	var (
+		history   []store.PriceChange
+		scheduled []store.ScheduledPrice
+		selected  = -1
+	)
+
+	historyList := widget.NewList(
+		func() int { return len(history) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(priceChangeText(history[id]))
+		},
+	)
+	scheduledList := widget.NewList(
+		func() int { return len(scheduled) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			change := scheduled[id]
+			text := fmt.Sprintf("%s from %s", change.Price, change.EffectiveAt.Format(dateTimeLayout))
+			if change.User != "" {
+				text += "  " + change.User
+			}
+			item.(*widget.Label).SetText(text)
+		},
+	)
+	scheduledList.OnSelected = func(id widget.ListItemID) {
+		selected = id
+	}
+
+	load := func() {
+		changes, err := store.PriceHistory(db, product.ID)
+		if err != nil {
+			fmt.Println("Failed to load price history:", err)
+			return
+		}
+		pending, err := store.ListScheduledPrices(db, product.ID)
+		if err != nil {
+			fmt.Println("Failed to load scheduled prices:", err)
+			return
+		}
+		history = changes
+		scheduled = pending
+		selected = -1
+		scheduledList.UnselectAll()
+		historyList.Refresh()
+		scheduledList.Refresh()
+	}
+	load()
+
+	priceEntry := widget.NewEntry()
+	priceEntry.SetPlaceHolder("New price")
+	effectiveEntry := widget.NewEntry()
+	effectiveEntry.SetPlaceHolder("YYYY-MM-DD HH:MM")
+	userEntry := widget.NewEntry()
+	userEntry.SetPlaceHolder("Scheduled by")
+
+	scheduleButton := widget.NewButton("Schedule", func() {
+		price, err := money.Parse(priceEntry.Text, product.Price.Currency)
+		if err != nil {
+			dialog.NewInformation("Invalid Price", "Enter the new price.", window).Show()
+			return
+		}
+		effective, err := parseDateTime(effectiveEntry.Text)
+		if err != nil {
+			dialog.NewInformation("Invalid Time", "Enter when the price changes, like 2025-12-31 09:00.", window).Show()
+			return
+		}
+		_, err = store.SchedulePriceChange(db, store.ScheduledPrice{
+			ProductID:   product.ID,
+			Price:       price,
+			EffectiveAt: effective,
+			User:        strings.TrimSpace(userEntry.Text),
+		})
+		if err != nil {
+			dialog.NewInformation("Schedule Price", err.Error(), window).Show()
+			return
+		}
+		priceEntry.SetText("")
+		effectiveEntry.SetText("")
+		load()
+	})
+
+	cancelButton := widget.NewButton("Cancel Change", func() {
+		if selected < 0 || selected >= len(scheduled) {
+			return
+		}
+		if err := store.CancelScheduledPrice(db, scheduled[selected].ID); err != nil {
+			if errors.Is(err, store.ErrPriceChangeApplied) {
+				dialog.NewInformation("Cancel Change", err.Error(), window).Show()
+			} else {
+				dialog.NewError(err, window).Show()
+			}
+		}
+		load()
+	})
+
+	dateEntry := widget.NewEntry()
+	dateEntry.SetPlaceHolder("YYYY-MM-DD HH:MM")
+	priceAtLabel := widget.NewLabel("")
+	lookUpButton := widget.NewButton("Price At", func() {
+		at, err := parseDateTime(dateEntry.Text)
+		if err != nil {
+			dialog.NewInformation("Invalid Time", "Enter a date like 2025-12-31, with a time if needed.", window).Show()
+			return
+		}
+		price, err := store.PriceAt(db, product.ID, at)
+		if err != nil {
+			dialog.NewError(err, window).Show()
+			return
+		}
+		priceAtLabel.SetText(fmt.Sprintf("%s on %s", price, at.Format(dateTimeLayout)))
+	})
+
+	scheduleForm := widget.NewForm(
+		widget.NewFormItem("Price", priceEntry),
+		widget.NewFormItem("From", effectiveEntry),
+		widget.NewFormItem("User", userEntry),
+	)
+	content := container.NewGridWithColumns(2,
+		container.NewBorder(widget.NewLabel("Price history"), nil, nil, nil, historyList),
+		container.NewBorder(
+			widget.NewLabel("Scheduled changes"),
+			container.NewVBox(
+				container.NewHBox(cancelButton),
+				scheduleForm,
+				scheduleButton,
+				widget.NewSeparator(),
+				container.NewBorder(nil, nil, nil, lookUpButton, dateEntry),
+				priceAtLabel,
+			),
+			nil,
+			nil,
+			scheduledList,
+		),
+	)
+	pricesDialog := dialog.NewCustom("Prices of "+product.Name, "Close", content, window)
+	pricesDialog.Resize(fyne.NewSize(820, 480))
+	pricesDialog.Show()
+}
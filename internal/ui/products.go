@@ -2,22 +2,35 @@ package ui
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 
+	"pos-system/internal/money"
+	"pos-system/internal/quantity"
 	"pos-system/internal/store"
 )
 
 type productForm struct {
-	name    *widget.Entry
-	barcode *widget.Entry
-	price   *widget.Entry
-	stock   *widget.Entry
+	name     *widget.Entry
+	barcode  *widget.Entry
+	price    *widget.Entry
+	cost     *widget.Entry
+	stock    *widget.Entry
+	unit     *widget.Select
+	taxClass *widget.Select
+	category *widget.Select
+	lots     *widget.Check
+	serials  *widget.Check
+	// user is who is editing, recorded against price changes.
+	user *widget.Entry
 }
 
 type ProductsView struct {
@@ -25,29 +38,140 @@ type ProductsView struct {
 	Refresh func()
 }
 
-func NewProductsTab(db *sql.DB) *ProductsView {
-	var products []store.Product
+func NewProductsTab(db *sql.DB, window fyne.Window) *ProductsView {
+	var (
+		products    []store.Product
+		taxClasses  []store.TaxClass
+		categoryIDs []int64
+		filterID    int64
+	)
 	selectedIndex := -1
 
 	form := productForm{
-		name:    widget.NewEntry(),
-		barcode: widget.NewEntry(),
-		price:   widget.NewEntry(),
-		stock:   widget.NewEntry(),
+		name:     widget.NewEntry(),
+		barcode:  widget.NewEntry(),
+		price:    widget.NewEntry(),
+		cost:     widget.NewEntry(),
+		stock:    widget.NewEntry(),
+		unit:     widget.NewSelect(unitOptions(), nil),
+		taxClass: widget.NewSelect(nil, nil),
+		category: widget.NewSelect(nil, nil),
+		lots:     widget.NewCheck("Track lots and expiry", nil),
+		serials:  widget.NewCheck("Track serial numbers", nil),
+		user:     widget.NewEntry(),
+	}
+	filter := widget.NewSelect(nil, nil)
+	filter.PlaceHolder = "All categories"
+	breakdown := widget.NewLabel("")
+
+	taxClassLabel := func(class store.TaxClass) string {
+		return fmt.Sprintf("%s (%s)", class.Name, class.Rate)
+	}
+	selectedUnit := func() quantity.Unit {
+		index := form.unit.SelectedIndex()
+		if index < 0 || index >= len(quantity.Units) {
+			return quantity.Each
+		}
+		return quantity.Units[index]
+	}
+	selectUnit := func(unit quantity.Unit) {
+		form.unit.SetSelectedIndex(0)
+		for i, candidate := range quantity.Units {
+			if candidate == unit {
+				form.unit.SetSelectedIndex(i)
+			}
+		}
+	}
+
+	selectedTaxClass := func() int64 {
+		index := form.taxClass.SelectedIndex()
+		if index < 0 || index >= len(taxClasses) {
+			return store.DefaultTaxClassID
+		}
+		return taxClasses[index].ID
+	}
+	selectTaxClass := func(id int64) {
+		for i, class := range taxClasses {
+			if class.ID == id {
+				form.taxClass.SetSelectedIndex(i)
+				return
+			}
+		}
+		form.taxClass.ClearSelected()
+	}
+
+	// The category select has "None" first, the filter "All categories".
+	selectedCategory := func() *int64 {
+		index := form.category.SelectedIndex()
+		if index <= 0 || index > len(categoryIDs) {
+			return nil
+		}
+		id := categoryIDs[index-1]
+		return &id
+	}
+	selectCategory := func(id *int64) {
+		form.category.SetSelectedIndex(0)
+		if id == nil {
+			return
+		}
+		for i, candidate := range categoryIDs {
+			if candidate == *id {
+				form.category.SetSelectedIndex(i + 1)
+			}
+		}
 	}
 
 	refresh := func(list *widget.List) {
-		items, err := store.ListProducts(db)
+		categories, err := store.ListCategories(db)
+		if err != nil {
+			fmt.Println("Failed to load categories:", err)
+			return
+		}
+		var items []store.Product
+		if filterID != 0 {
+			items, err = store.ListProductsInCategory(db, filterID)
+		} else {
+			items, err = store.ListProducts(db)
+		}
 		if err != nil {
 			fmt.Println("Failed to load products:", err)
 			return
 		}
+		classes, err := store.ListTaxClasses(db)
+		if err != nil {
+			fmt.Println("Failed to load tax classes:", err)
+			return
+		}
 		products = items
+		taxClasses = classes
+		options := make([]string, 0, len(taxClasses))
+		for _, class := range taxClasses {
+			options = append(options, taxClassLabel(class))
+		}
+		form.taxClass.SetOptions(options)
+		var categoryLabels []string
+		categoryLabels, categoryIDs = categoryOptions(categories)
+		form.category.SetOptions(append([]string{"None"}, categoryLabels...))
+		filter.SetOptions(append([]string{"All categories"}, categoryLabels...))
 		selectedIndex = -1
+		breakdown.SetText("")
 		form.name.SetText("")
 		form.barcode.SetText("")
 		form.price.SetText("")
+		form.cost.SetText("")
 		form.stock.SetText("")
+		form.stock.Enable()
+		form.name.Enable()
+		form.unit.Enable()
+		form.taxClass.Enable()
+		form.category.Enable()
+		form.lots.Enable()
+		form.lots.SetChecked(false)
+		form.serials.Enable()
+		form.serials.SetChecked(false)
+		selectUnit(quantity.Each)
+		selectTaxClass(store.DefaultTaxClassID)
+		selectCategory(nil)
 		list.Refresh()
 	}
 
@@ -56,7 +180,7 @@ func NewProductsTab(db *sql.DB) *ProductsView {
 		func() fyne.CanvasObject { return widget.NewLabel("") },
 		func(id widget.ListItemID, item fyne.CanvasObject) {
 			product := products[id]
-			item.(*widget.Label).SetText(fmt.Sprintf("%d - %s", product.ID, product.Name))
+			item.(*widget.Label).SetText(variantPrefix(product) + product.Name + stockWarning(product.StockLevel()))
 		},
 	)
 
@@ -73,19 +197,30 @@ func NewProductsTab(db *sql.DB) *ProductsView {
 				return
 			}
 		}
-		price, err := strconv.ParseFloat(form.price.Text, 64)
+		price, err := money.Parse(form.price.Text, money.DefaultCurrency)
+		if err != nil {
+			return
+		}
+		cost, err := parseCost(form.cost.Text, money.DefaultCurrency)
 		if err != nil {
+			dialog.NewInformation("Invalid Cost", "Cost must be an amount, or empty for none.", window).Show()
 			return
 		}
-		stock, err := strconv.ParseInt(form.stock.Text, 10, 64)
+		stock, err := quantity.Parse(form.stock.Text)
 		if err != nil {
 			return
 		}
 		_, err = store.CreateProduct(db, store.Product{
-			Name:    form.name.Text,
-			Barcode: barcode,
-			Price:   price,
-			Stock:   stock,
+			Name:         form.name.Text,
+			Barcode:      barcode,
+			Price:        price,
+			Cost:         cost,
+			Unit:         selectedUnit(),
+			Stock:        stock,
+			TaxClassID:   selectedTaxClass(),
+			CategoryID:   selectedCategory(),
+			TrackLots:    form.lots.Checked,
+			TrackSerials: form.serials.Checked,
 		})
 		if err != nil {
 			fmt.Println("Failed to create product:", err)
@@ -110,20 +245,26 @@ func NewProductsTab(db *sql.DB) *ProductsView {
 				return
 			}
 		}
-		price, err := strconv.ParseFloat(form.price.Text, 64)
+		price, err := money.Parse(form.price.Text, products[selectedIndex].Price.Currency)
 		if err != nil {
 			return
 		}
-		stock, err := strconv.ParseInt(form.stock.Text, 10, 64)
+		cost, err := parseCost(form.cost.Text, products[selectedIndex].Price.Currency)
 		if err != nil {
+			dialog.NewInformation("Invalid Cost", "Cost must be an amount, or empty for none.", window).Show()
 			return
 		}
 		product := products[selectedIndex]
 		product.Name = form.name.Text
 		product.Barcode = barcode
 		product.Price = price
-		product.Stock = stock
-		if err := store.UpdateProduct(db, product); err != nil {
+		product.Cost = cost
+		product.Unit = selectedUnit()
+		product.TaxClassID = selectedTaxClass()
+		product.CategoryID = selectedCategory()
+		product.TrackLots = form.lots.Checked
+		product.TrackSerials = form.serials.Checked
+		if err := store.UpdateProduct(db, product, strings.TrimSpace(form.user.Text)); err != nil {
 			fmt.Println("Failed to update product:", err)
 			return
 		}
@@ -135,22 +276,115 @@ func NewProductsTab(db *sql.DB) *ProductsView {
 			return
 		}
 		if err := store.DeleteProduct(db, products[selectedIndex].ID); err != nil {
+			if errors.Is(err, store.ErrProductHasVariants) || errors.Is(err, store.ErrProductHasHistory) {
+				dialog.NewInformation("Delete Product", err.Error(), window).Show()
+				return
+			}
 			fmt.Println("Failed to delete product:", err)
 			return
 		}
 		refresh(list)
 	})
 
+	adjustButton := widget.NewButton("Adjust Stock", func() {
+		if selectedIndex < 0 || selectedIndex >= len(products) {
+			return
+		}
+		showAdjustStockDialog(db, window, products[selectedIndex], func() { refresh(list) })
+	})
+
+	historyButton := widget.NewButton("Stock History", func() {
+		if selectedIndex < 0 || selectedIndex >= len(products) {
+			return
+		}
+		showStockHistoryDialog(db, window, products[selectedIndex])
+	})
+
+	variantsButton := widget.NewButton("Variants", func() {
+		if selectedIndex < 0 || selectedIndex >= len(products) {
+			return
+		}
+		product := products[selectedIndex]
+		if product.ParentID != nil {
+			dialog.NewInformation("Variants", "Select the parent product to manage its variants.", window).Show()
+			return
+		}
+		showVariantsDialog(db, window, product, func() { refresh(list) })
+	})
+
+	barcodesButton := widget.NewButton("Barcodes", func() {
+		if selectedIndex < 0 || selectedIndex >= len(products) {
+			return
+		}
+		showBarcodesDialog(db, window, products[selectedIndex])
+	})
+
+	packsButton := widget.NewButton("Packs", func() {
+		if selectedIndex < 0 || selectedIndex >= len(products) {
+			return
+		}
+		showPacksDialog(db, window, products[selectedIndex])
+	})
+
+	reorderButton := widget.NewButton("Reorder Levels", func() {
+		if selectedIndex < 0 || selectedIndex >= len(products) {
+			return
+		}
+		showReorderLevelsDialog(db, window, products[selectedIndex], func() { refresh(list) })
+	})
+
+	pricesButton := widget.NewButton("Prices", func() {
+		if selectedIndex < 0 || selectedIndex >= len(products) {
+			return
+		}
+		showPricesDialog(db, window, products[selectedIndex])
+	})
+
+	lotsButton := widget.NewButton("Lots", func() {
+		if selectedIndex < 0 || selectedIndex >= len(products) {
+			return
+		}
+		showLotsDialog(db, window, products[selectedIndex])
+	})
+
+	serialsButton := widget.NewButton("Serials", func() {
+		if selectedIndex < 0 || selectedIndex >= len(products) {
+			return
+		}
+		showSerialsDialog(db, window, products[selectedIndex])
+	})
+
+	expiryButton := widget.NewButton("Near Expiry", func() {
+		showNearExpiryDialog(db, window)
+	})
+
+	categoriesButton := widget.NewButton("Categories", func() {
+		showCategoriesDialog(db, window, func() { refresh(list) })
+	})
+
+	reconcileButton := widget.NewButton("Reconcile", func() {
+		showReconcileDialog(db, window)
+	})
+
 	formItems := []*widget.FormItem{
 		{Text: "Name", Widget: form.name},
 		{Text: "Barcode", Widget: form.barcode},
 		{Text: "Price", Widget: form.price},
+		{Text: "Cost", Widget: form.cost},
 		{Text: "Stock", Widget: form.stock},
+		{Text: "Unit", Widget: form.unit},
+		{Text: "Tax Class", Widget: form.taxClass},
+		{Text: "Category", Widget: form.category},
+		{Text: "Lots", Widget: form.lots},
+		{Text: "Serials", Widget: form.serials},
+		{Text: "Changed by", Widget: form.user},
 	}
 	formWidget := widget.NewForm(formItems...)
 
-	buttons := container.NewHBox(addButton, updateButton, deleteButton)
-	controls := container.NewVBox(formWidget, buttons)
+	buttons := container.NewHBox(addButton, updateButton, deleteButton, variantsButton, barcodesButton, packsButton, pricesButton)
+	stockButtons := container.NewHBox(adjustButton, historyButton, reorderButton, reconcileButton, categoriesButton)
+	lotButtons := container.NewHBox(lotsButton, expiryButton, serialsButton)
+	controls := container.NewVBox(formWidget, buttons, stockButtons, lotButtons, breakdown)
 
 	footer := widget.NewLabel("Selected: none")
 	list.OnSelected = func(id widget.ListItemID) {
@@ -158,16 +392,66 @@ func NewProductsTab(db *sql.DB) *ProductsView {
 		product := products[id]
 		form.name.SetText(product.Name)
 		form.barcode.SetText(product.Barcode)
-		form.price.SetText(fmt.Sprintf("%.2f", product.Price))
-		form.stock.SetText(strconv.FormatInt(product.Stock, 10))
+		form.price.SetText(product.Price.String())
+		form.cost.SetText(product.Cost.String())
+		form.stock.SetText(product.Stock.String())
+		form.stock.Disable()
+		form.name.Enable()
+		form.unit.Enable()
+		form.taxClass.Enable()
+		form.category.Enable()
+		form.lots.Enable()
+		form.lots.SetChecked(product.TrackLots)
+		form.serials.Enable()
+		form.serials.SetChecked(product.TrackSerials)
+		if product.ParentID != nil {
+			// Variants take their name, unit, tax class, category and lot
+			// and serial tracking from the parent.
+			form.name.Disable()
+			form.unit.Disable()
+			form.taxClass.Disable()
+			form.category.Disable()
+			form.lots.Disable()
+			form.serials.Disable()
+		}
+		selectUnit(product.Unit)
+		selectTaxClass(product.TaxClassID)
+		selectCategory(product.CategoryID)
 		footer.SetText("Selected ID: " + strconv.FormatInt(product.ID, 10))
+		breakdown.SetText("")
+		stock, err := store.ProductStockByLocation(db, product.ID)
+		if err != nil {
+			fmt.Println("Failed to load stock by location:", err)
+			return
+		}
+		inTransit, err := store.QuantityInTransit(db, product.ID)
+		if err != nil {
+			fmt.Println("Failed to load stock in transit:", err)
+			return
+		}
+		lines := make([]string, 0, len(stock)+1)
+		for _, line := range stock {
+			lines = append(lines, fmt.Sprintf("%s: %s", line.Location, line.Quantity.Format(product.Unit)))
+		}
+		if inTransit > 0 {
+			lines = append(lines, "In transit: "+inTransit.Format(product.Unit))
+		}
+		breakdown.SetText("Stock by location\n" + strings.Join(lines, "\n"))
+	}
+
+	filter.OnChanged = func(string) {
+		filterID = 0
+		if index := filter.SelectedIndex(); index > 0 && index <= len(categoryIDs) {
+			filterID = categoryIDs[index-1]
+		}
+		refresh(list)
 	}
 
 	refresh(list)
 
 	content := container.NewBorder(nil, footer, nil, nil,
 		container.NewHSplit(
-			container.NewBorder(nil, nil, nil, nil, list),
+			container.NewBorder(filter, nil, nil, nil, list),
 			container.NewVBox(controls, layout.NewSpacer()),
 		),
 	)
@@ -176,3 +460,11 @@ func NewProductsTab(db *sql.DB) *ProductsView {
 		Refresh: func() { refresh(list) },
 	}
 }
+
+// parseCost reads a cost price, which may be left empty for none.
+func parseCost(text, currency string) (money.Money, error) {
+	if strings.TrimSpace(text) == "" {
+		return money.Zero(currency), nil
+	}
+	return money.Parse(text, currency)
+}
@@ -0,0 +1,260 @@
+package ui
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+
+	"pos-system/internal/money"
+	"pos-system/internal/promo"
+	"pos-system/internal/store"
+	"pos-system/internal/tax"
+)
+
+var promotionKindLabels = map[promo.Kind]string{
+	promo.PercentOff:    "Percent off",
+	promo.AmountOff:     "Amount off each",
+	promo.BuyXGetY:      "Buy X get Y free",
+	promo.MixAndMatch:   "Mix and match (X for amount)",
+	promo.CartThreshold: "Spend threshold",
+}
+
+type promotionForm struct {
+	name      *widget.Entry
+	kind      *widget.Select
+	percent   *widget.Entry
+	amount    *widget.Entry
+	buyQty    *widget.Entry
+	getQty    *widget.Entry
+	threshold *widget.Entry
+	products  *widget.Entry
+	active    *widget.Check
+}
+
+func (f promotionForm) clear() {
+	f.name.SetText("")
+	f.kind.SetSelectedIndex(0)
+	f.percent.SetText("")
+	f.amount.SetText("")
+	f.buyQty.SetText("")
+	f.getQty.SetText("")
+	f.threshold.SetText("")
+	f.products.SetText("")
+	f.active.SetChecked(true)
+}
+
+func (f promotionForm) fill(promotion store.Promotion) {
+	f.name.SetText(promotion.Name)
+	f.kind.SetSelected(promotionKindLabels[promotion.Kind])
+	f.percent.SetText("")
+	if promotion.PercentBP > 0 {
+		f.percent.SetText(tax.Rate(promotion.PercentBP).String())
+	}
+	f.amount.SetText("")
+	if !promotion.Amount.IsZero() {
+		f.amount.SetText(promotion.Amount.String())
+	}
+	f.buyQty.SetText(strconv.FormatInt(promotion.BuyQty, 10))
+	f.getQty.SetText(strconv.FormatInt(promotion.GetQty, 10))
+	f.threshold.SetText("")
+	if !promotion.Threshold.IsZero() {
+		f.threshold.SetText(promotion.Threshold.String())
+	}
+	ids := make([]string, 0, len(promotion.ProductIDs))
+	for _, id := range promotion.ProductIDs {
+		ids = append(ids, strconv.FormatInt(id, 10))
+	}
+	f.products.SetText(strings.Join(ids, ", "))
+	f.active.SetChecked(promotion.Active)
+}
+
+func (f promotionForm) read(promotion store.Promotion) (store.Promotion, error) {
+	promotion.Name = strings.TrimSpace(f.name.Text)
+	if promotion.Name == "" {
+		return promotion, errors.New("name is required")
+	}
+	if f.kind.SelectedIndex() < 0 {
+		return promotion, errors.New("type is required")
+	}
+	promotion.Kind = promo.Kinds[f.kind.SelectedIndex()]
+	promotion.Active = f.active.Checked
+
+	promotion.PercentBP = 0
+	if text := strings.TrimSpace(f.percent.Text); text != "" {
+		rate, err := tax.ParseRate(text)
+		if err != nil {
+			return promotion, err
+		}
+		promotion.PercentBP = int64(rate)
+	}
+
+	var err error
+	if promotion.Amount, err = parseOptionalMoney(f.amount.Text); err != nil {
+		return promotion, err
+	}
+	if promotion.Threshold, err = parseOptionalMoney(f.threshold.Text); err != nil {
+		return promotion, err
+	}
+	if promotion.BuyQty, err = parseOptionalInt(f.buyQty.Text); err != nil {
+		return promotion, err
+	}
+	if promotion.GetQty, err = parseOptionalInt(f.getQty.Text); err != nil {
+		return promotion, err
+	}
+
+	promotion.ProductIDs = nil
+	for _, field := range strings.Split(f.products.Text, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return promotion, fmt.Errorf("invalid product id %q", field)
+		}
+		promotion.ProductIDs = append(promotion.ProductIDs, id)
+	}
+
+	return promotion, promotion.Validate()
+}
+
+func parseOptionalMoney(text string) (money.Money, error) {
+	if strings.TrimSpace(text) == "" {
+		return money.Zero(money.DefaultCurrency), nil
+	}
+	return money.Parse(text, money.DefaultCurrency)
+}
+
+func parseOptionalInt(text string) (int64, error) {
+	if strings.TrimSpace(text) == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+}
+
+func PromotionsTab(db *sql.DB) fyne.CanvasObject {
+	var promotions []store.Promotion
+	selectedIndex := -1
+
+	kindOptions := make([]string, 0, len(promo.Kinds))
+	for _, kind := range promo.Kinds {
+		kindOptions = append(kindOptions, promotionKindLabels[kind])
+	}
+
+	form := promotionForm{
+		name:      widget.NewEntry(),
+		kind:      widget.NewSelect(kindOptions, nil),
+		percent:   widget.NewEntry(),
+		amount:    widget.NewEntry(),
+		buyQty:    widget.NewEntry(),
+		getQty:    widget.NewEntry(),
+		threshold: widget.NewEntry(),
+		products:  widget.NewEntry(),
+		active:    widget.NewCheck("Active", nil),
+	}
+	form.products.SetPlaceHolder("Product IDs, blank for all")
+
+	refresh := func(list *widget.List) {
+		items, err := store.ListPromotions(db)
+		if err != nil {
+			fmt.Println("Failed to load promotions:", err)
+			return
+		}
+		promotions = items
+		selectedIndex = -1
+		form.clear()
+		list.Refresh()
+	}
+
+	list := widget.NewList(
+		func() int { return len(promotions) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			promotion := promotions[id]
+			text := fmt.Sprintf("%d - %s (%s)", promotion.ID, promotion.Name, promotionKindLabels[promotion.Kind])
+			if !promotion.Active {
+				text += " [inactive]"
+			}
+			item.(*widget.Label).SetText(text)
+		},
+	)
+
+	addButton := widget.NewButton("Add", func() {
+		promotion, err := form.read(store.Promotion{})
+		if err != nil {
+			fmt.Println("Invalid promotion:", err)
+			return
+		}
+		if _, err := store.CreatePromotion(db, promotion); err != nil {
+			fmt.Println("Failed to create promotion:", err)
+			return
+		}
+		refresh(list)
+	})
+
+	updateButton := widget.NewButton("Update", func() {
+		if selectedIndex < 0 || selectedIndex >= len(promotions) {
+			return
+		}
+		promotion, err := form.read(promotions[selectedIndex])
+		if err != nil {
+			fmt.Println("Invalid promotion:", err)
+			return
+		}
+		if err := store.UpdatePromotion(db, promotion); err != nil {
+			fmt.Println("Failed to update promotion:", err)
+			return
+		}
+		refresh(list)
+	})
+
+	deactivateButton := widget.NewButton("Deactivate", func() {
+		if selectedIndex < 0 || selectedIndex >= len(promotions) {
+			return
+		}
+		if err := store.DeactivatePromotion(db, promotions[selectedIndex].ID); err != nil {
+			fmt.Println("Failed to deactivate promotion:", err)
+			return
+		}
+		refresh(list)
+	})
+
+	formItems := []*widget.FormItem{
+		{Text: "Name", Widget: form.name},
+		{Text: "Type", Widget: form.kind},
+		{Text: "Percent", Widget: form.percent},
+		{Text: "Amount", Widget: form.amount},
+		{Text: "Buy Qty", Widget: form.buyQty},
+		{Text: "Get Qty", Widget: form.getQty},
+		{Text: "Threshold", Widget: form.threshold},
+		{Text: "Products", Widget: form.products},
+		{Text: "", Widget: form.active},
+	}
+	formWidget := widget.NewForm(formItems...)
+
+	buttons := container.NewHBox(addButton, updateButton, deactivateButton)
+	controls := container.NewVBox(formWidget, buttons)
+
+	footer := widget.NewLabel("Selected: none")
+	list.OnSelected = func(id widget.ListItemID) {
+		selectedIndex = id
+		form.fill(promotions[id])
+		footer.SetText("Selected ID: " + strconv.FormatInt(promotions[id].ID, 10))
+	}
+
+	refresh(list)
+
+	return container.NewBorder(nil, footer, nil, nil,
+		container.NewHSplit(
+			container.NewBorder(nil, nil, nil, nil, list),
+			container.NewVBox(controls, layout.NewSpacer()),
+		),
+	)
+}
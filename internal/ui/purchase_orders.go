@@ -0,0 +1,399 @@
+package ui
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+
+	"pos-system/internal/money"
+	"pos-system/internal/quantity"
+	"pos-system/internal/store"
+)
+
+var purchaseOrderStatusLabels = map[store.PurchaseOrderStatus]string{
+	store.PODraft:             "Draft",
+	store.POSent:              "Sent",
+	store.POPartiallyReceived: "Partially received",
+	store.POClosed:            "Closed",
+}
+
+type PurchaseOrdersView struct {
+	Tab            *container.TabItem
+	Refresh        func()
+	OnStockChanged func()
+}
+
+func NewPurchaseOrdersTab(db *sql.DB, window fyne.Window) *PurchaseOrdersView {
+	var (
+		orders        []store.PurchaseOrder
+		suppliers     []store.Supplier
+		products      []store.Product
+		locations     []store.Location
+		linePacks     []store.Pack
+		selectedOrder = -1
+		selectedLine  = -1
+	)
+	view := &PurchaseOrdersView{}
+
+	supplierSelect := widget.NewSelect(nil, nil)
+	referenceEntry := widget.NewEntry()
+	referenceEntry.SetPlaceHolder("Supplier reference")
+	productSelect := widget.NewSelect(nil, nil)
+	quantityEntry := widget.NewEntry()
+	quantityEntry.SetPlaceHolder("Qty")
+	costEntry := widget.NewEntry()
+	costEntry.SetPlaceHolder("Unit cost")
+	receiveEntry := widget.NewEntry()
+	receiveEntry.SetPlaceHolder("Qty received")
+	receivePackSelect := widget.NewSelect(nil, nil)
+	receiveLocationSelect := widget.NewSelect(nil, nil)
+	lotEntry := widget.NewEntry()
+	lotEntry.SetPlaceHolder("For products tracked by lot")
+	expiryEntry := widget.NewEntry()
+	expiryEntry.SetPlaceHolder("YYYY-MM-DD")
+	serialsEntry := widget.NewMultiLineEntry()
+	serialsEntry.SetPlaceHolder("One serial number per unit, for products tracked by serial")
+	userEntry := widget.NewEntry()
+	userEntry.SetPlaceHolder("Received by")
+	detail := widget.NewLabel("Select a purchase order.")
+
+	currentOrder := func() (store.PurchaseOrder, bool) {
+		if selectedOrder < 0 || selectedOrder >= len(orders) {
+			return store.PurchaseOrder{}, false
+		}
+		return orders[selectedOrder], true
+	}
+
+	lineList := widget.NewList(
+		func() int {
+			order, ok := currentOrder()
+			if !ok {
+				return 0
+			}
+			return len(order.Lines)
+		},
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			order, _ := currentOrder()
+			line := order.Lines[id]
+			item.(*widget.Label).SetText(fmt.Sprintf(
+				"%s: ordered %s, received %s @ %s", line.Name, line.Quantity, line.Received, line.Cost,
+			))
+		},
+	)
+	// Goods can be received as units or as any pack of the line's product.
+	lineList.OnSelected = func(id widget.ListItemID) {
+		selectedLine = id
+		order, _ := currentOrder()
+		line := order.Lines[id]
+		receiveEntry.SetText(line.Outstanding().String())
+		packs, err := store.ListPacks(db, line.ProductID)
+		if err != nil {
+			fmt.Println("Failed to load packs:", err)
+		}
+		linePacks = packs
+		options := []string{"Units"}
+		for _, pack := range linePacks {
+			options = append(options, fmt.Sprintf("%s (%s units)", pack.Name, pack.Factor))
+		}
+		receivePackSelect.SetOptions(options)
+		receivePackSelect.SetSelectedIndex(0)
+	}
+
+	showDetail := func() {
+		selectedLine = -1
+		lineList.UnselectAll()
+		lineList.Refresh()
+		order, ok := currentOrder()
+		if !ok {
+			detail.SetText("Select a purchase order.")
+			return
+		}
+		text := fmt.Sprintf("PO #%d for %s - %s, total %s", order.ID, order.Supplier, purchaseOrderStatusLabels[order.Status], order.Total())
+		if order.Reference != "" {
+			text += "\nReference: " + order.Reference
+		}
+		detail.SetText(text)
+	}
+
+	orderList := widget.NewList(
+		func() int { return len(orders) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			order := orders[id]
+			item.(*widget.Label).SetText(fmt.Sprintf(
+				"#%d %s - %s", order.ID, order.Supplier, purchaseOrderStatusLabels[order.Status],
+			))
+		},
+	)
+	orderList.OnSelected = func(id widget.ListItemID) {
+		selectedOrder = id
+		showDetail()
+	}
+
+	// reload keeps the same order selected after it changes.
+	reload := func() {
+		selectedID := int64(0)
+		if order, ok := currentOrder(); ok {
+			selectedID = order.ID
+		}
+		items, err := store.ListPurchaseOrders(db)
+		if err != nil {
+			fmt.Println("Failed to load purchase orders:", err)
+			return
+		}
+		orders = items
+		selectedOrder = -1
+		for i, order := range orders {
+			if order.ID == selectedID {
+				selectedOrder = i
+			}
+		}
+		orderList.Refresh()
+		if selectedOrder >= 0 {
+			orderList.Select(selectedOrder)
+		} else {
+			orderList.UnselectAll()
+		}
+		showDetail()
+	}
+
+	view.Refresh = func() {
+		supplierItems, err := store.ListSuppliers(db)
+		if err != nil {
+			fmt.Println("Failed to load suppliers:", err)
+			return
+		}
+		productItems, err := store.ListProducts(db)
+		if err != nil {
+			fmt.Println("Failed to load products:", err)
+			return
+		}
+		locationItems, err := store.ListLocations(db)
+		if err != nil {
+			fmt.Println("Failed to load locations:", err)
+			return
+		}
+		suppliers = supplierItems
+		products = productItems
+		locations = locationItems
+		receiveLocationSelect.SetOptions(locationOptions(locations))
+		selectLocation(receiveLocationSelect, locations, store.DefaultLocationID)
+		supplierOptions := make([]string, 0, len(suppliers))
+		for _, supplier := range suppliers {
+			supplierOptions = append(supplierOptions, fmt.Sprintf("%d - %s", supplier.ID, supplier.Name))
+		}
+		supplierSelect.SetOptions(supplierOptions)
+		productOptions := make([]string, 0, len(products))
+		for _, product := range products {
+			productOptions = append(productOptions, fmt.Sprintf("%d - %s", product.ID, product.Name))
+		}
+		productSelect.SetOptions(productOptions)
+		reload()
+	}
+
+	showError := func(err error) {
+		dialog.NewInformation("Purchase Order", err.Error(), window).Show()
+	}
+
+	newOrderButton := widget.NewButton("New Order", func() {
+		index := supplierSelect.SelectedIndex()
+		if index < 0 || index >= len(suppliers) {
+			dialog.NewInformation("Supplier Required", "Choose a supplier for the order.", window).Show()
+			return
+		}
+		id, err := store.CreatePurchaseOrder(db, suppliers[index].ID, strings.TrimSpace(referenceEntry.Text))
+		if err != nil {
+			showError(err)
+			return
+		}
+		referenceEntry.SetText("")
+		orders = append([]store.PurchaseOrder{{ID: id}}, orders...)
+		selectedOrder = 0
+		reload()
+	})
+
+	reorderButton := widget.NewButton("Reorder Suggestions", func() {
+		showReorderDialog(db, window, reload)
+	})
+
+	addLineButton := widget.NewButton("Add Line", func() {
+		order, ok := currentOrder()
+		index := productSelect.SelectedIndex()
+		if !ok || index < 0 || index >= len(products) {
+			return
+		}
+		ordered, err := quantity.Parse(quantityEntry.Text)
+		if err != nil {
+			dialog.NewInformation("Invalid Quantity", "Quantity must be a number.", window).Show()
+			return
+		}
+		cost, err := money.Parse(costEntry.Text, money.DefaultCurrency)
+		if err != nil {
+			dialog.NewInformation("Invalid Cost", "Enter the agreed unit cost.", window).Show()
+			return
+		}
+		if _, err := store.AddPurchaseOrderLine(db, order.ID, products[index].ID, ordered, cost); err != nil {
+			showError(err)
+			return
+		}
+		quantityEntry.SetText("")
+		costEntry.SetText("")
+		reload()
+	})
+
+	removeLineButton := widget.NewButton("Remove Line", func() {
+		order, ok := currentOrder()
+		if !ok || selectedLine < 0 || selectedLine >= len(order.Lines) {
+			return
+		}
+		if err := store.RemovePurchaseOrderLine(db, order.ID, order.Lines[selectedLine].ID); err != nil {
+			showError(err)
+			return
+		}
+		reload()
+	})
+
+	sendButton := widget.NewButton("Mark Sent", func() {
+		order, ok := currentOrder()
+		if !ok {
+			return
+		}
+		if err := store.SendPurchaseOrder(db, order.ID); err != nil {
+			showError(err)
+			return
+		}
+		reload()
+	})
+
+	closeButton := widget.NewButton("Close Order", func() {
+		order, ok := currentOrder()
+		if !ok {
+			return
+		}
+		dialog.ShowConfirm("Close Order", "Close this order? Outstanding quantities will no longer be expected.", func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			if err := store.ClosePurchaseOrder(db, order.ID); err != nil {
+				showError(err)
+				return
+			}
+			reload()
+		}, window)
+	})
+
+	// receive books receipts, all in the lot entered and with the serial
+	// numbers entered, which only apply to products tracked by lot or serial.
+	receive := func(receipts []store.ReceiptLine) {
+		order, _ := currentOrder()
+		expiry, err := parseOptionalDate(expiryEntry.Text)
+		if err != nil {
+			dialog.NewInformation("Invalid Date", "Expiry must be a date like 2025-12-31.", window).Show()
+			return
+		}
+		for i := range receipts {
+			receipts[i].Lot = strings.TrimSpace(lotEntry.Text)
+			receipts[i].Expiry = expiry
+			receipts[i].Serials = parseSerials(serialsEntry.Text)
+		}
+		if err := store.ReceivePurchaseOrder(db, order.ID, receipts, strings.TrimSpace(userEntry.Text)); err != nil {
+			showError(err)
+			return
+		}
+		receiveEntry.SetText("")
+		lotEntry.SetText("")
+		expiryEntry.SetText("")
+		serialsEntry.SetText("")
+		reload()
+		if view.OnStockChanged != nil {
+			view.OnStockChanged()
+		}
+	}
+
+	receiveLineButton := widget.NewButton("Receive Line", func() {
+		order, ok := currentOrder()
+		if !ok || selectedLine < 0 || selectedLine >= len(order.Lines) {
+			return
+		}
+		received, err := quantity.Parse(receiveEntry.Text)
+		if err != nil {
+			dialog.NewInformation("Invalid Quantity", "Quantity must be a number.", window).Show()
+			return
+		}
+		receipt := store.ReceiptLine{
+			LineID:     order.Lines[selectedLine].ID,
+			Quantity:   received,
+			LocationID: selectedLocation(receiveLocationSelect, locations),
+		}
+		if index := receivePackSelect.SelectedIndex(); index > 0 && index <= len(linePacks) {
+			receipt.PackID = &linePacks[index-1].ID
+		}
+		receive([]store.ReceiptLine{receipt})
+	})
+
+	receiveAllButton := widget.NewButton("Receive All Outstanding", func() {
+		order, ok := currentOrder()
+		if !ok {
+			return
+		}
+		var receipts []store.ReceiptLine
+		for _, line := range order.Lines {
+			if line.Outstanding() > 0 {
+				receipts = append(receipts, store.ReceiptLine{
+					LineID:     line.ID,
+					Quantity:   line.Outstanding(),
+					LocationID: selectedLocation(receiveLocationSelect, locations),
+				})
+			}
+		}
+		receive(receipts)
+	})
+
+	newOrderForm := widget.NewForm(
+		&widget.FormItem{Text: "Supplier", Widget: supplierSelect},
+		&widget.FormItem{Text: "Reference", Widget: referenceEntry},
+	)
+	lineForm := widget.NewForm(
+		&widget.FormItem{Text: "Product", Widget: productSelect},
+		&widget.FormItem{Text: "Quantity", Widget: quantityEntry},
+		&widget.FormItem{Text: "Unit Cost", Widget: costEntry},
+	)
+	receiveForm := widget.NewForm(
+		&widget.FormItem{Text: "Quantity", Widget: receiveEntry},
+		&widget.FormItem{Text: "Received As", Widget: receivePackSelect},
+		&widget.FormItem{Text: "Into", Widget: receiveLocationSelect},
+		&widget.FormItem{Text: "Lot", Widget: lotEntry},
+		&widget.FormItem{Text: "Expiry", Widget: expiryEntry},
+		&widget.FormItem{Text: "Serials", Widget: serialsEntry},
+		&widget.FormItem{Text: "User", Widget: userEntry},
+	)
+
+	controls := container.NewVBox(
+		newOrderForm,
+		container.NewHBox(newOrderButton, reorderButton),
+		widget.NewSeparator(),
+		lineForm,
+		container.NewHBox(addLineButton, removeLineButton, sendButton, closeButton),
+		widget.NewSeparator(),
+		receiveForm,
+		container.NewHBox(receiveLineButton, receiveAllButton),
+		layout.NewSpacer(),
+	)
+	orderPane := container.NewBorder(detail, nil, nil, nil, lineList)
+
+	view.Refresh()
+
+	content := container.NewHSplit(
+		orderList,
+		container.NewHSplit(orderPane, controls),
+	)
+	view.Tab = container.NewTabItem("Purchase Orders", content)
+	return view
+}
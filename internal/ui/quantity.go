@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"pos-system/internal/quantity"
+	"pos-system/internal/store"
+)
+
+// promptQuantity hands one unit of product to onQuantity. Products sold by
+// measure, scanned without a scale label, ask for the amount first.
+func promptQuantity(window fyne.Window, product store.Product, onQuantity func(quantity.Quantity)) {
+	if product.Unit.Whole() {
+		onQuantity(quantity.Of(1))
+		return
+	}
+
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder("0.000")
+	dialog.ShowForm(product.Name, "Add", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Quantity ("+string(product.Unit)+")", entry),
+	}, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		qty, err := quantity.Parse(entry.Text)
+		if err != nil || qty <= 0 {
+			dialog.NewInformation("Invalid Quantity", "Enter the amount in "+string(product.Unit)+".", window).Show()
+			return
+		}
+		onQuantity(qty)
+	}, window)
+}
+
+func parseOptionalQuantity(text string) (quantity.Quantity, error) {
+	if strings.TrimSpace(text) == "" {
+		return 0, nil
+	}
+	return quantity.Parse(text)
+}
+
+// signed formats a stock movement with an explicit plus for goods in.
+func signed(q quantity.Quantity) string {
+	if q > 0 {
+		return "+" + q.String()
+	}
+	return q.String()
+}
+
+var unitLabels = map[quantity.Unit]string{
+	quantity.Each:     "Each",
+	quantity.Kilogram: "Kilogram (kg)",
+	quantity.Gram:     "Gram (g)",
+	quantity.Pound:    "Pound (lb)",
+	quantity.Litre:    "Litre (l)",
+	quantity.Metre:    "Metre (m)",
+}
+
+func unitOptions() []string {
+	options := make([]string, 0, len(quantity.Units))
+	for _, unit := range quantity.Units {
+		options = append(options, unitLabels[unit])
+	}
+	return options
+}
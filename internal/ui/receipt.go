@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"pos-system/internal/money"
+	"pos-system/internal/store"
+	"pos-system/internal/tax"
+)
+
+func receiptText(sale store.Sale) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Sale #%d\n%s\n", sale.ID, sale.CreatedAt.Format(time.RFC3339))
+	if sale.Status == store.SaleVoided && sale.VoidedAt != nil {
+		fmt.Fprintf(&b, "VOIDED %s by %s: %s\n", sale.VoidedAt.Format(time.RFC3339), sale.VoidedBy, sale.VoidReason)
+	}
+	b.WriteString("\n")
+	for _, line := range sale.Lines {
+		if line.PackID != nil {
+			fmt.Fprintf(&b, "%s - %s x%s @ %s = %s\n", line.Name, line.PackName, line.Packs, line.PackPrice, line.Total)
+		} else {
+			fmt.Fprintf(&b, "%s %s @ %s = %s\n", line.Name, line.Quantity.Format(line.Unit), line.UnitPrice, line.Total)
+		}
+		if line.PriceList != "" {
+			fmt.Fprintf(&b, "  %s price\n", line.PriceList)
+		}
+		for _, discount := range line.Discounts {
+			fmt.Fprintf(&b, "  %s -%s\n", discount.Name, discount.Amount)
+		}
+		for _, lot := range line.Lots {
+			fmt.Fprintf(&b, "  Lot %s%s: %s\n", lot.Lot, expiryText(lot.Expiry), lot.Quantity.Format(line.Unit))
+		}
+		for _, serial := range line.Serials {
+			fmt.Fprintf(&b, "  S/N %s\n", serial.Serial)
+		}
+	}
+	b.WriteString("\n")
+	if !sale.Discount.IsZero() {
+		fmt.Fprintf(&b, "You saved: %s\n", sale.Discount)
+	}
+	if sale.Mode == tax.Exclusive {
+		fmt.Fprintf(&b, "Subtotal: %s\n", sale.Subtotal)
+	}
+	for _, taxLine := range sale.Taxes {
+		fmt.Fprintf(&b, "Tax %s on %s: %s\n", taxLine.Rate, taxLine.Net, taxLine.Tax)
+	}
+	fmt.Fprintf(&b, "Total: %s", sale.Total)
+	if sale.Mode == tax.Inclusive {
+		fmt.Fprintf(&b, " (incl. tax %s)", sale.Tax)
+	}
+	for _, payment := range sale.Payments {
+		fmt.Fprintf(&b, "\n%s: %s", tenderLabels[payment.Tender.Type], payment.Tender.Amount)
+	}
+	if !sale.Change.IsZero() {
+		fmt.Fprintf(&b, "\nChange: %s", sale.Change)
+	}
+	return b.String()
+}
+
+func cartTotalsText(cart store.PricedCart) string {
+	if cart.Discount.IsZero() {
+		return fmt.Sprintf("Subtotal: %s  Tax: %s  Total: %s", cart.Subtotal, cart.Tax, cart.Total)
+	}
+	return fmt.Sprintf("Subtotal: %s  Discount: %s  Tax: %s  Total: %s", cart.Subtotal, cart.Discount, cart.Tax, cart.Total)
+}
+
+// pricedUnitPrice is what one unit, or one pack on pack lines, was priced at.
+func pricedUnitPrice(line store.PricedLine) money.Money {
+	if line.PackID != nil {
+		return line.PackPrice
+	}
+	return line.UnitPrice
+}
+
+func pricedLineText(line store.PricedLine) string {
+	if line.Discount.IsZero() {
+		return line.Total.String()
+	}
+	return fmt.Sprintf("%s (-%s)", line.Total, line.Discount)
+}
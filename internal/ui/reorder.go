@@ -0,0 +1,146 @@
+package ui
+
+import (
+	"database/sql"
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"pos-system/internal/quantity"
+	"pos-system/internal/store"
+)
+
+var stockLevelLabels = map[store.StockLevel]string{
+	store.StockLow:          "low stock",
+	store.StockBelowMinimum: "below minimum",
+	store.StockOut:          "out of stock",
+}
+
+// stockWarning is appended to product names whose stock needs attention.
+func stockWarning(level store.StockLevel) string {
+	if label, ok := stockLevelLabels[level]; ok {
+		return "  [" + label + "]"
+	}
+	return ""
+}
+
+func showReorderLevelsDialog(db *sql.DB, window fyne.Window, product store.Product, onDone func()) {
+	suppliers, err := store.ListSuppliers(db)
+	if err != nil {
+		dialog.NewError(err, window).Show()
+		return
+	}
+	// "None" comes first so a product can be left without a supplier.
+	options := []string{"None"}
+	for _, supplier := range suppliers {
+		options = append(options, fmt.Sprintf("%d - %s", supplier.ID, supplier.Name))
+	}
+	supplierSelect := widget.NewSelect(options, nil)
+	supplierSelect.SetSelectedIndex(0)
+	for i, supplier := range suppliers {
+		if product.SupplierID != nil && *product.SupplierID == supplier.ID {
+			supplierSelect.SetSelectedIndex(i + 1)
+		}
+	}
+
+	levelEntry := func(level quantity.Quantity) *widget.Entry {
+		entry := widget.NewEntry()
+		entry.SetPlaceHolder("Not set")
+		if level > 0 {
+			entry.SetText(level.String())
+		}
+		return entry
+	}
+	minEntry := levelEntry(product.MinStock)
+	pointEntry := levelEntry(product.ReorderPoint)
+	qtyEntry := levelEntry(product.ReorderQty)
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Supplier", supplierSelect),
+		widget.NewFormItem("Minimum Level", minEntry),
+		widget.NewFormItem("Reorder Point", pointEntry),
+		widget.NewFormItem("Reorder Quantity", qtyEntry),
+	}
+	title := fmt.Sprintf("Reorder levels for %s (on hand %s)", product.Name, product.Stock)
+	dialog.ShowForm(title, "Save", "Cancel", items, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		var levels store.ReorderLevels
+		for _, field := range []struct {
+			entry *widget.Entry
+			level *quantity.Quantity
+		}{
+			{minEntry, &levels.MinStock},
+			{pointEntry, &levels.ReorderPoint},
+			{qtyEntry, &levels.ReorderQty},
+		} {
+			level, err := parseOptionalQuantity(field.entry.Text)
+			if err != nil {
+				dialog.NewInformation("Invalid Quantity", "Levels must be numbers.", window).Show()
+				return
+			}
+			*field.level = level
+		}
+		if index := supplierSelect.SelectedIndex(); index > 0 && index <= len(suppliers) {
+			levels.SupplierID = &suppliers[index-1].ID
+		}
+		if err := store.SetReorderLevels(db, product.ID, levels); err != nil {
+			dialog.NewError(err, window).Show()
+			return
+		}
+		onDone()
+	}, window)
+}
+
+// showReorderDialog lists what to order from each supplier. A supplier's
+// proposals can be turned into a draft purchase order in one go.
+func showReorderDialog(db *sql.DB, window fyne.Window, onOrdered func()) {
+	report, err := store.ReorderReport(db)
+	if err != nil {
+		dialog.NewError(err, window).Show()
+		return
+	}
+	if len(report) == 0 {
+		dialog.NewInformation("Reorder", "Nothing needs reordering.", window).Show()
+		return
+	}
+
+	var reorder dialog.Dialog
+	groups := container.NewVBox()
+	for _, group := range report {
+		supplier := group.Supplier
+		if group.SupplierID == nil {
+			supplier = "No preferred supplier"
+		}
+		groups.Add(widget.NewLabelWithStyle(supplier, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+		for _, line := range group.Lines {
+			groups.Add(widget.NewLabel(fmt.Sprintf(
+				"%s: order %s (on hand %s, in transit %s, on order %s, reorder at %s) @ %s",
+				line.Name, line.Quantity.Format(line.Unit), line.Stock, line.InTransit, line.OnOrder, line.ReorderPoint, line.Cost,
+			)))
+		}
+		if group.SupplierID == nil {
+			continue
+		}
+		groups.Add(widget.NewButton("Draft Order for "+supplier, func() {
+			id, err := store.CreateReorderPurchaseOrder(db, group)
+			if err != nil {
+				dialog.NewError(err, window).Show()
+				return
+			}
+			reorder.Hide()
+			dialog.NewInformation("Reorder", fmt.Sprintf("Drafted purchase order #%d.", id), window).Show()
+			if onOrdered != nil {
+				onOrdered()
+			}
+		}))
+	}
+
+	reorder = dialog.NewCustom("Reorder Suggestions", "Close", container.NewVScroll(groups), window)
+	reorder.Resize(fyne.NewSize(640, 420))
+	reorder.Show()
+}
@@ -0,0 +1,188 @@
+package ui
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"pos-system/internal/store"
+)
+
+type ReportsView struct {
+	Tab     *container.TabItem
+	Refresh func()
+}
+
+// report renders one store report for sales created in [from, to) as lines
+// of text.
+type report struct {
+	name string
+	run  func(db *sql.DB, from, to time.Time) ([]string, error)
+}
+
+var reports = []report{
+	{"Sales summary", salesSummaryReport},
+	{"Tax", taxReport},
+	{"Payments", paymentReport},
+	{"Promotions", promotionReport},
+	{"Categories", categoryReport},
+	{"Margin by product", marginReport(store.MarginByProduct)},
+	{"Margin by category", marginReport(store.MarginByCategory)},
+	{"Margin by day", marginReport(store.MarginByDay)},
+}
+
+func salesSummaryReport(db *sql.DB, from, to time.Time) ([]string, error) {
+	summary, err := store.SalesReport(db, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return []string{
+		fmt.Sprintf("Sales: %d", summary.Sales),
+		"Subtotal: " + summary.Subtotal.String(),
+		"Discounts: " + summary.Discount.String(),
+		"Tax: " + summary.Tax.String(),
+		"Total: " + summary.Total.String(),
+		"Refunds: " + summary.Refunds.String(),
+		fmt.Sprintf("Voided: %d (%s)", summary.Voided, summary.VoidedTotal),
+	}, nil
+}
+
+func taxReport(db *sql.DB, from, to time.Time) ([]string, error) {
+	taxes, err := store.TaxReport(db, from, to)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, 0, len(taxes))
+	for _, line := range taxes {
+		lines = append(lines, fmt.Sprintf("Class %d at %s: net %s, tax %s", line.TaxClassID, line.Rate, line.Net, line.Tax))
+	}
+	return lines, nil
+}
+
+func paymentReport(db *sql.DB, from, to time.Time) ([]string, error) {
+	totals, err := store.PaymentReport(db, from, to)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, 0, len(totals))
+	for _, total := range totals {
+		lines = append(lines, fmt.Sprintf("%s: %s from %d payments", tenderLabels[total.Type], total.Amount, total.Payments))
+	}
+	return lines, nil
+}
+
+func promotionReport(db *sql.DB, from, to time.Time) ([]string, error) {
+	totals, err := store.PromotionReport(db, from, to)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, 0, len(totals))
+	for _, total := range totals {
+		lines = append(lines, fmt.Sprintf("%s: %s off %d lines", total.Name, total.Amount, total.Lines))
+	}
+	return lines, nil
+}
+
+func categoryReport(db *sql.DB, from, to time.Time) ([]string, error) {
+	totals, err := store.CategoryReport(db, from, to)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, 0, len(totals))
+	for _, total := range totals {
+		lines = append(lines, fmt.Sprintf("%s%s: qty %s, net %s, tax %s, total %s",
+			strings.Repeat("    ", total.Depth), total.Path, total.Quantity, total.Net, total.Tax, total.Total))
+	}
+	return lines, nil
+}
+
+// marginReport renders one of the store's margin reports.
+func marginReport(margins func(db *sql.DB, from, to time.Time) ([]store.MarginLine, error)) func(db *sql.DB, from, to time.Time) ([]string, error) {
+	return func(db *sql.DB, from, to time.Time) ([]string, error) {
+		totals, err := margins(db, from, to)
+		if err != nil {
+			return nil, err
+		}
+		lines := make([]string, 0, len(totals))
+		for _, total := range totals {
+			lines = append(lines, fmt.Sprintf("%s: qty %s, revenue %s, cost %s, margin %s (%.1f%%)",
+				total.Name, total.Quantity, total.Revenue, total.Cost, total.Margin(), total.Percent()))
+		}
+		return lines, nil
+	}
+}
+
+// NewReportsTab runs the sales reports over a range of days, both ends
+// included.
+func NewReportsTab(db *sql.DB, window fyne.Window) *ReportsView {
+	var lines []string
+	view := &ReportsView{}
+
+	names := make([]string, 0, len(reports))
+	for _, r := range reports {
+		names = append(names, r.name)
+	}
+	reportSelect := widget.NewSelect(names, nil)
+	reportSelect.SetSelectedIndex(0)
+
+	now := time.Now()
+	fromEntry := widget.NewEntry()
+	fromEntry.SetText(time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local).Format(time.DateOnly))
+	toEntry := widget.NewEntry()
+	toEntry.SetText(now.Format(time.DateOnly))
+
+	list := widget.NewList(
+		func() int { return len(lines) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(lines[id])
+		},
+	)
+
+	view.Refresh = func() {
+		from, err := time.ParseInLocation(time.DateOnly, strings.TrimSpace(fromEntry.Text), time.Local)
+		if err != nil {
+			dialog.NewInformation("Invalid Date", "From must be a date like 2025-12-31.", window).Show()
+			return
+		}
+		to, err := time.ParseInLocation(time.DateOnly, strings.TrimSpace(toEntry.Text), time.Local)
+		if err != nil {
+			dialog.NewInformation("Invalid Date", "To must be a date like 2025-12-31.", window).Show()
+			return
+		}
+		index := reportSelect.SelectedIndex()
+		if index < 0 || index >= len(reports) {
+			return
+		}
+		result, err := reports[index].run(db, from, to.AddDate(0, 0, 1))
+		if err != nil {
+			fmt.Println("Failed to run report:", err)
+			return
+		}
+		if len(result) == 0 {
+			result = []string{"No sales in this period."}
+		}
+		lines = result
+		list.Refresh()
+	}
+	reportSelect.OnChanged = func(string) { view.Refresh() }
+
+	runButton := widget.NewButton("Run", view.Refresh)
+	form := widget.NewForm(
+		widget.NewFormItem("Report", reportSelect),
+		widget.NewFormItem("From", fromEntry),
+		widget.NewFormItem("To", toEntry),
+	)
+
+	view.Refresh()
+
+	content := container.NewBorder(container.NewVBox(form, runButton), nil, nil, nil, list)
+	view.Tab = container.NewTabItem("Reports", content)
+	return view
+}
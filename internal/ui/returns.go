@@ -0,0 +1,350 @@
+package ui
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"pos-system/internal/barcode"
+	"pos-system/internal/quantity"
+	"pos-system/internal/store"
+)
+
+type ReturnsView struct {
+	Tab            *container.TabItem
+	HandleScan     func(barcode.Scan)
+	OnStockChanged func()
+}
+
+func NewReturnsTab(db *sql.DB, window fyne.Window) *ReturnsView {
+	var (
+		saleID        int64
+		lines         []store.ReturnableLine
+		pending       []store.ReturnLine
+		selectedIndex = -1
+	)
+
+	status := widget.NewLabel("Scan a receipt or enter a sale ID.")
+	saleIDEntry := widget.NewEntry()
+	saleIDEntry.SetPlaceHolder("Sale ID")
+	qtyEntry := widget.NewEntry()
+	qtyEntry.SetPlaceHolder("Qty")
+	serialsEntry := widget.NewEntry()
+	serialsEntry.SetPlaceHolder("Serial numbers, for items tracked by serial")
+	reasonEntry := widget.NewEntry()
+	reasonEntry.SetPlaceHolder("Reason")
+	referenceEntry := widget.NewEntry()
+	referenceEntry.SetPlaceHolder("Refund reference")
+	restockCheck := widget.NewCheck("Return items to stock", nil)
+	restockCheck.SetChecked(true)
+
+	tenderOptions := make([]string, 0, len(store.TenderTypes))
+	for _, tenderType := range store.TenderTypes {
+		tenderOptions = append(tenderOptions, tenderLabels[tenderType])
+	}
+	tenderSelect := widget.NewSelect(tenderOptions, nil)
+	tenderSelect.SetSelectedIndex(0)
+
+	pendingText := func(pendingLine store.ReturnLine) string {
+		text := fmt.Sprintf("Item %d x%s", pendingLine.SaleItemID, pendingLine.Quantity)
+		for _, line := range lines {
+			if line.ID == pendingLine.SaleItemID {
+				text = line.Name + " " + pendingLine.Quantity.Format(line.Unit)
+			}
+		}
+		if len(pendingLine.Serials) > 0 {
+			text += "  S/N " + strings.Join(pendingLine.Serials, ", ")
+		}
+		return text
+	}
+
+	// unreturnedSerials lists the units of a line not yet brought back.
+	unreturnedSerials := func(line store.ReturnableLine) []string {
+		var serials []string
+		for _, serial := range line.Serials {
+			if serial.ReturnID == nil {
+				serials = append(serials, serial.Serial)
+			}
+		}
+		return serials
+	}
+
+	saleList := widget.NewList(
+		func() int { return len(lines) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			line := lines[id]
+			text := fmt.Sprintf(
+				"%s sold %s, returned %s, paid %s",
+				line.Name, line.Quantity, line.Returned, line.Total,
+			)
+			if serials := unreturnedSerials(line); len(serials) > 0 {
+				text += "  S/N " + strings.Join(serials, ", ")
+			}
+			item.(*widget.Label).SetText(text)
+		},
+	)
+	pendingList := widget.NewList(
+		func() int { return len(pending) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(pendingText(pending[id]))
+		},
+	)
+
+	reset := func() {
+		pending = nil
+		selectedIndex = -1
+		qtyEntry.SetText("")
+		serialsEntry.SetText("")
+		reasonEntry.SetText("")
+		referenceEntry.SetText("")
+		saleList.UnselectAll()
+		saleList.Refresh()
+		pendingList.Refresh()
+	}
+
+	loadSale := func(text string) {
+		id, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+		if err != nil {
+			dialog.NewInformation("Invalid Sale", "Sale ID must be a number.", window).Show()
+			return
+		}
+		items, err := store.ReturnableLines(db, id)
+		if err != nil {
+			dialog.NewError(err, window).Show()
+			return
+		}
+		if len(items) == 0 {
+			dialog.NewInformation("Unknown Sale", fmt.Sprintf("No items found for sale %d.", id), window).Show()
+			return
+		}
+		sale, err := store.GetSale(db, id)
+		if err != nil {
+			dialog.NewError(err, window).Show()
+			return
+		}
+		saleID = id
+		lines = items
+		saleIDEntry.SetText(strconv.FormatInt(id, 10))
+		if sale.Status == store.SaleVoided {
+			status.SetText(fmt.Sprintf("Sale #%d was voided by %s: %s", id, sale.VoidedBy, sale.VoidReason))
+		} else {
+			status.SetText(fmt.Sprintf("Returning against sale #%d", id))
+		}
+		reset()
+	}
+
+	saleList.OnSelected = func(id widget.ListItemID) {
+		selectedIndex = id
+		line := lines[id]
+		qtyEntry.SetText((line.Quantity - line.Returned).String())
+		serialsEntry.SetText(strings.Join(unreturnedSerials(line), ", "))
+	}
+
+	addButton := widget.NewButton("Add to Return", func() {
+		if selectedIndex < 0 || selectedIndex >= len(lines) {
+			return
+		}
+		line := lines[selectedIndex]
+		// Units tracked by serial number are returned one per serial given.
+		var serials []string
+		if len(line.Serials) > 0 {
+			serials = parseSerials(serialsEntry.Text)
+			if len(serials) == 0 {
+				dialog.NewInformation("Serial Required", "Enter the serial number of each unit coming back.", window).Show()
+				return
+			}
+			qtyEntry.SetText(strconv.Itoa(len(serials)))
+		}
+		qty, err := quantity.Parse(qtyEntry.Text)
+		if err != nil || qty <= 0 {
+			dialog.NewInformation("Invalid Quantity", "Quantity must be a positive number.", window).Show()
+			return
+		}
+		alreadyPending := quantity.Quantity(0)
+		for _, existing := range pending {
+			if existing.SaleItemID != line.ID {
+				continue
+			}
+			alreadyPending += existing.Quantity
+			for _, serial := range serials {
+				if slices.Contains(existing.Serials, serial) {
+					dialog.NewInformation("Already Added", "Serial "+serial+" is already on this return.", window).Show()
+					return
+				}
+			}
+		}
+		if qty+alreadyPending > line.Quantity-line.Returned {
+			dialog.NewInformation("Too Many", fmt.Sprintf("Only %s of %s can be returned.", line.Quantity-line.Returned, line.Name), window).Show()
+			return
+		}
+		pending = append(pending, store.ReturnLine{SaleItemID: line.ID, Quantity: qty, Serials: serials})
+		pendingList.Refresh()
+	})
+
+	clearButton := widget.NewButton("Clear", func() {
+		pending = nil
+		pendingList.Refresh()
+	})
+
+	view := &ReturnsView{}
+
+	processButton := widget.NewButton("Process Return", func() {
+		if saleID == 0 || len(pending) == 0 {
+			dialog.NewInformation("Nothing to Return", "Add items to the return first.", window).Show()
+			return
+		}
+		refund := store.Tender{
+			Type:      store.TenderTypes[tenderSelect.SelectedIndex()],
+			Reference: referenceEntry.Text,
+		}
+		returnID, err := store.CreateReturn(db, saleID, pending, restockCheck.Checked, refund, reasonEntry.Text)
+		if err != nil {
+			if errors.Is(err, store.ErrReturnExceedsSold) || errors.Is(err, store.ErrSaleVoided) ||
+				errors.Is(err, store.ErrSerialNotSold) || errors.Is(err, store.ErrSerialRequired) {
+				dialog.NewInformation("Return Rejected", err.Error(), window).Show()
+				return
+			}
+			dialog.NewError(err, window).Show()
+			return
+		}
+
+		if returns, err := store.ListReturns(db, saleID); err == nil {
+			for _, ret := range returns {
+				if ret.ID == returnID {
+					dialog.NewInformation("Return Complete", fmt.Sprintf(
+						"Return #%d against sale #%d\nRefund %s by %s",
+						ret.ID, ret.SaleID, ret.Refund.Amount, tenderLabels[ret.Refund.Type],
+					), window).Show()
+				}
+			}
+		}
+		loadSale(strconv.FormatInt(saleID, 10))
+		if view.OnStockChanged != nil {
+			view.OnStockChanged()
+		}
+	})
+
+	voidButton := widget.NewButton("Void Sale", func() {
+		if saleID == 0 {
+			return
+		}
+		userEntry := widget.NewEntry()
+		voidReasonEntry := widget.NewEntry()
+		items := []*widget.FormItem{
+			widget.NewFormItem("Voided by", userEntry),
+			widget.NewFormItem("Reason", voidReasonEntry),
+		}
+		dialog.ShowForm(fmt.Sprintf("Void sale #%d", saleID), "Void", "Cancel", items, func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			err := store.VoidSale(db, saleID, strings.TrimSpace(userEntry.Text), strings.TrimSpace(voidReasonEntry.Text))
+			if err != nil {
+				if errors.Is(err, store.ErrSaleVoided) || errors.Is(err, store.ErrSaleHasReturns) {
+					dialog.NewInformation("Void Rejected", err.Error(), window).Show()
+					return
+				}
+				dialog.NewError(err, window).Show()
+				return
+			}
+			loadSale(strconv.FormatInt(saleID, 10))
+			if view.OnStockChanged != nil {
+				view.OnStockChanged()
+			}
+		}, window)
+	})
+
+	loadButton := widget.NewButton("Load Sale", func() {
+		loadSale(saleIDEntry.Text)
+	})
+	saleIDEntry.OnSubmitted = loadSale
+
+	// findSerial shows where a serial number has been, and loads the last
+	// sale of it still open to return with the unit selected.
+	findSerial := func(serial string) {
+		units, err := store.FindSerial(db, serial)
+		if err != nil {
+			dialog.NewError(err, window).Show()
+			return
+		}
+		if len(units) == 0 {
+			dialog.NewInformation("Unknown Serial", "No unit found with serial number "+serial+".", window).Show()
+			return
+		}
+		var open *store.SerialSale
+		for _, unit := range units {
+			for i, sale := range unit.Sales {
+				if sale.ReturnID == nil && sale.SaleStatus != store.SaleVoided {
+					open = &unit.Sales[i]
+				}
+			}
+		}
+		dialog.NewInformation("Serial "+serial, serialHistoryText(units), window).Show()
+		if open == nil {
+			return
+		}
+		loadSale(strconv.FormatInt(open.SaleID, 10))
+		for i, line := range lines {
+			if line.ID == open.SaleItemID {
+				saleList.Select(i)
+				serialsEntry.SetText(serial)
+			}
+		}
+	}
+
+	findSerialButton := widget.NewButton("Find Serial", func() {
+		entry := widget.NewEntry()
+		dialog.ShowForm("Find Serial", "Find", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("Serial", entry),
+		}, func(confirmed bool) {
+			if serial := strings.TrimSpace(entry.Text); confirmed && serial != "" {
+				findSerial(serial)
+			}
+		}, window)
+	})
+
+	top := container.NewBorder(nil, nil, nil, container.NewHBox(loadButton, voidButton, findSerialButton), saleIDEntry)
+	left := container.NewBorder(
+		nil,
+		container.NewVBox(serialsEntry, container.NewBorder(nil, nil, nil, addButton, qtyEntry)),
+		nil,
+		nil,
+		saleList,
+	)
+	refundForm := widget.NewForm(
+		&widget.FormItem{Text: "Refund to", Widget: tenderSelect},
+		&widget.FormItem{Text: "Reference", Widget: referenceEntry},
+		&widget.FormItem{Text: "Reason", Widget: reasonEntry},
+	)
+	right := container.NewBorder(
+		nil,
+		container.NewVBox(restockCheck, refundForm, container.NewHBox(clearButton, processButton)),
+		nil,
+		nil,
+		pendingList,
+	)
+
+	content := container.NewBorder(top, status, nil, nil, container.NewHSplit(left, right))
+
+	view.Tab = container.NewTabItem("Returns", content)
+	// Receipts carry the sale ID as a plain code; GS1 labels with a serial
+	// number find the sale of that unit.
+	view.HandleScan = func(scan barcode.Scan) {
+		if scan.Serial != "" {
+			findSerial(scan.Serial)
+			return
+		}
+		loadSale(scan.Raw)
+	}
+	return view
+}
@@ -4,32 +4,78 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
 
+	"pos-system/internal/barcode"
+	"pos-system/internal/money"
+	"pos-system/internal/quantity"
 	"pos-system/internal/store"
 )
 
 type SalesView struct {
 	Tab           *container.TabItem
-	HandleScan    func(string)
+	HandleScan    func(barcode.Scan)
 	ClearCart     func()
 	OnSaleCreated func()
 }
 
+// cartItem is a product, or with Pack set, a number of its packs. Lot is
+// set when the scanned label named the lot, and Serials hold one serial
+// number per unit of products tracked by serial number.
 type cartItem struct {
 	Product  store.Product
-	Quantity int64
+	Pack     *store.Pack
+	Quantity quantity.Quantity
+	Lot      string
+	Serials  []string
 }
 
-func NewSalesTab(db *sql.DB, window fyne.Window) *SalesView {
+func (c cartItem) label() string {
+	if c.Pack != nil {
+		return lotLabel(packLabel(c.Product, *c.Pack), c.Lot)
+	}
+	return lotLabel(c.Product.Name, c.Lot)
+}
+
+func (c cartItem) unitPrice() money.Money {
+	if c.Pack != nil {
+		return c.Pack.PriceFor(c.Product.Price)
+	}
+	return c.Product.Price
+}
+
+// code keys the cart, so each lot of a product gets its own line.
+func (c cartItem) code() string {
+	code := c.Product.Barcode
+	if c.Pack != nil {
+		code = c.Pack.Barcode
+	}
+	if c.Lot != "" {
+		code += "|" + c.Lot
+	}
+	return code
+}
+
+func (c cartItem) saleItem() store.SaleItem {
+	item := store.SaleItem{ProductID: c.Product.ID, Quantity: c.Quantity, Lot: c.Lot, Serials: c.Serials}
+	if c.Pack != nil {
+		item.PackID = &c.Pack.ID
+	}
+	return item
+}
+
+func NewSalesTab(db *sql.DB, window fyne.Window, terminal string) *SalesView {
 	var (
 		items      []cartItem
 		itemByCode = make(map[string]int)
+		priced     store.PricedCart
 	)
 
 	list := widget.NewList(
@@ -37,10 +83,21 @@ func NewSalesTab(db *sql.DB, window fyne.Window) *SalesView {
 		func() fyne.CanvasObject { return widget.NewLabel("") },
 		func(id widget.ListItemID, item fyne.CanvasObject) {
 			cart := items[id]
-			lineTotal := float64(cart.Quantity) * cart.Product.Price
-			item.(*widget.Label).SetText(
-				fmt.Sprintf("%s x%d @ %.2f = %.2f", cart.Product.Name, cart.Quantity, cart.Product.Price, lineTotal),
-			)
+			unit := cart.Product.Unit
+			if cart.Pack != nil {
+				unit = quantity.Each
+			}
+			unitPrice := cart.unitPrice()
+			lineTotal := cart.Quantity.Times(unitPrice).String()
+			if id < len(priced.Lines) {
+				unitPrice = pricedUnitPrice(priced.Lines[id])
+				lineTotal = pricedLineText(priced.Lines[id])
+			}
+			text := fmt.Sprintf("%s %s @ %s = %s", cart.label(), cart.Quantity.Format(unit), unitPrice, lineTotal)
+			if len(cart.Serials) > 0 {
+				text += "  S/N " + strings.Join(cart.Serials, ", ")
+			}
+			item.(*widget.Label).SetText(text)
 		},
 	)
 
@@ -48,31 +105,64 @@ func NewSalesTab(db *sql.DB, window fyne.Window) *SalesView {
 	customerIDEntry := widget.NewEntry()
 	customerIDEntry.SetPlaceHolder("Customer ID")
 
-	totalLabel := widget.NewLabel("Total: 0.00")
+	// enteredCustomer is the customer entered, nil while there is none.
+	enteredCustomer := func() *int64 {
+		id, err := strconv.ParseInt(strings.TrimSpace(customerIDEntry.Text), 10, 64)
+		if err != nil {
+			return nil
+		}
+		return &id
+	}
+
+	totalLabel := widget.NewLabel(cartTotalsText(store.PricedCart{}))
+
+	saleItems := func() []store.SaleItem {
+		saleItems := make([]store.SaleItem, 0, len(items))
+		for _, item := range items {
+			saleItems = append(saleItems, item.saleItem())
+		}
+		return saleItems
+	}
 
 	clearCart := func() {
 		items = nil
 		itemByCode = make(map[string]int)
+		priced = store.PricedCart{}
 		status.SetText("Scan a barcode to add items.")
-		totalLabel.SetText("Total: 0.00")
+		totalLabel.SetText(cartTotalsText(store.PricedCart{}))
 		list.Refresh()
 	}
 
 	updateTotal := func() {
-		var total float64
-		for _, item := range items {
-			total += float64(item.Quantity) * item.Product.Price
+		cart, err := store.PriceCart(db, enteredCustomer(), saleItems())
+		if err != nil {
+			fmt.Println("Failed to price cart:", err)
+			return
 		}
-		totalLabel.SetText(fmt.Sprintf("Total: %.2f", total))
+		priced = cart
+		totalLabel.SetText(cartTotalsText(cart))
+		list.Refresh()
+	}
+	// Customers on a price list pay its prices, so the cart is priced again
+	// whenever the customer changes.
+	customerIDEntry.OnChanged = func(string) {
+		updateTotal()
 	}
 
-	handleScan := func(barcode string) {
-		if barcode == "" {
+	addItem := func(item cartItem) {
+		if index, ok := itemByCode[item.code()]; ok {
+			items[index].Quantity += item.Quantity
+			items[index].Serials = append(items[index].Serials, item.Serials...)
 			return
 		}
-		product, err := store.GetProductByBarcode(db, barcode)
+		items = append(items, item)
+		itemByCode[item.code()] = len(items) - 1
+	}
+
+	handleScan := func(scan barcode.Scan) {
+		scanned, err := store.LookupScan(db, scan)
 		if err == sql.ErrNoRows {
-			dialog.NewInformation("Unknown Barcode", "No product found for barcode: "+barcode, window).Show()
+			dialog.NewInformation("Unknown Barcode", "No product found for barcode: "+scan.Code, window).Show()
 			return
 		}
 		if err != nil {
@@ -80,15 +170,47 @@ func NewSalesTab(db *sql.DB, window fyne.Window) *SalesView {
 			return
 		}
 
-		if index, ok := itemByCode[barcode]; ok {
-			items[index].Quantity++
-		} else {
-			items = append(items, cartItem{Product: product, Quantity: 1})
-			itemByCode[barcode] = len(items) - 1
+		add := func(product store.Product, qty quantity.Quantity) {
+			addItem(cartItem{Product: product, Quantity: qty, Lot: scannedLot(product, scan)})
+			status.SetText(fmt.Sprintf("Added %s %s", product.Name, qty.Format(product.Unit)))
+			list.Refresh()
+			updateTotal()
 		}
-		status.SetText(fmt.Sprintf("Added %s", product.Name))
-		list.Refresh()
-		updateTotal()
+		// Each scan of a product tracked by serial number adds one unit.
+		addSerial := func(product store.Product) {
+			promptSerial(window, product.Name, scan, func(serial string) {
+				for _, item := range items {
+					if item.Product.ID == product.ID && slices.Contains(item.Serials, serial) {
+						dialog.NewInformation("Already Added", "Serial "+serial+" is already in the cart.", window).Show()
+						return
+					}
+				}
+				addItem(cartItem{Product: product, Quantity: quantity.Of(1), Lot: scannedLot(product, scan), Serials: []string{serial}})
+				status.SetText(fmt.Sprintf("Added %s serial %s", product.Name, serial))
+				list.Refresh()
+				updateTotal()
+			})
+		}
+		if scanned.Pack != nil {
+			addItem(cartItem{Product: scanned.Product, Pack: scanned.Pack, Quantity: quantity.Of(1), Lot: scannedLot(scanned.Product, scan)})
+			status.SetText("Added " + packLabel(scanned.Product, *scanned.Pack))
+			list.Refresh()
+			updateTotal()
+			return
+		}
+		if scanned.Quantity > 0 {
+			add(scanned.Product, scanned.Quantity)
+			return
+		}
+		resolveVariant(db, window, scanned.Product, func(product store.Product) {
+			if product.TrackSerials {
+				addSerial(product)
+				return
+			}
+			promptQuantity(window, product, func(qty quantity.Quantity) {
+				add(product, qty)
+			})
+		})
 	}
 
 	view := &SalesView{}
@@ -108,30 +230,87 @@ func NewSalesTab(db *sql.DB, window fyne.Window) *SalesView {
 			return
 		}
 
-		saleItems := make([]store.SaleItem, 0, len(items))
-		for _, item := range items {
-			saleItems = append(saleItems, store.SaleItem{
-				ProductID: item.Product.ID,
-				Quantity:  item.Quantity,
-			})
+		cart, err := store.PriceCart(db, &customerID, saleItems())
+		if err != nil {
+			dialog.NewError(err, window).Show()
+			return
 		}
 
-		if _, err := store.CreateSale(db, &customerID, saleItems); err != nil {
-			if errors.Is(err, store.ErrInsufficientStock) {
-				dialog.NewInformation("Insufficient Stock", err.Error(), window).Show()
-				return
-			}
+		location, err := store.TerminalLocation(db, terminal)
+		if err != nil {
 			dialog.NewError(err, window).Show()
 			return
 		}
 
-		clearCart()
-		if view.OnSaleCreated != nil {
-			view.OnSaleCreated()
-		}
+		showPaymentDialog(window, cart.Total, func(tenders []store.Tender) error {
+			saleID, err := store.CreateSale(db, location.ID, &customerID, saleItems(), tenders)
+			if err != nil {
+				switch {
+				case errors.Is(err, store.ErrInsufficientStock):
+					dialog.NewInformation("Insufficient Stock", err.Error(), window).Show()
+				case errors.Is(err, store.ErrLotExpired):
+					dialog.NewInformation("Expired Lot", err.Error(), window).Show()
+				case errors.Is(err, store.ErrSerialRequired), errors.Is(err, store.ErrSerialUnavailable):
+					dialog.NewInformation("Serial Number", err.Error(), window).Show()
+				case errors.Is(err, store.ErrInsufficientCredit), errors.Is(err, store.ErrUnderpaid),
+					errors.Is(err, money.ErrCurrencyMismatch):
+					dialog.NewInformation("Payment Declined", err.Error(), window).Show()
+				default:
+					dialog.NewError(err, window).Show()
+				}
+				return err
+			}
+
+			clearCart()
+			if sale, err := store.GetSale(db, saleID); err == nil {
+				dialog.NewInformation("Sale Complete", receiptText(sale), window).Show()
+			} else {
+				fmt.Println("Failed to load receipt:", err)
+			}
+			if view.OnSaleCreated != nil {
+				view.OnSaleCreated()
+			}
+			return nil
+		})
+	})
+
+	parkButton := widget.NewButton("Park", func() {
+		showParkDialog(db, window, enteredCustomer(), saleItems(), func() {
+			clearCart()
+			customerIDEntry.SetText("")
+		})
+	})
+
+	resumeButton := widget.NewButton("Resume", func() {
+		showResumeDialog(db, window, terminal, func(sale store.SuspendedSale) {
+			clearCart()
+			customerIDEntry.SetText("")
+			if sale.CustomerID != nil {
+				customerIDEntry.SetText(strconv.FormatInt(*sale.CustomerID, 10))
+			}
+			for _, saleItem := range sale.Items {
+				product, err := store.GetProduct(db, saleItem.ProductID)
+				if err != nil {
+					fmt.Println("Failed to load product:", err)
+					continue
+				}
+				item := cartItem{Product: product, Quantity: saleItem.Quantity, Lot: saleItem.Lot, Serials: saleItem.Serials}
+				if saleItem.PackID != nil {
+					pack, err := store.GetPack(db, *saleItem.PackID)
+					if err != nil {
+						fmt.Println("Failed to load pack:", err)
+						continue
+					}
+					item.Pack = &pack
+				}
+				addItem(item)
+			}
+			status.SetText("Resumed " + sale.Label)
+			updateTotal()
+		})
 	})
 
-	controls := container.NewHBox(customerIDEntry, createSaleButton, totalLabel)
+	controls := container.NewHBox(customerIDEntry, createSaleButton, parkButton, resumeButton, totalLabel)
 
 	content := container.NewBorder(controls, status, nil, nil, list)
 
@@ -5,12 +5,19 @@ import (
 	"time"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
+
+	"pos-system/internal/barcode"
 )
 
+// ScannerService reads the keyboard-wedge scanner through a hidden entry
+// and hands each decoded barcode to the handler. Readings that fail
+// validation are reported to the cashier and never reach the handler.
 type ScannerService struct {
 	entry    *widget.Entry
-	onScan   func(string)
+	window   fyne.Window
+	onScan   func(barcode.Scan)
 	mu       sync.RWMutex
 	stopOnce sync.Once
 	stopCh   chan struct{}
@@ -27,13 +34,24 @@ func NewScannerService() *ScannerService {
 	}
 
 	entry.OnSubmitted = func(value string) {
+		entry.SetText("")
 		scanner.mu.RLock()
 		handler := scanner.onScan
+		window := scanner.window
 		scanner.mu.RUnlock()
+		if value == "" {
+			return
+		}
+		scan, err := barcode.Parse(value)
+		if err != nil {
+			if window != nil {
+				dialog.NewInformation("Invalid Barcode", err.Error(), window).Show()
+			}
+			return
+		}
 		if handler != nil {
-			handler(value)
+			handler(scan)
 		}
-		entry.SetText("")
 	}
 
 	return scanner
@@ -43,13 +61,16 @@ func (s *ScannerService) Widget() *widget.Entry {
 	return s.entry
 }
 
-func (s *ScannerService) OnScan(handler func(string)) {
+func (s *ScannerService) OnScan(handler func(barcode.Scan)) {
 	s.mu.Lock()
 	s.onScan = handler
 	s.mu.Unlock()
 }
 
 func (s *ScannerService) Start(window fyne.Window) {
+	s.mu.Lock()
+	s.window = window
+	s.mu.Unlock()
 	canvas := window.Canvas()
 	canvas.Focus(s.entry)
 
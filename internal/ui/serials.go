@@ -0,0 +1,108 @@
+package ui
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"pos-system/internal/barcode"
+	"pos-system/internal/store"
+)
+
+var serialStatusLabels = map[store.SerialStatus]string{
+	store.SerialInStock:   "in stock",
+	store.SerialSold:      "sold",
+	store.SerialInTransit: "in transit",
+	store.SerialReturned:  "returned, not restocked",
+	store.SerialRemoved:   "written off",
+}
+
+// serialStatus says what became of a unit, and where it is in stock.
+func serialStatus(serial store.Serial) string {
+	if serial.Status == store.SerialInStock {
+		return serialStatusLabels[serial.Status] + " at " + serial.Location
+	}
+	return serialStatusLabels[serial.Status]
+}
+
+// parseSerials reads serial numbers separated by commas or new lines.
+func parseSerials(text string) []string {
+	var serials []string
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if serial := strings.TrimSpace(field); serial != "" {
+			serials = append(serials, serial)
+		}
+	}
+	return serials
+}
+
+// promptSerial asks for the serial number of a unit being sold, unless the
+// scanned label already carried it.
+func promptSerial(window fyne.Window, name string, scan barcode.Scan, onSerial func(string)) {
+	if scan.Serial != "" {
+		onSerial(scan.Serial)
+		return
+	}
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder("Scan or type the serial number")
+	dialog.ShowForm(name, "Add", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Serial", entry),
+	}, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		serial := strings.TrimSpace(entry.Text)
+		if serial == "" {
+			dialog.NewInformation("Serial Required", name+" needs the serial number of each unit.", window).Show()
+			return
+		}
+		onSerial(serial)
+	}, window)
+}
+
+func showSerialsDialog(db *sql.DB, window fyne.Window, product store.Product) {
+	serials, err := store.ListSerials(db, product.ID)
+	if err != nil {
+		dialog.NewError(err, window).Show()
+		return
+	}
+	if len(serials) == 0 {
+		dialog.NewInformation("Serial Numbers", product.Name+" has no serial numbers recorded.", window).Show()
+		return
+	}
+	list := container.NewVBox()
+	for _, serial := range serials {
+		list.Add(widget.NewLabel(fmt.Sprintf(
+			"%s - %s, received %s", serial.Serial, serialStatus(serial), serial.ReceivedAt.Format(time.DateOnly),
+		)))
+	}
+	serialsDialog := dialog.NewCustom("Serial numbers of "+product.Name, "Close", container.NewVScroll(list), window)
+	serialsDialog.Resize(fyne.NewSize(520, 360))
+	serialsDialog.Show()
+}
+
+// serialHistoryText describes each unit carrying a serial number and the
+// sales it went out on.
+func serialHistoryText(serials []store.Serial) string {
+	var b strings.Builder
+	for _, serial := range serials {
+		fmt.Fprintf(&b, "%s serial %s: %s\n", serial.Name, serial.Serial, serialStatus(serial))
+		for _, sale := range serial.Sales {
+			fmt.Fprintf(&b, "  Sale #%d on %s", sale.SaleID, sale.SoldAt.Format(time.DateOnly))
+			if sale.SaleStatus == store.SaleVoided {
+				b.WriteString(", voided")
+			}
+			if sale.ReturnID != nil {
+				fmt.Fprintf(&b, ", returned on #%d", *sale.ReturnID)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,161 @@
+package ui
+
+import (
+	"database/sql"
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+
+	"pos-system/internal/store"
+	"pos-system/internal/tax"
+)
+
+var taxModeLabels = map[tax.Mode]string{
+	tax.Inclusive: "Prices include tax",
+	tax.Exclusive: "Tax added at checkout",
+}
+
+var costMethodLabels = map[store.CostMethod]string{
+	store.CostLast:    "Last cost",
+	store.CostAverage: "Moving average",
+	store.CostFIFO:    "First in, first out",
+}
+
+func SettingsTab(db *sql.DB, terminal string) fyne.CanvasObject {
+	var classes []store.TaxClass
+	selectedIndex := -1
+
+	modeRadio := widget.NewRadioGroup(
+		[]string{taxModeLabels[tax.Inclusive], taxModeLabels[tax.Exclusive]},
+		nil,
+	)
+	if mode, err := store.GetTaxMode(db); err == nil {
+		modeRadio.SetSelected(taxModeLabels[mode])
+	} else {
+		fmt.Println("Failed to load tax mode:", err)
+	}
+	modeRadio.OnChanged = func(label string) {
+		for mode, modeLabel := range taxModeLabels {
+			if modeLabel == label {
+				if err := store.SetTaxMode(db, mode); err != nil {
+					fmt.Println("Failed to save tax mode:", err)
+				}
+				return
+			}
+		}
+	}
+
+	blockExpired := widget.NewCheck("Refuse to sell expired lots", nil)
+	if block, err := store.BlockExpiredLots(db); err == nil {
+		blockExpired.SetChecked(block)
+	} else {
+		fmt.Println("Failed to load expired lot setting:", err)
+	}
+	blockExpired.OnChanged = func(block bool) {
+		if err := store.SetBlockExpiredLots(db, block); err != nil {
+			fmt.Println("Failed to save expired lot setting:", err)
+		}
+	}
+
+	costOptions := make([]string, 0, len(store.CostMethods))
+	for _, method := range store.CostMethods {
+		costOptions = append(costOptions, costMethodLabels[method])
+	}
+	costRadio := widget.NewRadioGroup(costOptions, nil)
+	if method, err := store.CostingMethod(db); err == nil {
+		costRadio.SetSelected(costMethodLabels[method])
+	} else {
+		fmt.Println("Failed to load costing method:", err)
+	}
+	costRadio.OnChanged = func(label string) {
+		for method, methodLabel := range costMethodLabels {
+			if methodLabel == label {
+				if err := store.SetCostingMethod(db, method); err != nil {
+					fmt.Println("Failed to save costing method:", err)
+				}
+				return
+			}
+		}
+	}
+
+	name := widget.NewEntry()
+	rate := widget.NewEntry()
+	rate.SetPlaceHolder("e.g. 20 or 5.5")
+
+	list := widget.NewList(
+		func() int { return len(classes) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			class := classes[id]
+			item.(*widget.Label).SetText(fmt.Sprintf("%s - %s", class.Name, class.Rate))
+		},
+	)
+
+	refresh := func() {
+		items, err := store.ListTaxClasses(db)
+		if err != nil {
+			fmt.Println("Failed to load tax classes:", err)
+			return
+		}
+		classes = items
+		selectedIndex = -1
+		name.SetText("")
+		rate.SetText("")
+		list.UnselectAll()
+		list.Refresh()
+	}
+
+	list.OnSelected = func(id widget.ListItemID) {
+		selectedIndex = id
+		class := classes[id]
+		name.SetText(class.Name)
+		rate.SetText(fmt.Sprintf("%d.%02d", class.Rate/100, class.Rate%100))
+	}
+
+	updateButton := widget.NewButton("Update", func() {
+		if selectedIndex < 0 || selectedIndex >= len(classes) {
+			return
+		}
+		parsed, err := tax.ParseRate(rate.Text)
+		if err != nil {
+			fmt.Println("Invalid tax rate:", err)
+			return
+		}
+		class := classes[selectedIndex]
+		class.Name = name.Text
+		class.Rate = parsed
+		if err := store.UpdateTaxClass(db, class); err != nil {
+			fmt.Println("Failed to update tax class:", err)
+			return
+		}
+		refresh()
+	})
+
+	refresh()
+
+	form := widget.NewForm(
+		&widget.FormItem{Text: "Name", Widget: name},
+		&widget.FormItem{Text: "Rate %", Widget: rate},
+	)
+	taxControls := container.NewVBox(
+		widget.NewLabel("Pricing mode"),
+		modeRadio,
+		blockExpired,
+		widget.NewLabel("Costing method"),
+		costRadio,
+		widget.NewLabel("Tax class"),
+		form,
+		updateButton,
+		widget.NewSeparator(),
+		locationSettings(db, terminal),
+		layout.NewSpacer(),
+	)
+
+	return container.NewHSplit(
+		container.NewBorder(widget.NewLabel("Tax classes"), nil, nil, nil, list),
+		taxControls,
+	)
+}
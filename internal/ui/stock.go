@@ -0,0 +1,173 @@
+package ui
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"pos-system/internal/money"
+	"pos-system/internal/quantity"
+	"pos-system/internal/store"
+)
+
+var movementLabels = map[store.MovementKind]string{
+	store.MovementSale:       "Sale",
+	store.MovementVoid:       "Void",
+	store.MovementReturn:     "Return",
+	store.MovementReceipt:    "Receipt",
+	store.MovementAdjustment: "Adjustment",
+	store.MovementCount:      "Count correction",
+	store.MovementTransfer:   "Transfer",
+}
+
+// manualMovements are the kinds a user can book from the Products tab.
+var manualMovements = []store.MovementKind{store.MovementReceipt, store.MovementAdjustment, store.MovementCount}
+
+func showAdjustStockDialog(db *sql.DB, window fyne.Window, product store.Product, onDone func()) {
+	locations, err := store.ListLocations(db)
+	if err != nil {
+		dialog.NewError(err, window).Show()
+		return
+	}
+	locationSelect := widget.NewSelect(locationOptions(locations), nil)
+	selectLocation(locationSelect, locations, store.DefaultLocationID)
+	options := make([]string, 0, len(manualMovements))
+	for _, kind := range manualMovements {
+		options = append(options, movementLabels[kind])
+	}
+	kindSelect := widget.NewSelect(options, nil)
+	kindSelect.SetSelectedIndex(0)
+	quantityEntry := widget.NewEntry()
+	quantityEntry.SetPlaceHolder("Quantity in (negative for out), or counted")
+	reasonEntry := widget.NewEntry()
+	referenceEntry := widget.NewEntry()
+	userEntry := widget.NewEntry()
+	lotEntry := widget.NewEntry()
+	lotEntry.SetPlaceHolder("Required for receipts and counts")
+	expiryEntry := widget.NewEntry()
+	expiryEntry.SetPlaceHolder("YYYY-MM-DD")
+	serialsEntry := widget.NewMultiLineEntry()
+	serialsEntry.SetPlaceHolder("One serial number per unit")
+	unitCostEntry := widget.NewEntry()
+	unitCostEntry.SetPlaceHolder("Goods in only; cost price " + product.Cost.String() + " if empty")
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Type", kindSelect),
+		widget.NewFormItem("Location", locationSelect),
+		widget.NewFormItem("Quantity", quantityEntry),
+		widget.NewFormItem("Unit cost", unitCostEntry),
+		widget.NewFormItem("Reason", reasonEntry),
+		widget.NewFormItem("Reference", referenceEntry),
+		widget.NewFormItem("User", userEntry),
+	}
+	if product.TrackLots {
+		items = append(items, widget.NewFormItem("Lot", lotEntry), widget.NewFormItem("Expiry", expiryEntry))
+	}
+	if product.TrackSerials {
+		items = append(items, widget.NewFormItem("Serials", serialsEntry))
+	}
+	title := fmt.Sprintf("Stock for %s (on hand %s)", product.Name, product.Stock)
+	dialog.ShowForm(title, "Save", "Cancel", items, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		qty, err := quantity.Parse(quantityEntry.Text)
+		if err != nil {
+			dialog.NewInformation("Invalid Quantity", "Quantity must be a number.", window).Show()
+			return
+		}
+		expiry, err := parseOptionalDate(expiryEntry.Text)
+		if err != nil {
+			dialog.NewInformation("Invalid Date", "Expiry must be a date like 2025-12-31.", window).Show()
+			return
+		}
+		var unitCost *money.Money
+		if text := strings.TrimSpace(unitCostEntry.Text); text != "" {
+			cost, err := money.Parse(text, product.Price.Currency)
+			if err != nil {
+				dialog.NewInformation("Invalid Cost", "Unit cost must be an amount.", window).Show()
+				return
+			}
+			unitCost = &cost
+		}
+		kind := manualMovements[kindSelect.SelectedIndex()]
+		locationID := selectedLocation(locationSelect, locations)
+		reason := strings.TrimSpace(reasonEntry.Text)
+		user := strings.TrimSpace(userEntry.Text)
+		if kind == store.MovementCount {
+			_, err = store.CorrectStockCount(db, product.ID, locationID, qty, strings.TrimSpace(lotEntry.Text), parseSerials(serialsEntry.Text), reason, user)
+		} else {
+			err = store.AdjustStock(db, store.StockMovement{
+				ProductID:  product.ID,
+				LocationID: locationID,
+				Kind:       kind,
+				Quantity:   qty,
+				Reason:     reason,
+				Reference:  strings.TrimSpace(referenceEntry.Text),
+				User:       user,
+				Lot:        strings.TrimSpace(lotEntry.Text),
+				Expiry:     expiry,
+				Serials:    parseSerials(serialsEntry.Text),
+				UnitCost:   unitCost,
+			})
+		}
+		if err != nil {
+			dialog.NewError(err, window).Show()
+			return
+		}
+		onDone()
+	}, window)
+}
+
+func showStockHistoryDialog(db *sql.DB, window fyne.Window, product store.Product) {
+	movements, err := store.ListStockMovements(db, product.ID)
+	if err != nil {
+		dialog.NewError(err, window).Show()
+		return
+	}
+	list := widget.NewList(
+		func() int { return len(movements) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			movement := movements[id]
+			text := fmt.Sprintf(
+				"%s  %s %s  %s", movement.CreatedAt.Format(time.DateTime), movementLabels[movement.Kind], signed(movement.Quantity), movement.Location,
+			)
+			if movement.Lot != "" {
+				text += "  lot " + movement.Lot
+			}
+			for _, detail := range []string{movement.Reference, movement.Reason, movement.User} {
+				if detail != "" {
+					text += "  " + detail
+				}
+			}
+			item.(*widget.Label).SetText(text)
+		},
+	)
+	history := dialog.NewCustom("Stock history: "+product.Name, "Close", list, window)
+	history.Resize(fyne.NewSize(560, 400))
+	history.Show()
+}
+
+func showReconcileDialog(db *sql.DB, window fyne.Window) {
+	discrepancies, err := store.ReconcileStock(db)
+	if err != nil {
+		dialog.NewError(err, window).Show()
+		return
+	}
+	if len(discrepancies) == 0 {
+		dialog.NewInformation("Stock Reconciled", "Stock on hand matches the movement ledger for every product.", window).Show()
+		return
+	}
+	var b strings.Builder
+	b.WriteString("These products do not match their movement ledger:\n")
+	for _, discrepancy := range discrepancies {
+		fmt.Fprintf(&b, "\n%d - %s: on hand %s, ledger %s", discrepancy.ProductID, discrepancy.Name, discrepancy.OnHand, discrepancy.Ledger)
+	}
+	dialog.NewInformation("Stock Discrepancies", b.String(), window).Show()
+}
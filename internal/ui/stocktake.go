@@ -0,0 +1,310 @@
+package ui
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+
+	"pos-system/internal/barcode"
+	"pos-system/internal/quantity"
+	"pos-system/internal/store"
+)
+
+var countStatusLabels = map[store.CountStatus]string{
+	store.CountOpen:      "Open",
+	store.CountApproved:  "Approved",
+	store.CountCancelled: "Cancelled",
+}
+
+type StocktakeView struct {
+	Tab            *container.TabItem
+	Refresh        func()
+	HandleScan     func(barcode.Scan)
+	OnStockChanged func()
+}
+
+func NewStocktakeTab(db *sql.DB, window fyne.Window) *StocktakeView {
+	var (
+		counts        []store.StockCount
+		current       store.StockCount
+		categoryIDs   []int64
+		locations     []store.Location
+		selectedCount = -1
+		selectedLine  = -1
+	)
+	view := &StocktakeView{}
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("Count name")
+	scopeSelect := widget.NewSelect(nil, nil)
+	locationSelect := widget.NewSelect(nil, nil)
+	countedEntry := widget.NewEntry()
+	countedEntry.SetPlaceHolder("Counted")
+	userEntry := widget.NewEntry()
+	userEntry.SetPlaceHolder("User")
+	detail := widget.NewLabel("Select a stock count.")
+
+	lineList := widget.NewList(
+		func() int { return len(current.Lines) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			line := current.Lines[id]
+			text := fmt.Sprintf("%s: expected %s, not counted", line.Name, line.Expected.Format(line.Unit))
+			if line.Counted != nil {
+				text = fmt.Sprintf(
+					"%s: expected %s, counted %s, variance %s (%s)",
+					line.Name, line.Expected.Format(line.Unit), line.Counted.Format(line.Unit), signed(line.Variance()), line.VarianceValue(),
+				)
+			}
+			item.(*widget.Label).SetText(text)
+		},
+	)
+	lineList.OnSelected = func(id widget.ListItemID) {
+		selectedLine = id
+		countedEntry.SetText("")
+		if counted := current.Lines[id].Counted; counted != nil {
+			countedEntry.SetText(counted.String())
+		}
+	}
+
+	showDetail := func() {
+		selectedLine = -1
+		lineList.UnselectAll()
+		if current.ID == 0 {
+			detail.SetText("Select a stock count.")
+			lineList.Refresh()
+			return
+		}
+		counted := 0
+		for _, line := range current.Lines {
+			if line.Counted != nil {
+				counted++
+			}
+		}
+		text := fmt.Sprintf(
+			"Count #%d %s at %s - %s, %d of %d counted, variance value %s",
+			current.ID, current.Name, current.Location, countStatusLabels[current.Status], counted, len(current.Lines), current.VarianceValue(),
+		)
+		if current.ClosedAt != nil {
+			text += fmt.Sprintf("\nClosed %s by %s", current.ClosedAt.Format(time.DateTime), current.ClosedBy)
+		}
+		detail.SetText(text)
+		lineList.Refresh()
+	}
+
+	loadCurrent := func() {
+		current = store.StockCount{}
+		if selectedCount >= 0 && selectedCount < len(counts) {
+			count, err := store.GetStockCount(db, counts[selectedCount].ID)
+			if err != nil {
+				fmt.Println("Failed to load stock count:", err)
+			} else {
+				current = count
+			}
+		}
+		showDetail()
+	}
+
+	countList := widget.NewList(
+		func() int { return len(counts) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			count := counts[id]
+			item.(*widget.Label).SetText(fmt.Sprintf("#%d %s (%s) - %s", count.ID, count.Name, count.Location, countStatusLabels[count.Status]))
+		},
+	)
+	countList.OnSelected = func(id widget.ListItemID) {
+		selectedCount = id
+		loadCurrent()
+	}
+
+	// reload keeps the same count selected after it changes.
+	reload := func() {
+		selectedID := current.ID
+		items, err := store.ListStockCounts(db)
+		if err != nil {
+			fmt.Println("Failed to load stock counts:", err)
+			return
+		}
+		counts = items
+		selectedCount = -1
+		for i, count := range counts {
+			if count.ID == selectedID {
+				selectedCount = i
+			}
+		}
+		countList.Refresh()
+		if selectedCount >= 0 {
+			countList.Select(selectedCount)
+		} else {
+			countList.UnselectAll()
+		}
+		loadCurrent()
+	}
+
+	view.Refresh = func() {
+		categories, err := store.ListCategories(db)
+		if err != nil {
+			fmt.Println("Failed to load categories:", err)
+			return
+		}
+		locationItems, err := store.ListLocations(db)
+		if err != nil {
+			fmt.Println("Failed to load locations:", err)
+			return
+		}
+		locations = locationItems
+		locationSelect.SetOptions(locationOptions(locations))
+		selectLocation(locationSelect, locations, store.DefaultLocationID)
+		var labels []string
+		labels, categoryIDs = categoryOptions(categories)
+		scopeSelect.SetOptions(append([]string{"All products"}, labels...))
+		scopeSelect.SetSelectedIndex(0)
+		reload()
+	}
+
+	showError := func(err error) {
+		dialog.NewInformation("Stock Count", err.Error(), window).Show()
+	}
+
+	startButton := widget.NewButton("Start Count", func() {
+		var categoryID *int64
+		if index := scopeSelect.SelectedIndex(); index > 0 && index <= len(categoryIDs) {
+			categoryID = &categoryIDs[index-1]
+		}
+		id, err := store.StartStockCount(db, strings.TrimSpace(nameEntry.Text), selectedLocation(locationSelect, locations), categoryID)
+		if err != nil {
+			showError(err)
+			return
+		}
+		nameEntry.SetText("")
+		current = store.StockCount{ID: id}
+		reload()
+	})
+
+	setCountButton := widget.NewButton("Set Count", func() {
+		if selectedLine < 0 || selectedLine >= len(current.Lines) {
+			return
+		}
+		counted, err := quantity.Parse(countedEntry.Text)
+		if err != nil {
+			dialog.NewInformation("Invalid Quantity", "Quantity must be a number.", window).Show()
+			return
+		}
+		if err := store.SetCountedQuantity(db, current.ID, current.Lines[selectedLine].ProductID, counted); err != nil {
+			showError(err)
+			return
+		}
+		countedEntry.SetText("")
+		loadCurrent()
+	})
+
+	approveButton := widget.NewButton("Approve", func() {
+		if current.ID == 0 {
+			return
+		}
+		message := fmt.Sprintf("Post the counted variances (%s) to stock? Uncounted products are left unchanged.", current.VarianceValue())
+		dialog.ShowConfirm("Approve Count", message, func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			if err := store.ApproveStockCount(db, current.ID, strings.TrimSpace(userEntry.Text)); err != nil {
+				showError(err)
+				return
+			}
+			reload()
+			if view.OnStockChanged != nil {
+				view.OnStockChanged()
+			}
+		}, window)
+	})
+
+	cancelButton := widget.NewButton("Cancel Count", func() {
+		if current.ID == 0 {
+			return
+		}
+		dialog.ShowConfirm("Cancel Count", "Discard this count? Stock is left unchanged.", func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			if err := store.CancelStockCount(db, current.ID, strings.TrimSpace(userEntry.Text)); err != nil {
+				showError(err)
+				return
+			}
+			reload()
+		}, window)
+	})
+
+	// Each scan counts one unit, or one pack, or the weight on the label.
+	view.HandleScan = func(scan barcode.Scan) {
+		if current.ID == 0 || current.Status != store.CountOpen {
+			dialog.NewInformation("Stock Count", "Select an open stock count before scanning.", window).Show()
+			return
+		}
+		item, err := store.LookupScan(db, scan)
+		if err == sql.ErrNoRows {
+			dialog.NewInformation("Unknown Barcode", "No product found for barcode: "+scan.Code, window).Show()
+			return
+		}
+		if err != nil {
+			dialog.NewError(err, window).Show()
+			return
+		}
+		count := func(product store.Product, qty quantity.Quantity) {
+			if err := store.CountItem(db, current.ID, product.ID, qty); err != nil {
+				showError(err)
+				return
+			}
+			loadCurrent()
+		}
+		if item.Pack != nil {
+			count(item.Product, item.Pack.Factor)
+			return
+		}
+		if item.Quantity > 0 {
+			count(item.Product, item.Quantity)
+			return
+		}
+		resolveVariant(db, window, item.Product, func(product store.Product) {
+			promptQuantity(window, product, func(qty quantity.Quantity) {
+				count(product, qty)
+			})
+		})
+	}
+
+	newCountForm := widget.NewForm(
+		&widget.FormItem{Text: "Name", Widget: nameEntry},
+		&widget.FormItem{Text: "Location", Widget: locationSelect},
+		&widget.FormItem{Text: "Scope", Widget: scopeSelect},
+	)
+	countForm := widget.NewForm(
+		&widget.FormItem{Text: "Counted", Widget: countedEntry},
+		&widget.FormItem{Text: "User", Widget: userEntry},
+	)
+	controls := container.NewVBox(
+		newCountForm,
+		startButton,
+		widget.NewSeparator(),
+		widget.NewLabel("Scan items to count them, or select a line and type its count."),
+		countForm,
+		container.NewHBox(setCountButton, approveButton, cancelButton),
+		layout.NewSpacer(),
+	)
+	countPane := container.NewBorder(detail, nil, nil, nil, lineList)
+
+	view.Refresh()
+
+	content := container.NewHSplit(
+		countList,
+		container.NewHSplit(countPane, controls),
+	)
+	view.Tab = container.NewTabItem("Stocktake", content)
+	return view
+}
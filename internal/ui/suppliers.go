@@ -0,0 +1,125 @@
+package ui
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+
+	"pos-system/internal/store"
+)
+
+type supplierForm struct {
+	name  *widget.Entry
+	email *widget.Entry
+	phone *widget.Entry
+}
+
+func SuppliersTab(db *sql.DB) fyne.CanvasObject {
+	var suppliers []store.Supplier
+	selectedIndex := -1
+
+	form := supplierForm{
+		name:  widget.NewEntry(),
+		email: widget.NewEntry(),
+		phone: widget.NewEntry(),
+	}
+
+	refresh := func(list *widget.List) {
+		items, err := store.ListSuppliers(db)
+		if err != nil {
+			fmt.Println("Failed to load suppliers:", err)
+			return
+		}
+		suppliers = items
+		selectedIndex = -1
+		form.name.SetText("")
+		form.email.SetText("")
+		form.phone.SetText("")
+		list.Refresh()
+	}
+
+	list := widget.NewList(
+		func() int { return len(suppliers) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			supplier := suppliers[id]
+			item.(*widget.Label).SetText(fmt.Sprintf("%d - %s", supplier.ID, supplier.Name))
+		},
+	)
+
+	addButton := widget.NewButton("Add", func() {
+		if form.name.Text == "" {
+			return
+		}
+		_, err := store.CreateSupplier(db, store.Supplier{
+			Name:  form.name.Text,
+			Email: form.email.Text,
+			Phone: form.phone.Text,
+		})
+		if err != nil {
+			fmt.Println("Failed to create supplier:", err)
+			return
+		}
+		refresh(list)
+	})
+
+	updateButton := widget.NewButton("Update", func() {
+		if selectedIndex < 0 || selectedIndex >= len(suppliers) {
+			return
+		}
+		supplier := suppliers[selectedIndex]
+		supplier.Name = form.name.Text
+		supplier.Email = form.email.Text
+		supplier.Phone = form.phone.Text
+		if err := store.UpdateSupplier(db, supplier); err != nil {
+			fmt.Println("Failed to update supplier:", err)
+			return
+		}
+		refresh(list)
+	})
+
+	deleteButton := widget.NewButton("Delete", func() {
+		if selectedIndex < 0 || selectedIndex >= len(suppliers) {
+			return
+		}
+		if err := store.DeleteSupplier(db, suppliers[selectedIndex].ID); err != nil {
+			fmt.Println("Failed to delete supplier:", err)
+			return
+		}
+		refresh(list)
+	})
+
+	formItems := []*widget.FormItem{
+		{Text: "Name", Widget: form.name},
+		{Text: "Email", Widget: form.email},
+		{Text: "Phone", Widget: form.phone},
+	}
+	formWidget := widget.NewForm(formItems...)
+
+	buttons := container.NewHBox(addButton, updateButton, deleteButton)
+	controls := container.NewVBox(formWidget, buttons)
+
+	footer := widget.NewLabel("Selected: none")
+	list.OnSelected = func(id widget.ListItemID) {
+		selectedIndex = id
+		supplier := suppliers[id]
+		form.name.SetText(supplier.Name)
+		form.email.SetText(supplier.Email)
+		form.phone.SetText(supplier.Phone)
+		footer.SetText("Selected ID: " + strconv.FormatInt(supplier.ID, 10))
+	}
+
+	refresh(list)
+
+	return container.NewBorder(nil, footer, nil, nil,
+		container.NewHSplit(
+			container.NewBorder(nil, nil, nil, nil, list),
+			container.NewVBox(controls, layout.NewSpacer()),
+		),
+	)
+}
@@ -0,0 +1,369 @@
+package ui
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+
+	"pos-system/internal/quantity"
+	"pos-system/internal/store"
+)
+
+var transferStatusLabels = map[store.TransferStatus]string{
+	store.TransferDraft:      "Draft",
+	store.TransferDispatched: "In transit",
+	store.TransferReceived:   "Received",
+	store.TransferCancelled:  "Cancelled",
+}
+
+type TransfersView struct {
+	Tab            *container.TabItem
+	Refresh        func()
+	OnStockChanged func()
+}
+
+func NewTransfersTab(db *sql.DB, window fyne.Window) *TransfersView {
+	var (
+		transfers        []store.Transfer
+		locations        []store.Location
+		products         []store.Product
+		selectedTransfer = -1
+		selectedLine     = -1
+		// receipts holds what arrived of lines that differ from dispatch.
+		receipts = make(map[int64]store.TransferReceipt)
+	)
+	view := &TransfersView{}
+
+	fromSelect := widget.NewSelect(nil, nil)
+	toSelect := widget.NewSelect(nil, nil)
+	referenceEntry := widget.NewEntry()
+	referenceEntry.SetPlaceHolder("Reference")
+	productSelect := widget.NewSelect(nil, nil)
+	quantityEntry := widget.NewEntry()
+	quantityEntry.SetPlaceHolder("Qty")
+	serialsEntry := widget.NewMultiLineEntry()
+	serialsEntry.SetPlaceHolder("One serial number per unit, for products tracked by serial")
+	receivedEntry := widget.NewEntry()
+	receivedEntry.SetPlaceHolder("Qty received")
+	receivedSerialsEntry := widget.NewMultiLineEntry()
+	receivedSerialsEntry.SetPlaceHolder("Serial numbers that arrived, when not all did")
+	noteEntry := widget.NewEntry()
+	noteEntry.SetPlaceHolder("Reason for any difference")
+	userEntry := widget.NewEntry()
+	userEntry.SetPlaceHolder("User")
+	detail := widget.NewLabel("Select a transfer.")
+
+	currentTransfer := func() (store.Transfer, bool) {
+		if selectedTransfer < 0 || selectedTransfer >= len(transfers) {
+			return store.Transfer{}, false
+		}
+		return transfers[selectedTransfer], true
+	}
+
+	lineList := widget.NewList(
+		func() int {
+			transfer, ok := currentTransfer()
+			if !ok {
+				return 0
+			}
+			return len(transfer.Lines)
+		},
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			transfer, _ := currentTransfer()
+			line := transfer.Lines[id]
+			text := fmt.Sprintf("%s: %s", line.Name, line.Quantity.Format(line.Unit))
+			if len(line.Serials) > 0 {
+				text += " (S/N " + strings.Join(line.Serials, ", ") + ")"
+			}
+			if receipt, ok := receipts[line.ID]; ok {
+				text += fmt.Sprintf(", arriving %s (%s)", receipt.Quantity.Format(line.Unit), receipt.Note)
+			}
+			if line.Received != nil {
+				text += ", received " + line.Received.Format(line.Unit)
+				if line.Discrepancy() != 0 {
+					text += fmt.Sprintf(", discrepancy %s (%s)", signed(line.Discrepancy()), line.Note)
+				}
+			}
+			item.(*widget.Label).SetText(text)
+		},
+	)
+	lineList.OnSelected = func(id widget.ListItemID) {
+		selectedLine = id
+		transfer, _ := currentTransfer()
+		line := transfer.Lines[id]
+		receivedEntry.SetText(line.Quantity.String())
+		noteEntry.SetText("")
+		receivedSerialsEntry.SetText("")
+		if receipt, ok := receipts[line.ID]; ok {
+			receivedEntry.SetText(receipt.Quantity.String())
+			noteEntry.SetText(receipt.Note)
+			receivedSerialsEntry.SetText(strings.Join(receipt.Serials, "\n"))
+		}
+	}
+
+	showDetail := func() {
+		selectedLine = -1
+		receipts = make(map[int64]store.TransferReceipt)
+		lineList.UnselectAll()
+		lineList.Refresh()
+		transfer, ok := currentTransfer()
+		if !ok {
+			detail.SetText("Select a transfer.")
+			return
+		}
+		text := fmt.Sprintf(
+			"Transfer #%d from %s to %s - %s",
+			transfer.ID, transfer.FromLocation, transfer.ToLocation, transferStatusLabels[transfer.Status],
+		)
+		if transfer.Reference != "" {
+			text += "\nReference: " + transfer.Reference
+		}
+		if transfer.DispatchedBy != "" {
+			text += "\nDispatched by " + transfer.DispatchedBy
+		}
+		if transfer.ReceivedBy != "" {
+			text += "\nReceived by " + transfer.ReceivedBy
+		}
+		detail.SetText(text)
+	}
+
+	transferList := widget.NewList(
+		func() int { return len(transfers) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			transfer := transfers[id]
+			item.(*widget.Label).SetText(fmt.Sprintf(
+				"#%d %s to %s - %s", transfer.ID, transfer.FromLocation, transfer.ToLocation, transferStatusLabels[transfer.Status],
+			))
+		},
+	)
+	transferList.OnSelected = func(id widget.ListItemID) {
+		selectedTransfer = id
+		showDetail()
+	}
+
+	// reload keeps the same transfer selected after it changes.
+	reload := func() {
+		selectedID := int64(0)
+		if transfer, ok := currentTransfer(); ok {
+			selectedID = transfer.ID
+		}
+		items, err := store.ListTransfers(db)
+		if err != nil {
+			fmt.Println("Failed to load transfers:", err)
+			return
+		}
+		transfers = items
+		selectedTransfer = -1
+		for i, transfer := range transfers {
+			if transfer.ID == selectedID {
+				selectedTransfer = i
+			}
+		}
+		transferList.Refresh()
+		if selectedTransfer >= 0 {
+			transferList.Select(selectedTransfer)
+		} else {
+			transferList.UnselectAll()
+		}
+		showDetail()
+	}
+
+	view.Refresh = func() {
+		locationItems, err := store.ListLocations(db)
+		if err != nil {
+			fmt.Println("Failed to load locations:", err)
+			return
+		}
+		productItems, err := store.ListProducts(db)
+		if err != nil {
+			fmt.Println("Failed to load products:", err)
+			return
+		}
+		locations = locationItems
+		products = productItems
+		fromSelect.SetOptions(locationOptions(locations))
+		toSelect.SetOptions(locationOptions(locations))
+		productOptions := make([]string, 0, len(products))
+		for _, product := range products {
+			productOptions = append(productOptions, fmt.Sprintf("%d - %s", product.ID, product.Name))
+		}
+		productSelect.SetOptions(productOptions)
+		reload()
+	}
+
+	showError := func(err error) {
+		dialog.NewInformation("Transfer", err.Error(), window).Show()
+	}
+
+	newTransferButton := widget.NewButton("New Transfer", func() {
+		if fromSelect.SelectedIndex() < 0 || toSelect.SelectedIndex() < 0 {
+			dialog.NewInformation("Locations Required", "Choose where the stock comes from and goes to.", window).Show()
+			return
+		}
+		id, err := store.CreateTransfer(
+			db,
+			selectedLocation(fromSelect, locations),
+			selectedLocation(toSelect, locations),
+			strings.TrimSpace(referenceEntry.Text),
+		)
+		if err != nil {
+			showError(err)
+			return
+		}
+		referenceEntry.SetText("")
+		transfers = append([]store.Transfer{{ID: id}}, transfers...)
+		selectedTransfer = 0
+		reload()
+	})
+
+	addLineButton := widget.NewButton("Add Line", func() {
+		transfer, ok := currentTransfer()
+		index := productSelect.SelectedIndex()
+		if !ok || index < 0 || index >= len(products) {
+			return
+		}
+		qty, err := quantity.Parse(quantityEntry.Text)
+		if err != nil {
+			dialog.NewInformation("Invalid Quantity", "Quantity must be a number.", window).Show()
+			return
+		}
+		if _, err := store.AddTransferLine(db, transfer.ID, products[index].ID, qty, parseSerials(serialsEntry.Text)); err != nil {
+			showError(err)
+			return
+		}
+		quantityEntry.SetText("")
+		serialsEntry.SetText("")
+		reload()
+	})
+
+	removeLineButton := widget.NewButton("Remove Line", func() {
+		transfer, ok := currentTransfer()
+		if !ok || selectedLine < 0 || selectedLine >= len(transfer.Lines) {
+			return
+		}
+		if err := store.RemoveTransferLine(db, transfer.ID, transfer.Lines[selectedLine].ID); err != nil {
+			showError(err)
+			return
+		}
+		reload()
+	})
+
+	stockChanged := func() {
+		reload()
+		if view.OnStockChanged != nil {
+			view.OnStockChanged()
+		}
+	}
+
+	dispatchButton := widget.NewButton("Dispatch", func() {
+		transfer, ok := currentTransfer()
+		if !ok {
+			return
+		}
+		if err := store.DispatchTransfer(db, transfer.ID, strings.TrimSpace(userEntry.Text)); err != nil {
+			showError(err)
+			return
+		}
+		stockChanged()
+	})
+
+	cancelButton := widget.NewButton("Cancel Transfer", func() {
+		transfer, ok := currentTransfer()
+		if !ok {
+			return
+		}
+		if err := store.CancelTransfer(db, transfer.ID); err != nil {
+			showError(err)
+			return
+		}
+		reload()
+	})
+
+	setReceivedButton := widget.NewButton("Set Received", func() {
+		transfer, ok := currentTransfer()
+		if !ok || selectedLine < 0 || selectedLine >= len(transfer.Lines) {
+			return
+		}
+		received, err := quantity.Parse(receivedEntry.Text)
+		if err != nil {
+			dialog.NewInformation("Invalid Quantity", "Quantity must be a number.", window).Show()
+			return
+		}
+		line := transfer.Lines[selectedLine]
+		if received == line.Quantity {
+			delete(receipts, line.ID)
+		} else {
+			receipts[line.ID] = store.TransferReceipt{
+				LineID:   line.ID,
+				Quantity: received,
+				Note:     strings.TrimSpace(noteEntry.Text),
+				Serials:  parseSerials(receivedSerialsEntry.Text),
+			}
+		}
+		lineList.Refresh()
+	})
+
+	receiveButton := widget.NewButton("Receive Transfer", func() {
+		transfer, ok := currentTransfer()
+		if !ok {
+			return
+		}
+		lines := make([]store.TransferReceipt, 0, len(receipts))
+		for _, receipt := range receipts {
+			lines = append(lines, receipt)
+		}
+		if err := store.ReceiveTransfer(db, transfer.ID, lines, strings.TrimSpace(userEntry.Text)); err != nil {
+			showError(err)
+			return
+		}
+		stockChanged()
+	})
+
+	newTransferForm := widget.NewForm(
+		&widget.FormItem{Text: "From", Widget: fromSelect},
+		&widget.FormItem{Text: "To", Widget: toSelect},
+		&widget.FormItem{Text: "Reference", Widget: referenceEntry},
+	)
+	lineForm := widget.NewForm(
+		&widget.FormItem{Text: "Product", Widget: productSelect},
+		&widget.FormItem{Text: "Quantity", Widget: quantityEntry},
+		&widget.FormItem{Text: "Serials", Widget: serialsEntry},
+	)
+	receiveForm := widget.NewForm(
+		&widget.FormItem{Text: "Received", Widget: receivedEntry},
+		&widget.FormItem{Text: "Serials", Widget: receivedSerialsEntry},
+		&widget.FormItem{Text: "Note", Widget: noteEntry},
+		&widget.FormItem{Text: "User", Widget: userEntry},
+	)
+
+	controls := container.NewVBox(
+		newTransferForm,
+		newTransferButton,
+		widget.NewSeparator(),
+		lineForm,
+		container.NewHBox(addLineButton, removeLineButton, dispatchButton, cancelButton),
+		widget.NewSeparator(),
+		widget.NewLabel("Lines arrive in full unless set otherwise."),
+		receiveForm,
+		container.NewHBox(setReceivedButton, receiveButton),
+		layout.NewSpacer(),
+	)
+	transferPane := container.NewBorder(detail, nil, nil, nil, lineList)
+
+	view.Refresh()
+
+	content := container.NewHSplit(
+		transferList,
+		container.NewHSplit(transferPane, controls),
+	)
+	view.Tab = container.NewTabItem("Transfers", content)
+	return view
+}
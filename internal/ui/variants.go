@@ -0,0 +1,161 @@
+package ui
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"pos-system/internal/money"
+	"pos-system/internal/store"
+)
+
+// resolveVariant hands product to onResolved, unless it is a parent with
+// variants, in which case the cashier picks the exact variant first.
+func resolveVariant(db *sql.DB, window fyne.Window, product store.Product, onResolved func(store.Product)) {
+	variants, err := store.ListVariants(db, product.ID)
+	if err != nil {
+		dialog.NewError(err, window).Show()
+		return
+	}
+	if len(variants) == 0 {
+		onResolved(product)
+		return
+	}
+
+	var picker dialog.Dialog
+	list := widget.NewList(
+		func() int { return len(variants) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			variant := variants[id]
+			item.(*widget.Label).SetText(fmt.Sprintf("%s - %s (%s in stock)", variant.Name, variant.Price, variant.Stock))
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		picker.Hide()
+		onResolved(variants[id].Product)
+	}
+	picker = dialog.NewCustom("Choose "+product.Name, "Cancel", list, window)
+	picker.Resize(fyne.NewSize(420, 360))
+	picker.Show()
+}
+
+// parseAttributes reads "Size=M, Colour=Red".
+func parseAttributes(text string) ([]store.VariantAttribute, error) {
+	var attributes []store.VariantAttribute
+	for _, field := range strings.Split(text, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(field, "=")
+		if !ok || strings.TrimSpace(name) == "" || strings.TrimSpace(value) == "" {
+			return nil, fmt.Errorf("attribute %q should look like Size=M", field)
+		}
+		attributes = append(attributes, store.VariantAttribute{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+	}
+	if len(attributes) == 0 {
+		return nil, fmt.Errorf("enter at least one attribute, e.g. Size=M, Colour=Red")
+	}
+	return attributes, nil
+}
+
+func showVariantsDialog(db *sql.DB, window fyne.Window, parent store.Product, onChanged func()) {
+	var variants []store.Variant
+
+	list := widget.NewList(
+		func() int { return len(variants) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			variant := variants[id]
+			attributes := make([]string, 0, len(variant.Attributes))
+			for _, attribute := range variant.Attributes {
+				attributes = append(attributes, attribute.Name+"="+attribute.Value)
+			}
+			price := variant.Price.String()
+			if !variant.PriceOverride {
+				price += " (inherited)"
+			}
+			item.(*widget.Label).SetText(fmt.Sprintf(
+				"%d - %s | %s | %s | stock %s", variant.ID, strings.Join(attributes, ", "), variant.Barcode, price, variant.Stock,
+			))
+		},
+	)
+	reload := func() {
+		items, err := store.ListVariants(db, parent.ID)
+		if err != nil {
+			fmt.Println("Failed to load variants:", err)
+			return
+		}
+		variants = items
+		list.Refresh()
+	}
+
+	attributesEntry := widget.NewEntry()
+	attributesEntry.SetPlaceHolder("Size=M, Colour=Red")
+	barcodeEntry := widget.NewEntry()
+	priceEntry := widget.NewEntry()
+	priceEntry.SetPlaceHolder("Blank to use " + parent.Price.String())
+	stockEntry := widget.NewEntry()
+	stockEntry.SetPlaceHolder("0")
+
+	addButton := widget.NewButton("Add Variant", func() {
+		attributes, err := parseAttributes(attributesEntry.Text)
+		if err != nil {
+			dialog.NewInformation("Invalid Attributes", err.Error(), window).Show()
+			return
+		}
+		variant := store.Variant{Attributes: attributes}
+		variant.Barcode = strings.TrimSpace(barcodeEntry.Text)
+		if text := strings.TrimSpace(priceEntry.Text); text != "" {
+			price, err := money.Parse(text, parent.Price.Currency)
+			if err != nil {
+				dialog.NewInformation("Invalid Price", err.Error(), window).Show()
+				return
+			}
+			variant.Price = price
+			variant.PriceOverride = true
+		}
+		if variant.Stock, err = parseOptionalQuantity(stockEntry.Text); err != nil {
+			dialog.NewInformation("Invalid Stock", "Opening stock must be a number.", window).Show()
+			return
+		}
+		if _, err := store.CreateVariant(db, parent.ID, variant); err != nil {
+			dialog.NewError(err, window).Show()
+			return
+		}
+		attributesEntry.SetText("")
+		barcodeEntry.SetText("")
+		priceEntry.SetText("")
+		stockEntry.SetText("")
+		reload()
+		onChanged()
+	})
+
+	form := widget.NewForm(
+		widget.NewFormItem("Attributes", attributesEntry),
+		widget.NewFormItem("Barcode", barcodeEntry),
+		widget.NewFormItem("Price", priceEntry),
+		widget.NewFormItem("Opening Stock", stockEntry),
+	)
+	footer := widget.NewLabel("Edit or delete variants from the product list.")
+	content := container.NewBorder(nil, container.NewVBox(form, addButton, footer), nil, nil, list)
+
+	reload()
+	variantsDialog := dialog.NewCustom("Variants of "+parent.Name, "Close", content, window)
+	variantsDialog.Resize(fyne.NewSize(640, 480))
+	variantsDialog.Show()
+}
+
+func variantPrefix(product store.Product) string {
+	if product.ParentID == nil {
+		return strconv.FormatInt(product.ID, 10) + " - "
+	}
+	return "    " + strconv.FormatInt(product.ID, 10) + " - "
+}
@@ -1,6 +1,10 @@
 package main
 
-import "time"
+import (
+	"time"
+
+	"pos-system/internal/money"
+)
 
 const dataFile = "data.json"
 
@@ -12,19 +16,34 @@ type Customer struct {
 }
 
 type Product struct {
-	ID    int     `json:"id"`
-	Name  string  `json:"name"`
-	Price float64 `json:"price"`
-	Stock int     `json:"stock"`
+	ID    int         `json:"id"`
+	Name  string      `json:"name"`
+	Price money.Money `json:"price"`
+	Stock int         `json:"stock"`
 }
 
+const (
+	saleStatusCompleted = "completed"
+	saleStatusVoided    = "voided"
+)
+
 type Sale struct {
-	ID         int       `json:"id"`
-	CustomerID int       `json:"customer_id"`
-	ProductID  int       `json:"product_id"`
-	Quantity   int       `json:"quantity"`
-	Total      float64   `json:"total"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID         int         `json:"id"`
+	CustomerID int         `json:"customer_id"`
+	ProductID  int         `json:"product_id"`
+	Quantity   int         `json:"quantity"`
+	Total      money.Money `json:"total"`
+	CreatedAt  time.Time   `json:"created_at"`
+	Status     string      `json:"status,omitempty"`
+	VoidedAt   *time.Time  `json:"voided_at,omitempty"`
+	VoidedBy   string      `json:"voided_by,omitempty"`
+	VoidReason string      `json:"void_reason,omitempty"`
+}
+
+// Voided reports whether the sale was voided. Sales saved before statuses
+// existed have no status and count as completed.
+func (s Sale) Voided() bool {
+	return s.Status == saleStatusVoided
 }
 
 type Store struct {
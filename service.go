@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"strconv"
 	"time"
+
+	"pos-system/internal/money"
 )
 
 func listCustomers(store *Store) {
@@ -85,13 +87,13 @@ func listProducts(store *Store) {
 		return
 	}
 	for _, product := range store.Products {
-		fmt.Printf("ID: %d | %s | $%.2f | Stock: %d\n", product.ID, product.Name, product.Price, product.Stock)
+		fmt.Printf("ID: %d | %s | $%s | Stock: %d\n", product.ID, product.Name, product.Price, product.Stock)
 	}
 }
 
 func addProduct(reader *bufio.Reader, store *Store) {
 	name := readString(reader, "Name: ")
-	price := readFloat(reader, "Price: ")
+	price := readMoney(reader, "Price: ")
 	stock := readInt(reader, "Stock: ")
 
 	store.Products = append(store.Products, Product{
@@ -115,14 +117,14 @@ func editProduct(reader *bufio.Reader, store *Store) {
 
 	product := store.Products[index]
 	name := readOptionalString(reader, fmt.Sprintf("Name [%s]: ", product.Name))
-	priceStr := readOptionalString(reader, fmt.Sprintf("Price [%.2f]: ", product.Price))
+	priceStr := readOptionalString(reader, fmt.Sprintf("Price [%s]: ", product.Price))
 	stockStr := readOptionalString(reader, fmt.Sprintf("Stock [%d]: ", product.Stock))
 
 	if name != "" {
 		product.Name = name
 	}
 	if priceStr != "" {
-		price, err := strconv.ParseFloat(priceStr, 64)
+		price, err := money.Parse(priceStr, product.Price.Currency)
 		if err != nil {
 			fmt.Println("Invalid price; keeping existing.")
 		} else {
@@ -168,7 +170,7 @@ func listSales(store *Store) {
 	for _, sale := range store.Sales {
 		customerName := lookupCustomerName(store, sale.CustomerID)
 		productName := lookupProductName(store, sale.ProductID)
-		fmt.Printf("ID: %d | Customer: %s | Product: %s | Qty: %d | Total: $%.2f | Date: %s\n",
+		fmt.Printf("ID: %d | Customer: %s | Product: %s | Qty: %d | Total: $%s | Date: %s",
 			sale.ID,
 			customerName,
 			productName,
@@ -176,6 +178,10 @@ func listSales(store *Store) {
 			sale.Total,
 			sale.CreatedAt.Format(time.RFC3339),
 		)
+		if sale.Voided() {
+			fmt.Printf(" | VOIDED %s by %s: %s", sale.VoidedAt.Format(time.RFC3339), sale.VoidedBy, sale.VoidReason)
+		}
+		fmt.Println()
 	}
 }
 
@@ -215,7 +221,7 @@ func addSale(reader *bufio.Reader, store *Store) {
 	product.Stock -= quantity
 	store.Products[productIndex] = product
 
-	total := product.Price * float64(quantity)
+	total := product.Price.Mul(int64(quantity))
 	store.Sales = append(store.Sales, Sale{
 		ID:         store.NextSaleID,
 		CustomerID: customerID,
@@ -223,6 +229,7 @@ func addSale(reader *bufio.Reader, store *Store) {
 		Quantity:   quantity,
 		Total:      total,
 		CreatedAt:  time.Now(),
+		Status:     saleStatusCompleted,
 	})
 	store.NextSaleID++
 
@@ -238,6 +245,10 @@ func editSale(reader *bufio.Reader, store *Store) {
 	}
 
 	sale := store.Sales[index]
+	if sale.Voided() {
+		fmt.Println("Voided sales cannot be edited.")
+		return
+	}
 	oldProductIndex := findProductIndex(store, sale.ProductID)
 	if oldProductIndex != -1 {
 		oldProduct := store.Products[oldProductIndex]
@@ -291,7 +302,7 @@ func editSale(reader *bufio.Reader, store *Store) {
 	sale.CustomerID = customerID
 	sale.ProductID = productID
 	sale.Quantity = quantity
-	sale.Total = product.Price * float64(quantity)
+	sale.Total = product.Price.Mul(int64(quantity))
 	store.Sales[index] = sale
 
 	fmt.Println("Sale updated.")
@@ -307,8 +318,8 @@ func restoreSaleStock(store *Store, sale Sale) {
 	store.Products[productIndex] = product
 }
 
-func removeSale(reader *bufio.Reader, store *Store) {
-	id := readInt(reader, "Sale ID to remove: ")
+func voidSale(reader *bufio.Reader, store *Store) {
+	id := readInt(reader, "Sale ID to void: ")
 	index := findSaleIndex(store, id)
 	if index == -1 {
 		fmt.Println("Sale not found.")
@@ -316,6 +327,13 @@ func removeSale(reader *bufio.Reader, store *Store) {
 	}
 
 	sale := store.Sales[index]
+	if sale.Voided() {
+		fmt.Println("Sale is already voided.")
+		return
+	}
+	voidedBy := readString(reader, "Voided by: ")
+	reason := readString(reader, "Reason: ")
+
 	productIndex := findProductIndex(store, sale.ProductID)
 	if productIndex != -1 {
 		product := store.Products[productIndex]
@@ -323,8 +341,13 @@ func removeSale(reader *bufio.Reader, store *Store) {
 		store.Products[productIndex] = product
 	}
 
-	store.Sales = append(store.Sales[:index], store.Sales[index+1:]...)
-	fmt.Println("Sale removed.")
+	now := time.Now()
+	sale.Status = saleStatusVoided
+	sale.VoidedAt = &now
+	sale.VoidedBy = voidedBy
+	sale.VoidReason = reason
+	store.Sales[index] = sale
+	fmt.Println("Sale voided.")
 }
 
 func findCustomerIndex(store *Store, id int) int {